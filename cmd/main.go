@@ -1,9 +1,13 @@
 package main
 
 import (
+	"api/pkg/audit"
+	"api/pkg/auth"
 	"api/pkg/common"
 	"api/pkg/db"
 	"api/pkg/instance"
+	"api/pkg/rbac"
+	"api/pkg/search"
 	"api/pkg/template"
 	"context"
 	"log"
@@ -45,21 +49,71 @@ func main() {
 	}
 	log.Println("successfully connected to database")
 
+	if err := dbRepository.EnsureTextIndexes(); err != nil {
+		log.Println("error creating search indexes: ", err)
+	}
+
+	if err := dbRepository.EnsureMetricPointsCollection(); err != nil {
+		log.Println("error creating metric points collection: ", err)
+	}
+
+	if err := dbRepository.EnsureRoleAssignmentIndexes(); err != nil {
+		log.Println("error creating role assignment indexes: ", err)
+	}
+
+	if err := dbRepository.EnsureAuditIndexes(); err != nil {
+		log.Println("error creating audit indexes: ", err)
+	}
+
+	if err := dbRepository.EnsureHistoryIndexes(); err != nil {
+		log.Println("error creating history indexes: ", err)
+	}
+
 	r := gin.Default()
 
-	r.Use(cors.Default())
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.AddAllowHeaders("Authorization", auth.APIKeyHeader, audit.RequestIDHeader, common.HeaderIfMatch)
+	corsConfig.AddExposeHeaders(audit.RequestIDHeader, common.HeaderETag)
+	r.Use(cors.New(corsConfig))
+	r.Use(audit.RequestID())
+
+	authenticators, err := auth.AuthenticatorsFromEnv()
+	if err != nil {
+		log.Println("error configuring authentication: ", err)
+		panic(err)
+	}
+	roleService := rbac.NewService(dbRepository)
+	authorizer := rbac.NewAuthorizer(roleService)
+	if len(authenticators) > 0 {
+		r.Use(auth.Middleware(authenticators...))
+	} else {
+		log.Println("authentication is disabled")
+		authorizer = rbac.NewAllowAllAuthorizer()
+	}
+
+	roleController := rbac.NewController(roleService)
+	rbac.RegisterRoutes(r, roleController, authorizer)
 
 	commonService := common.NewService(dbRepository)
 	commonController := common.NewController(commonService)
-	common.RegisterRoutes(r, commonController)
+	common.RegisterRoutes(r, commonController, authorizer)
 
 	templateService := template.NewService(dbRepository)
 	templateController := template.NewController(templateService)
-	template.RegisterRoutes(r, templateController)
+	template.RegisterRoutes(r, templateController, authorizer)
 
 	instanceService := instance.NewService(dbRepository, templateService, commonService)
 	instanceController := instance.NewController(instanceService)
-	instance.RegisterRoutes(r, instanceController)
+	instance.RegisterRoutes(r, instanceController, authorizer)
+
+	auditService := audit.NewService(dbRepository)
+	auditController := audit.NewController(auditService)
+	audit.RegisterRoutes(r, auditController, authorizer)
+
+	searchService := search.NewService(dbRepository)
+	searchController := search.NewController(searchService)
+	search.RegisterRoutes(r, searchController, authorizer)
 
 	if err = r.Run(); err != nil {
 		panic(err)
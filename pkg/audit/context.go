@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"api/pkg/auth"
+	"api/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	requestIdKey       = "requestId"
+	maxRequestIdLength = 128
+)
+
+func RequestID() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		requestId := context.GetHeader(RequestIDHeader)
+		if requestId == "" || len(requestId) > maxRequestIdLength {
+			requestId = uuid.NewString()
+		}
+
+		context.Set(requestIdKey, requestId)
+		context.Header(RequestIDHeader, requestId)
+		context.Next()
+	}
+}
+
+func ContextFrom(context *gin.Context) models.AuditContext {
+	auditContext := models.AuditContext{
+		RequestID: context.GetString(requestIdKey),
+	}
+	if principal, ok := auth.CurrentPrincipal(context); ok {
+		auditContext.Actor = principal.Subject
+	}
+	return auditContext
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"api/pkg/common"
+	"api/pkg/db"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	GetAuditEvents(context *gin.Context)
+}
+
+type controller struct {
+	auditService Service
+}
+
+func NewController(auditService Service) Controller {
+	return &controller{
+		auditService: auditService,
+	}
+}
+
+func (c *controller) GetAuditEvents(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+
+	query, err := db.ParseAuditQuery(context.Request.URL.Query())
+	if err != nil {
+		log.Println("error parsing audit query: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	res, err := c.auditService.GetAuditEvents(tenantId, query)
+	if err != nil {
+		log.Println("error getting audit events: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
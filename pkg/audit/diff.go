@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"api/pkg/models"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+func Diff(before interface{}, after interface{}) []models.AuditChange {
+	changes := make([]models.AuditChange, 0)
+	diffValues("", normalise(before), normalise(after), &changes)
+	return slices.DeleteFunc(changes, func(change models.AuditChange) bool {
+		return change.Path == "version"
+	})
+}
+
+func normalise(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	var normalised interface{}
+	if err := json.Unmarshal(data, &normalised); err != nil {
+		return string(data)
+	}
+	return normalised
+}
+
+func diffValues(path string, before interface{}, after interface{}, changes *[]models.AuditChange) {
+	beforeObject, beforeIsObject := before.(map[string]interface{})
+	afterObject, afterIsObject := after.(map[string]interface{})
+	if (beforeIsObject || before == nil) && (afterIsObject || after == nil) && (beforeIsObject || afterIsObject) {
+		for _, key := range objectKeys(beforeObject, afterObject) {
+			diffValues(joinPath(path, key), beforeObject[key], afterObject[key], changes)
+		}
+		return
+	}
+
+	beforeArray, beforeIsArray := before.([]interface{})
+	afterArray, afterIsArray := after.([]interface{})
+	if (beforeIsArray || before == nil) && (afterIsArray || after == nil) && (beforeIsArray || afterIsArray) {
+		for i := 0; i < max(len(beforeArray), len(afterArray)); i++ {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), elementAt(beforeArray, i), elementAt(afterArray, i), changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, models.AuditChange{Path: path, Before: before, After: after})
+	}
+}
+
+func objectKeys(before map[string]interface{}, after map[string]interface{}) []string {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func elementAt(values []interface{}, i int) interface{} {
+	if i < len(values) {
+		return values[i]
+	}
+	return nil
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
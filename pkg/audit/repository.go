@@ -0,0 +1,192 @@
+package audit
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var auditedCollections = map[string]string{
+	"templates": models.AuditEntityTemplate,
+	"instances": models.AuditEntityInstance,
+}
+
+type repository struct {
+	db.Repository
+	auditContext models.AuditContext
+}
+
+func NewRepository(dbRepository db.Repository, auditContext models.AuditContext) db.Repository {
+	return &repository{
+		Repository:   dbRepository,
+		auditContext: auditContext,
+	}
+}
+
+func (r *repository) WithTransaction(fn func(tx db.Repository) error) error {
+	return r.Repository.WithTransaction(func(tx db.Repository) error {
+		return fn(NewRepository(tx, r.auditContext))
+	})
+}
+
+func (r *repository) AddOne(collectionName string, data interface{}) error {
+	if err := r.Repository.AddOne(collectionName, data); err != nil {
+		return err
+	}
+
+	kind, ok := auditedCollections[collectionName]
+	if !ok {
+		return nil
+	}
+	return r.record(r.newEvent(kind, models.AuditOperationCreate, nil, data))
+}
+
+func (r *repository) ReplaceTemplate(filter primitive.D, data interface{}) (int64, error) {
+	var before interface{}
+	existingTemplate, err := r.Repository.GetTemplate(filter)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Println("error getting template for audit: ", err)
+		return 0, err
+	}
+	if existingTemplate != nil {
+		before = existingTemplate
+	}
+
+	version, err := r.Repository.ReplaceTemplate(filter, data)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.record(r.newEvent(models.AuditEntityTemplate, models.AuditOperationUpdate, before, data)); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (r *repository) ReplaceInstance(filter primitive.D, data interface{}) (int64, error) {
+	var before interface{}
+	existingInstance, err := r.Repository.GetInstance(filter)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Println("error getting instance for audit: ", err)
+		return 0, err
+	}
+	if existingInstance != nil {
+		before = existingInstance
+	}
+
+	version, err := r.Repository.ReplaceInstance(filter, data)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.record(r.newEvent(models.AuditEntityInstance, models.AuditOperationUpdate, before, data)); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (r *repository) DeleteInstance(filter primitive.D) error {
+	existingInstance, err := r.Repository.GetInstance(filter)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Println("error getting instance for audit: ", err)
+		return err
+	}
+
+	if err := r.Repository.DeleteInstance(filter); err != nil {
+		return err
+	}
+	if existingInstance == nil {
+		return nil
+	}
+	return r.record(r.newEvent(models.AuditEntityInstance, models.AuditOperationDelete, existingInstance, nil))
+}
+
+func (r *repository) DeleteInstances(filter primitive.D) error {
+	existingInstances, err := r.Repository.GetAllInstances(filter, nil)
+	if err != nil {
+		log.Println("error getting instances for audit: ", err)
+		return err
+	}
+
+	if err := r.Repository.DeleteInstances(filter); err != nil {
+		return err
+	}
+
+	events := make([]models.AuditEvent, 0, len(existingInstances))
+	for _, instance := range existingInstances {
+		events = append(events, r.newEvent(models.AuditEntityInstance, models.AuditOperationDelete, instance, nil))
+	}
+	return r.record(events...)
+}
+
+func (r *repository) DeleteTemplates(filter primitive.D) error {
+	existingTemplates, err := r.Repository.GetAllTemplates(filter, nil)
+	if err != nil {
+		log.Println("error getting templates for audit: ", err)
+		return err
+	}
+
+	if err := r.Repository.DeleteTemplates(filter); err != nil {
+		return err
+	}
+
+	events := make([]models.AuditEvent, 0, len(existingTemplates))
+	for _, template := range existingTemplates {
+		events = append(events, r.newEvent(models.AuditEntityTemplate, models.AuditOperationDelete, template, nil))
+	}
+	return r.record(events...)
+}
+
+func (r *repository) newEvent(kind string, operation string, before interface{}, after interface{}) models.AuditEvent {
+	entity := after
+	if entity == nil {
+		entity = before
+	}
+	tenantId, externalId := entityIdentity(entity)
+
+	return models.AuditEvent{
+		TenantID:         tenantId,
+		Actor:            r.auditContext.Actor,
+		RequestID:        r.auditContext.RequestID,
+		EntityKind:       kind,
+		EntityExternalId: externalId,
+		Operation:        operation,
+		Changes:          Diff(before, after),
+		Timestamp:        time.Now().UTC(),
+	}
+}
+
+func (r *repository) record(events ...models.AuditEvent) error {
+	recordedEvents := make([]models.AuditEvent, 0, len(events))
+	for _, event := range events {
+		if len(event.Changes) > 0 {
+			recordedEvents = append(recordedEvents, event)
+		}
+	}
+	if len(recordedEvents) == 0 {
+		return nil
+	}
+
+	if err := r.Repository.AddAuditEvents(recordedEvents); err != nil {
+		log.Println("error recording audit events: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func entityIdentity(entity interface{}) (string, string) {
+	switch e := entity.(type) {
+	case models.Template:
+		return e.TenantID, e.BasicInformation.ExternalID
+	case *models.Template:
+		return e.TenantID, e.BasicInformation.ExternalID
+	case models.Instance:
+		return e.TenantID, e.BasicInformation.ExternalId
+	case *models.Instance:
+		return e.TenantID, e.BasicInformation.ExternalId
+	}
+	return "", ""
+}
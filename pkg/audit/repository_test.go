@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newFloorTemplate(name string) models.Template {
+	return models.Template{
+		TenantID: "the-binary",
+		BasicInformation: models.TemplateBasicInformation{
+			Name:       name,
+			ExternalID: "p.com.floor",
+			Parent:     "p.com.space",
+		},
+		Attributes: []models.TemplateAttribute{},
+		Metrics:    []models.TemplateMetric{},
+	}
+}
+
+func TestRepository_ReplaceTemplate_Changed_RecordsDiff(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	auditedRepository := NewRepository(mockRepository, models.AuditContext{Actor: "alice", RequestID: "request-1"})
+
+	filter := bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "p.com.floor"}}
+	existingTemplate := newFloorTemplate("Floor")
+	updatedTemplate := newFloorTemplate("Storey")
+	mockRepository.On("GetTemplate", filter).Return(&existingTemplate, nil)
+	mockRepository.On("ReplaceTemplate", filter, updatedTemplate).Return(int64(2), nil)
+	mockRepository.On("AddAuditEvents", mock.MatchedBy(func(events []models.AuditEvent) bool {
+		return len(events) == 1 &&
+			events[0].TenantID == "the-binary" &&
+			events[0].Actor == "alice" &&
+			events[0].RequestID == "request-1" &&
+			events[0].EntityKind == models.AuditEntityTemplate &&
+			events[0].EntityExternalId == "p.com.floor" &&
+			events[0].Operation == models.AuditOperationUpdate &&
+			assert.ObjectsAreEqual([]models.AuditChange{{Path: "basicInformation.name", Before: "Floor", After: "Storey"}}, events[0].Changes)
+	})).Return(nil)
+
+	actualVersion, actualErr := auditedRepository.ReplaceTemplate(filter, updatedTemplate)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, int64(2), actualVersion)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestRepository_ReplaceTemplate_Unchanged_RecordsNothing(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	auditedRepository := NewRepository(mockRepository, models.AuditContext{Actor: "alice"})
+
+	filter := bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "p.com.floor"}}
+	existingTemplate := newFloorTemplate("Floor")
+	mockRepository.On("GetTemplate", filter).Return(&existingTemplate, nil)
+	mockRepository.On("ReplaceTemplate", filter, newFloorTemplate("Floor")).Return(int64(2), nil)
+
+	_, actualErr := auditedRepository.ReplaceTemplate(filter, newFloorTemplate("Floor"))
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertNotCalled(t, "AddAuditEvents", mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestRepository_DeleteTemplates_Success_RecordsEventPerTemplate(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	auditedRepository := NewRepository(mockRepository, models.AuditContext{Actor: "alice"})
+
+	filter := bson.D{{Key: "tenantId", Value: "the-binary"}}
+	mockRepository.On("GetAllTemplates", filter, mock.Anything).Return([]models.Template{newFloorTemplate("Floor"), newFloorTemplate("Level")}, nil)
+	mockRepository.On("DeleteTemplates", filter).Return(nil)
+	mockRepository.On("AddAuditEvents", mock.MatchedBy(func(events []models.AuditEvent) bool {
+		return len(events) == 2 &&
+			events[0].Operation == models.AuditOperationDelete &&
+			slices.Contains(events[1].Changes, models.AuditChange{Path: "basicInformation.name", Before: "Level", After: nil})
+	})).Return(nil)
+
+	actualErr := auditedRepository.DeleteTemplates(filter)
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestRepository_WithTransaction_AuditsTransactionalWrites(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	auditedRepository := NewRepository(mockRepository, models.AuditContext{Actor: "alice"})
+
+	instance := models.Instance{TenantID: "the-binary", BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", Name: "Floor 1"}}
+	mockRepository.On("AddOne", "instances", instance).Return(nil)
+	mockRepository.On("AddAuditEvents", mock.MatchedBy(func(events []models.AuditEvent) bool {
+		return len(events) == 1 &&
+			events[0].Operation == models.AuditOperationCreate &&
+			events[0].EntityKind == models.AuditEntityInstance &&
+			slices.Contains(events[0].Changes, models.AuditChange{Path: "basicInformation.name", Before: nil, After: "Floor 1"})
+	})).Return(nil)
+
+	actualErr := auditedRepository.WithTransaction(func(tx db.Repository) error {
+		return tx.AddOne("instances", instance)
+	})
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestDiff_NestedValues_ReturnsChangedPaths(t *testing.T) {
+	before := map[string]interface{}{"name": "Floor", "attributes": []interface{}{map[string]interface{}{"id": "a", "value": 1}}}
+	after := map[string]interface{}{"name": "Floor", "attributes": []interface{}{map[string]interface{}{"id": "a", "value": 2}, map[string]interface{}{"id": "b", "value": 3}}}
+
+	actual := Diff(before, after)
+	assert.Equal(t, []models.AuditChange{
+		{Path: "attributes[0].value", Before: float64(1), After: float64(2)},
+		{Path: "attributes[1].id", Before: nil, After: "b"},
+		{Path: "attributes[1].value", Before: nil, After: float64(3)},
+	}, actual)
+}
@@ -0,0 +1,13 @@
+package audit
+
+import (
+	"api/pkg/common"
+	"api/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, auditController Controller, authorizer common.Authorizer) {
+	reads := r.Group("", authorizer.Require(models.PermissionAuditRead))
+	reads.GET("/api/v1/tenants/:tenantId/audit", auditController.GetAuditEvents)
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Service interface {
+	GetAuditEvents(tenantId string, query models.AuditQuery) ([]models.AuditEvent, error)
+}
+
+type service struct {
+	db db.Repository
+}
+
+func NewService(dbRepository db.Repository) Service {
+	return &service{
+		db: dbRepository,
+	}
+}
+
+func (s *service) GetAuditEvents(tenantId string, query models.AuditQuery) ([]models.AuditEvent, error) {
+	events, err := s.db.GetAuditEvents(auditFilter(tenantId, query), query.Limit)
+	if err != nil {
+		log.Println("error getting audit events: ", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func auditFilter(tenantId string, query models.AuditQuery) primitive.D {
+	filter := bson.D{{Key: "tenantId", Value: tenantId}}
+	if query.Entity != "" {
+		filter = append(filter, bson.E{Key: "entityExternalId", Value: query.Entity})
+	}
+	if query.Kind != "" {
+		filter = append(filter, bson.E{Key: "entityKind", Value: query.Kind})
+	}
+
+	timestamp := bson.D{}
+	if !query.From.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$gte", Value: query.From})
+	}
+	if !query.To.IsZero() {
+		timestamp = append(timestamp, bson.E{Key: "$lt", Value: query.To})
+	}
+	if len(timestamp) > 0 {
+		filter = append(filter, bson.E{Key: "timestamp", Value: timestamp})
+	}
+
+	return filter
+}
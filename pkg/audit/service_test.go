@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNewService(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+	newService := NewService(mockRepository)
+
+	assert.Equal(t, mockService, newService)
+}
+
+func TestService_GetAuditEvents_TimeRange_FiltersByEntityAndTimestamp(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	events := []models.AuditEvent{{EntityExternalId: "floor1", Operation: models.AuditOperationUpdate}}
+	mockRepository.On("GetAuditEvents", bson.D{
+		{Key: "tenantId", Value: "the-binary"},
+		{Key: "entityExternalId", Value: "floor1"},
+		{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}},
+	}, int64(100)).Return(events, nil)
+
+	actual, actualErr := mockService.GetAuditEvents("the-binary", models.AuditQuery{Entity: "floor1", From: from, To: to, Limit: 100})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, events, actual)
+
+	mockRepository.AssertExpectations(t)
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type APIKey struct {
+	Name    string   `json:"name"`
+	Key     string   `json:"key"`
+	Tenants []string `json:"tenants"`
+}
+
+type apiKeyFile struct {
+	Keys []APIKey `json:"keys"`
+}
+
+type storedAPIKey struct {
+	name    string
+	digest  [sha256.Size]byte
+	tenants []string
+}
+
+type apiKeyAuthenticator struct {
+	keys []storedAPIKey
+}
+
+func NewAPIKeyAuthenticator(keys []APIKey) Authenticator {
+	storedKeys := make([]storedAPIKey, 0, len(keys))
+	for _, key := range keys {
+		storedKeys = append(storedKeys, storedAPIKey{
+			name:    key.Name,
+			digest:  sha256.Sum256([]byte(key.Key)),
+			tenants: key.Tenants,
+		})
+	}
+
+	return &apiKeyAuthenticator{
+		keys: storedKeys,
+	}
+}
+
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file apiKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing api keys file: %w", err)
+	}
+	for i, key := range file.Keys {
+		if key.Name == "" || key.Key == "" {
+			return nil, fmt.Errorf("api key %d needs a name and a key", i)
+		}
+	}
+
+	return file.Keys, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(request *http.Request) (*Principal, error) {
+	key := request.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	var match *storedAPIKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], a.keys[i].digest[:]) == 1 {
+			match = &a.keys[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+	}
+
+	return &Principal{
+		Subject: match.name,
+		Tenants: match.tenants,
+		Method:  MethodAPIKey,
+	}, nil
+}
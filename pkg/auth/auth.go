@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"api/pkg/common"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+
+	AllTenants = "*"
+
+	principalKey = "principal"
+)
+
+type Principal struct {
+	Subject string
+	Tenants []string
+	Method  string
+}
+
+func (p Principal) HasTenant(tenantId string) bool {
+	return slices.Contains(p.Tenants, tenantId) || slices.Contains(p.Tenants, AllTenants)
+}
+
+type Authenticator interface {
+	Authenticate(request *http.Request) (*Principal, error)
+}
+
+func Middleware(authenticators ...Authenticator) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		principal, err := authenticate(context.Request, authenticators)
+		if err != nil {
+			log.Println("error authenticating request: ", err)
+			common.RespondWithError(context, http.StatusUnauthorized, common.ErrorCodeUnauthorized, authenticationMessage(err), nil)
+			context.Abort()
+			return
+		}
+
+		if tenantId := context.Param("tenantId"); tenantId != "" && !principal.HasTenant(tenantId) {
+			log.Printf("principal %s is not allowed to access tenant %s\n", principal.Subject, tenantId)
+			common.RespondWithError(context, http.StatusForbidden, common.ErrorCodeForbidden, "not allowed to access this tenant", nil)
+			context.Abort()
+			return
+		}
+
+		context.Set(principalKey, principal)
+		context.Next()
+	}
+}
+
+func CurrentPrincipal(context *gin.Context) (*Principal, bool) {
+	value, ok := context.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
+
+func authenticate(request *http.Request, authenticators []Authenticator) (*Principal, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(request)
+		if errors.Is(err, ErrMissingCredentials) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrMissingCredentials
+}
+
+func authenticationMessage(err error) string {
+	if errors.Is(err, ErrMissingCredentials) {
+		return ErrMissingCredentials.Error()
+	}
+	return ErrInvalidCredentials.Error()
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthTestEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(NewAPIKeyAuthenticator([]APIKey{
+		{Name: "ingest", Key: "tenant-key", Tenants: []string{"the-binary"}},
+		{Name: "operator", Key: "operator-key", Tenants: []string{AllTenants}},
+	})))
+	handler := func(context *gin.Context) {
+		principal, _ := CurrentPrincipal(context)
+		context.String(http.StatusOK, principal.Subject)
+	}
+	r.GET("/api/v1/tenants/:tenantId/templates", handler)
+	r.GET("/api/v1/units", handler)
+	return r
+}
+
+func TestMiddleware_Requests_AreAuthenticatedAndScopedToTenant(t *testing.T) {
+	r := newAuthTestEngine()
+
+	for _, testCase := range []struct {
+		path           string
+		apiKey         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{path: "/api/v1/tenants/the-binary/templates", apiKey: "tenant-key", expectedStatus: http.StatusOK, expectedBody: "ingest"},
+		{path: "/api/v1/tenants/other/templates", apiKey: "tenant-key", expectedStatus: http.StatusForbidden},
+		{path: "/api/v1/tenants/other/templates", apiKey: "operator-key", expectedStatus: http.StatusOK, expectedBody: "operator"},
+		{path: "/api/v1/units", apiKey: "tenant-key", expectedStatus: http.StatusOK, expectedBody: "ingest"},
+		{path: "/api/v1/tenants/the-binary/templates", apiKey: "wrong-key", expectedStatus: http.StatusUnauthorized},
+		{path: "/api/v1/tenants/the-binary/templates", expectedStatus: http.StatusUnauthorized},
+	} {
+		w := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, testCase.path, nil)
+		if testCase.apiKey != "" {
+			request.Header.Set(APIKeyHeader, testCase.apiKey)
+		}
+		r.ServeHTTP(w, request)
+
+		assert.Equal(t, testCase.expectedStatus, w.Code, testCase.path+" "+testCase.apiKey)
+		if testCase.expectedBody != "" {
+			assert.Equal(t, testCase.expectedBody, w.Body.String())
+		}
+	}
+}
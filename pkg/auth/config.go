@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+var ErrNotConfigured = errors.New("no authentication configured: set AuthJWTKeyFile or AuthAPIKeysFile, or AuthDisabled=true for local development")
+
+func AuthenticatorsFromEnv() ([]Authenticator, error) {
+	authenticators := make([]Authenticator, 0, 2)
+
+	if keyFile := os.Getenv("AuthJWTKeyFile"); keyFile != "" {
+		keys, err := LoadKeySet(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading jwt keys: %w", err)
+		}
+		authenticators = append(authenticators, NewJWTAuthenticator(keys, JWTOptions{
+			Issuer:      os.Getenv("AuthJWTIssuer"),
+			Audience:    os.Getenv("AuthJWTAudience"),
+			TenantClaim: os.Getenv("AuthTenantClaim"),
+		}))
+		log.Printf("jwt authentication enabled with %d keys\n", len(keys))
+	}
+
+	if keysFile := os.Getenv("AuthAPIKeysFile"); keysFile != "" {
+		keys, err := LoadAPIKeys(keysFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading api keys: %w", err)
+		}
+		authenticators = append(authenticators, NewAPIKeyAuthenticator(keys))
+		log.Printf("api key authentication enabled with %d keys\n", len(keys))
+	}
+
+	if len(authenticators) == 0 && os.Getenv("AuthDisabled") != "true" {
+		return nil, ErrNotConfigured
+	}
+	return authenticators, nil
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+const (
+	DefaultTenantClaim = "tenants"
+	defaultLeeway      = time.Minute
+)
+
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+type JWTOptions struct {
+	Issuer      string
+	Audience    string
+	TenantClaim string
+	Leeway      time.Duration
+}
+
+type jwtAuthenticator struct {
+	keys    KeySet
+	options JWTOptions
+	now     func() time.Time
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string    `json:"sub"`
+	Issuer    string    `json:"iss"`
+	Audience  claimList `json:"aud"`
+	ExpiresAt *float64  `json:"exp"`
+	NotBefore *float64  `json:"nbf"`
+	Tenants   claimList `json:"-"`
+}
+
+type claimList []string
+
+func (c *claimList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*c = claimList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+func NewJWTAuthenticator(keys KeySet, options JWTOptions) Authenticator {
+	if options.TenantClaim == "" {
+		options.TenantClaim = DefaultTenantClaim
+	}
+	if options.Leeway == 0 {
+		options.Leeway = defaultLeeway
+	}
+
+	return &jwtAuthenticator{
+		keys:    keys,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+func (a *jwtAuthenticator) Authenticate(request *http.Request) (*Principal, error) {
+	scheme, token, ok := strings.Cut(request.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	claims, err := a.verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	return &Principal{
+		Subject: claims.Subject,
+		Tenants: claims.Tenants,
+		Method:  MethodJWT,
+	}, nil
+}
+
+func (a *jwtAuthenticator) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token must have three parts")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("decoding header: %w", err)
+	}
+	key, err := a.keys.find(header.KeyID, header.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decoding signature: %w", err)
+	}
+	if err := verifySignature(header.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("decoding claims: %w", err)
+	}
+	var rawClaims map[string]json.RawMessage
+	if err := decodeSegment(parts[1], &rawClaims); err != nil {
+		return nil, fmt.Errorf("decoding claims: %w", err)
+	}
+	if err := a.validateClaims(&claims, rawClaims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (a *jwtAuthenticator) validateClaims(claims *jwtClaims, rawClaims map[string]json.RawMessage) error {
+	now := a.now()
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(numericDate(*claims.ExpiresAt).Add(a.options.Leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(a.options.Leeway).Before(numericDate(*claims.NotBefore)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if a.options.Issuer != "" && claims.Issuer != a.options.Issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if a.options.Audience != "" && !slices.Contains(claims.Audience, a.options.Audience) {
+		return fmt.Errorf("token is not meant for audience %q", a.options.Audience)
+	}
+
+	tenantClaim, ok := rawClaims[a.options.TenantClaim]
+	if !ok {
+		return fmt.Errorf("token has no %s claim", a.options.TenantClaim)
+	}
+	if err := json.Unmarshal(tenantClaim, &claims.Tenants); err != nil {
+		return fmt.Errorf("decoding %s claim: %w", a.options.TenantClaim, err)
+	}
+
+	return nil
+}
+
+func verifySignature(algorithm string, key interface{}, signingInput string, signature []byte) error {
+	if len(algorithm) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	hash, ok := jwtHashes[algorithm[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	digest := hash.New()
+	digest.Write([]byte(signingInput))
+
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(publicKey, hash, digest.Sum(nil), signature); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature")
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest.Sum(nil), r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+	case []byte:
+		mac := hmac.New(hash.New, publicKey)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func numericDate(value float64) time.Time {
+	seconds := int64(value)
+	return time.Unix(seconds, int64((value-float64(seconds))*float64(time.Second)))
+}
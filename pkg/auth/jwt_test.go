@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signToken(t *testing.T, algorithm string, keyId string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": algorithm, "kid": keyId, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch signingKey := key.(type) {
+	case *rsa.PrivateKey:
+		rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, signingKey, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+		signature = rsaSignature
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, signingKey, digest[:])
+		assert.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case []byte:
+		mac := hmac.New(sha256.New, signingKey)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func bearerRequest(token string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	return request
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":     "modeller@the-binary",
+		"iss":     "https://issuer.example",
+		"aud":     []string{"buildifyy-api"},
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"tenants": []string{"the-binary"},
+	}
+}
+
+func TestJWTAuthenticator_Authenticate_ValidTokens_ReturnPrincipal(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret := []byte("a-shared-secret-of-sufficient-length")
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), "e": "AQAB"},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))), "y": base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "oct", "kid": "hmac", "alg": "HS256", "k": base64.RawURLEncoding.EncodeToString(secret)},
+	}})
+	keys, err := ParseJWKS(jwks)
+	assert.NoError(t, err)
+	authenticator := NewJWTAuthenticator(keys, JWTOptions{Issuer: "https://issuer.example", Audience: "buildifyy-api"})
+
+	for _, token := range []string{
+		signToken(t, "RS256", "rsa", rsaKey, validClaims()),
+		signToken(t, "ES256", "ec", ecKey, validClaims()),
+		signToken(t, "HS256", "hmac", secret, validClaims()),
+	} {
+		actual, actualErr := authenticator.Authenticate(bearerRequest(token))
+		assert.NoError(t, actualErr)
+		assert.Equal(t, &Principal{Subject: "modeller@the-binary", Tenants: []string{"the-binary"}, Method: MethodJWT}, actual)
+	}
+}
+
+func TestJWTAuthenticator_Authenticate_PEMKeyAndCustomTenantClaim_ReturnsPrincipal(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	publicKey, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	keys, err := ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	assert.NoError(t, err)
+	authenticator := NewJWTAuthenticator(keys, JWTOptions{TenantClaim: "https://buildifyy.com/tenant"})
+
+	claims := validClaims()
+	claims["https://buildifyy.com/tenant"] = "the-binary"
+	actual, actualErr := authenticator.Authenticate(bearerRequest(signToken(t, "RS256", "", rsaKey, claims)))
+	assert.NoError(t, actualErr)
+	assert.Equal(t, []string{"the-binary"}, actual.Tenants)
+}
+
+func TestJWTAuthenticator_Authenticate_InvalidTokens_ReturnInvalidCredentials(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	secret := []byte("a-shared-secret-of-sufficient-length")
+	authenticator := NewJWTAuthenticator(KeySet{{ID: "rsa", Value: &rsaKey.PublicKey}}, JWTOptions{Issuer: "https://issuer.example", Audience: "buildifyy-api"})
+
+	expired, wrongIssuer, wrongAudience, noExpiry, noTenants, notYetValid := validClaims(), validClaims(), validClaims(), validClaims(), validClaims(), validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	wrongIssuer["iss"] = "https://other.example"
+	wrongAudience["aud"] = "other-api"
+	delete(noExpiry, "exp")
+	delete(noTenants, "tenants")
+	notYetValid["nbf"] = time.Now().Add(time.Hour).Unix()
+
+	for name, token := range map[string]string{
+		"malformed":      "not-a-token",
+		"wrong key":      signToken(t, "RS256", "rsa", otherKey, validClaims()),
+		"hmac confusion": signToken(t, "HS256", "rsa", secret, validClaims()),
+		"none":           signToken(t, "none", "rsa", nil, validClaims()),
+		"expired":        signToken(t, "RS256", "rsa", rsaKey, expired),
+		"wrong issuer":   signToken(t, "RS256", "rsa", rsaKey, wrongIssuer),
+		"wrong audience": signToken(t, "RS256", "rsa", rsaKey, wrongAudience),
+		"no expiry":      signToken(t, "RS256", "rsa", rsaKey, noExpiry),
+		"no tenants":     signToken(t, "RS256", "rsa", rsaKey, noTenants),
+		"not yet valid":  signToken(t, "RS256", "rsa", rsaKey, notYetValid),
+	} {
+		_, actualErr := authenticator.Authenticate(bearerRequest(token))
+		assert.ErrorIs(t, actualErr, ErrInvalidCredentials, name)
+	}
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var ErrUnknownKey = errors.New("no matching verification key")
+
+type Key struct {
+	ID        string
+	Algorithm string
+	Value     interface{}
+}
+
+type KeySet []Key
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+func LoadKeySet(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return ParseJWKS(data)
+	}
+	return ParsePEM(data)
+}
+
+func ParseJWKS(data []byte) (KeySet, error) {
+	var jwks jsonWebKeySet
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("parsing jwks: %w", err)
+	}
+
+	keys := make(KeySet, 0, len(jwks.Keys))
+	for i, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		value, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("parsing jwks key %d: %w", i, err)
+		}
+		keys = append(keys, Key{ID: jwk.Kid, Algorithm: jwk.Alg, Value: value})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks contains no signing keys")
+	}
+
+	return keys, nil
+}
+
+func ParsePEM(data []byte) (KeySet, error) {
+	keys := make(KeySet, 0)
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		data = rest
+
+		var value interface{}
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			value, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			value, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var certificate *x509.Certificate
+			certificate, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				value = certificate.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", strings.ToLower(block.Type), err)
+		}
+		keys = append(keys, Key{Value: value})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys found in pem data")
+	}
+
+	return keys, nil
+}
+
+func (k KeySet) find(id string, algorithm string) (interface{}, error) {
+	candidates := make([]Key, 0, len(k))
+	for _, key := range k {
+		if id != "" && key.ID != "" && key.ID != id {
+			continue
+		}
+		if key.Algorithm != "" && key.Algorithm != algorithm {
+			continue
+		}
+		if !keyFitsAlgorithm(key.Value, algorithm) {
+			continue
+		}
+		candidates = append(candidates, key)
+	}
+
+	if len(candidates) != 1 {
+		return nil, fmt.Errorf("%w for kid %q and alg %s", ErrUnknownKey, id, algorithm)
+	}
+	return candidates[0].Value, nil
+}
+
+func keyFitsAlgorithm(value interface{}, algorithm string) bool {
+	switch value.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(algorithm, "RS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(algorithm, "ES")
+	case []byte:
+		return strings.HasPrefix(algorithm, "HS")
+	}
+	return false
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,295 @@
+package calculation
+
+import (
+	"api/pkg/db"
+	"api/pkg/formula"
+	"api/pkg/models"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const MaxRecalculationPasses = 10
+
+type TemplateSource interface {
+	GetTemplate(tenantId string, templateId string) (*models.Template, error)
+}
+
+type RelationshipSource interface {
+	GetRelationships() ([]models.Relationship, error)
+}
+
+type Calculator interface {
+	Template(externalId string) (*models.Template, error)
+	Calculate(instance *models.Instance, instanceTemplate *models.Template) bool
+	Propagate(changedExternalIds []string) error
+}
+
+type calculator struct {
+	db                 db.Repository
+	templateSource     TemplateSource
+	relationshipSource RelationshipSource
+	tenantId           string
+	templates          map[string]*models.Template
+	relationships      []models.Relationship
+}
+
+func NewCalculator(repository db.Repository, templateSource TemplateSource, relationshipSource RelationshipSource, tenantId string) Calculator {
+	return &calculator{
+		db:                 repository,
+		templateSource:     templateSource,
+		relationshipSource: relationshipSource,
+		tenantId:           tenantId,
+		templates:          make(map[string]*models.Template),
+	}
+}
+
+func (c *calculator) Template(externalId string) (*models.Template, error) {
+	if cachedTemplate, ok := c.templates[externalId]; ok {
+		return cachedTemplate, nil
+	}
+	fetchedTemplate, err := c.templateSource.GetTemplate(c.tenantId, externalId)
+	if err != nil {
+		log.Println("error getting template: ", err)
+		return nil, err
+	}
+	c.templates[externalId] = fetchedTemplate
+	return fetchedTemplate, nil
+}
+
+func (c *calculator) relationshipTemplates() ([]models.Relationship, error) {
+	if c.relationships != nil {
+		return c.relationships, nil
+	}
+	relationships, err := c.relationshipSource.GetRelationships()
+	if err != nil {
+		log.Println("error fetching relationships: ", err)
+		return nil, err
+	}
+	c.relationships = relationships
+	return relationships, nil
+}
+
+func (c *calculator) Calculate(instance *models.Instance, instanceTemplate *models.Template) bool {
+	formulas := make(map[string]*formula.Formula)
+	for _, metric := range instanceTemplate.Metrics {
+		if metric.Formula == "" {
+			continue
+		}
+		parsedFormula, err := formula.Parse(metric.Formula)
+		if err != nil {
+			log.Printf("error parsing formula of metric %s: %s\n", metric.Name, err)
+			continue
+		}
+		formulas[metric.Name] = parsedFormula
+	}
+	if len(formulas) == 0 {
+		return false
+	}
+
+	order, err := formula.Order(formulas)
+	if err != nil {
+		log.Printf("error ordering formulas of template %s: %s\n", instanceTemplate.BasicInformation.ExternalID, err)
+		return false
+	}
+
+	changed := false
+	for _, templateMetric := range instanceTemplate.Metrics {
+		if _, ok := formulas[templateMetric.Name]; !ok {
+			continue
+		}
+		if !slices.ContainsFunc(instance.Metrics, func(m models.InstanceMetric) bool {
+			return m.ID == templateMetric.ID
+		}) {
+			instance.Metrics = append(instance.Metrics, models.InstanceMetric{
+				ID:              templateMetric.ID,
+				MetricBehaviour: "Calculated",
+			})
+			changed = true
+		}
+	}
+
+	resolver := &instanceResolver{calculator: c, instance: instance, template: instanceTemplate}
+	for _, name := range order {
+		templateMetric := instanceTemplate.Metrics[slices.IndexFunc(instanceTemplate.Metrics, func(m models.TemplateMetric) bool {
+			return m.Name == name
+		})]
+
+		metricIndex := slices.IndexFunc(instance.Metrics, func(m models.InstanceMetric) bool {
+			return m.ID == templateMetric.ID
+		})
+		if instance.Metrics[metricIndex].MetricBehaviour != "Calculated" {
+			continue
+		}
+
+		value, err := formulas[name].Evaluate(resolver)
+		if err != nil {
+			log.Printf("error calculating metric %s of instance %s, keeping previous value: %s\n", name, instance.BasicInformation.ExternalId, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(instance.Metrics[metricIndex].Value, value) {
+			instance.Metrics[metricIndex].Value = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (c *calculator) Propagate(changedExternalIds []string) error {
+	passes := make(map[string]int)
+	for _, externalId := range changedExternalIds {
+		passes[externalId] = 1
+	}
+
+	frontier := changedExternalIds
+	for len(frontier) > 0 {
+		filter := bson.D{
+			{Key: "tenantId", Value: c.tenantId},
+			{Key: "relationships.target", Value: bson.D{{Key: "$in", Value: frontier}}},
+		}
+		dependents, err := c.db.GetAllInstances(filter, nil)
+		if err != nil {
+			log.Println("error fetching dependent instances: ", err)
+			return err
+		}
+
+		next := make([]string, 0)
+		for i := range dependents {
+			dependent := &dependents[i]
+			externalId := dependent.BasicInformation.ExternalId
+			if passes[externalId] >= MaxRecalculationPasses {
+				log.Printf("calculated metrics of instance %s did not settle after %d passes\n", externalId, MaxRecalculationPasses)
+				validationErr := &models.ValidationError{}
+				validationErr.Add("metrics", externalId, models.ValidationCodeInvalid, fmt.Sprintf("calculated metrics of instance %s did not settle after %d passes", externalId, MaxRecalculationPasses))
+				return validationErr
+			}
+			passes[externalId]++
+
+			dependentTemplate, err := c.Template(dependent.BasicInformation.Parent)
+			if err != nil {
+				return err
+			}
+			if !c.Calculate(dependent, dependentTemplate) {
+				continue
+			}
+
+			filter := bson.D{{Key: "tenantId", Value: c.tenantId}, {Key: "basicInformation.externalId", Value: externalId}}
+			if _, err := c.db.ReplaceInstance(filter, dependent); err != nil {
+				log.Println("error updating instance: ", err)
+				return err
+			}
+			if !slices.Contains(next, externalId) {
+				next = append(next, externalId)
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+func (c *calculator) relatedInstances(instance models.Instance, relationshipName string) ([]models.Instance, error) {
+	relationships, err := c.relationshipTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	relationshipIds := make([]primitive.ObjectID, 0)
+	for _, relationship := range relationships {
+		if relationship.Name == relationshipName {
+			relationshipIds = append(relationshipIds, relationship.ID)
+		}
+	}
+	if len(relationshipIds) == 0 {
+		return nil, fmt.Errorf("%w: relationship %q", formula.ErrUnknownReference, relationshipName)
+	}
+
+	targetExternalIds := make([]string, 0)
+	for _, instanceRelationship := range instance.Relationships {
+		if slices.Contains(relationshipIds, instanceRelationship.RelationshipTemplateId) {
+			targetExternalIds = append(targetExternalIds, instanceRelationship.TargetExternalIds()...)
+		}
+	}
+	if len(targetExternalIds) == 0 {
+		return []models.Instance{}, nil
+	}
+
+	filter := bson.D{
+		{Key: "tenantId", Value: c.tenantId},
+		{Key: "basicInformation.externalId", Value: bson.D{{Key: "$in", Value: targetExternalIds}}},
+	}
+	targets, err := c.db.GetAllInstances(filter, nil)
+	if err != nil {
+		log.Println("error fetching related instances: ", err)
+		return nil, err
+	}
+	return targets, nil
+}
+
+type instanceResolver struct {
+	calculator *calculator
+	instance   *models.Instance
+	template   *models.Template
+}
+
+func (r *instanceResolver) Value(name string) (float64, error) {
+	return namedValue(*r.instance, *r.template, name)
+}
+
+func (r *instanceResolver) Aggregate(function string, relationshipName string, name string) (float64, error) {
+	targets, err := r.calculator.relatedInstances(*r.instance, relationshipName)
+	if err != nil {
+		return 0, err
+	}
+	if function == "count" {
+		return float64(len(targets)), nil
+	}
+
+	values := make([]float64, 0, len(targets))
+	for _, target := range targets {
+		targetTemplate, err := r.calculator.Template(target.BasicInformation.Parent)
+		if err != nil {
+			return 0, err
+		}
+		value, err := namedValue(target, *targetTemplate, name)
+		if err != nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	return formula.Aggregate(function, values)
+}
+
+func namedValue(instance models.Instance, instanceTemplate models.Template, name string) (float64, error) {
+	if metricIndex := slices.IndexFunc(instanceTemplate.Metrics, func(m models.TemplateMetric) bool {
+		return m.Name == name
+	}); metricIndex != -1 {
+		for _, metric := range instance.Metrics {
+			if metric.ID == instanceTemplate.Metrics[metricIndex].ID {
+				if value, ok := models.NumericValue(metric.Value); ok {
+					return value, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("%w: metric %s of %s has no numeric value", formula.ErrUnknownReference, name, instance.BasicInformation.ExternalId)
+	}
+
+	if attributeIndex := slices.IndexFunc(instanceTemplate.Attributes, func(a models.TemplateAttribute) bool {
+		return a.Name == name
+	}); attributeIndex != -1 {
+		for _, attribute := range instance.Attributes {
+			if attribute.ID == instanceTemplate.Attributes[attributeIndex].ID {
+				if value, ok := models.NumericValue(attribute.Value); ok {
+					return value, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("%w: attribute %s of %s has no numeric value", formula.ErrUnknownReference, name, instance.BasicInformation.ExternalId)
+	}
+
+	return 0, fmt.Errorf("%w: %s", formula.ErrUnknownReference, name)
+}
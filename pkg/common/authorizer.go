@@ -0,0 +1,7 @@
+package common
+
+import "github.com/gin-gonic/gin"
+
+type Authorizer interface {
+	Require(permission string) gin.HandlerFunc
+}
@@ -28,7 +28,7 @@ func (c *controller) GetRelationships(context *gin.Context) {
 	values, err := c.commonService.GetRelationships()
 	if err != nil {
 		log.Println("error fetching relationships: ", err)
-		context.Status(http.StatusInternalServerError)
+		RespondWithServiceError(context, err)
 		return
 	}
 
@@ -39,7 +39,7 @@ func (c *controller) GetAttributeTypes(context *gin.Context) {
 	values, err := c.commonService.GetAttributeDropdown()
 	if err != nil {
 		log.Println("error fetching attribute dropdown values: ", err)
-		context.Status(http.StatusInternalServerError)
+		RespondWithServiceError(context, err)
 		return
 	}
 
@@ -50,7 +50,7 @@ func (c *controller) GetMetricTypes(context *gin.Context) {
 	values, err := c.commonService.GetMetricTypeDropdown()
 	if err != nil {
 		log.Println("error fetching metric type dropdown values: ", err)
-		context.Status(http.StatusInternalServerError)
+		RespondWithServiceError(context, err)
 		return
 	}
 
@@ -61,7 +61,7 @@ func (c *controller) GetUnits(context *gin.Context) {
 	values, err := c.commonService.GetUnitDropdown()
 	if err != nil {
 		log.Println("error fetching unit dropdown values: ", err)
-		context.Status(http.StatusInternalServerError)
+		RespondWithServiceError(context, err)
 		return
 	}
 
@@ -9,53 +9,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 )
 
-type MockService struct {
-	mock.Mock
-}
-
-func (m *MockService) GetAttributeDropdown() ([]models.Dropdown, error) {
-	args := m.Called()
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]models.Dropdown), args.Error(1)
-}
-
-func (m *MockService) GetMetricTypeDropdown() ([]models.Dropdown, error) {
-	args := m.Called()
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]models.Dropdown), args.Error(1)
-}
-
-func (m *MockService) GetUnitDropdown() ([]models.Dropdown, error) {
-	args := m.Called()
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]models.Dropdown), args.Error(1)
-}
-
-func (m *MockService) GetRelationships() ([]models.Relationship, error) {
-	args := m.Called()
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).([]models.Relationship), args.Error(1)
-}
-
-func (m *MockService) GetRelationship(id string) (models.Relationship, error) {
-	args := m.Called(id)
-	if args.Get(0) == nil {
-		return models.Relationship{}, args.Error(1)
-	}
-	return args.Get(0).(models.Relationship), args.Error(1)
-}
-
 func TestNewController(t *testing.T) {
 	mockService := &MockService{}
 	mockController := &controller{
@@ -122,6 +77,7 @@ func TestController_GetAttributeTypes_Fails_ReturnsInternalServerError(t *testin
 	mockController.GetAttributeTypes(ctx)
 
 	assert.Equal(t, http.StatusInternalServerError, ctx.Writer.Status())
+	assert.JSONEq(t, `{"error":{"code":"internal_error","message":"internal server error"}}`, w.Body.String())
 
 	mockService.AssertExpectations(t)
 }
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"api/pkg/models"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+const (
+	DefaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+func ParseListQuery(values url.Values) (models.ListQuery, error) {
+	query := models.ListQuery{
+		Limit:        DefaultListLimit,
+		Sort:         "externalId",
+		Parent:       values.Get("parent"),
+		RootTemplate: values.Get("rootTemplate"),
+		NamePrefix:   values.Get("name"),
+		Attributes:   make([]models.AttributeFilter, 0),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsedLimit, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxListLimit {
+			return query, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, maxListLimit)
+		}
+		query.Limit = parsedLimit
+	}
+
+	if page := values.Get("page"); page != "" {
+		parsedPage, err := strconv.ParseInt(page, 10, 64)
+		if err != nil || parsedPage < 1 {
+			return query, fmt.Errorf("%w: page must be a positive integer", ErrInvalidListQuery)
+		}
+		query.Page = parsedPage
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		query.Descending = strings.HasPrefix(sort, "-")
+		query.Sort = strings.TrimPrefix(sort, "-")
+		if !slices.Contains(models.ListSortFields, query.Sort) {
+			return query, fmt.Errorf("%w: cannot sort by %s", ErrInvalidListQuery, query.Sort)
+		}
+	}
+
+	for _, attribute := range values["attribute"] {
+		id, value, _ := strings.Cut(attribute, ":")
+		if id == "" {
+			return query, fmt.Errorf("%w: attribute filter must name an attribute", ErrInvalidListQuery)
+		}
+		query.Attributes = append(query.Attributes, models.AttributeFilter{ID: id, Value: value})
+	}
+
+	if token := values.Get("cursor"); token != "" {
+		if query.Page != 0 {
+			return query, fmt.Errorf("%w: cursor and page cannot be combined", ErrInvalidListQuery)
+		}
+		cursor, err := models.DecodeListCursor(token)
+		if err != nil {
+			return query, fmt.Errorf("%w: malformed cursor", ErrInvalidListQuery)
+		}
+		if cursor.Sort != query.Sort || cursor.Descending != query.Descending {
+			return query, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidListQuery)
+		}
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
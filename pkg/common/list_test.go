@@ -0,0 +1,53 @@
+package common
+
+import (
+	"api/pkg/models"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListQuery_Success_ParsesAllParameters(t *testing.T) {
+	values, _ := url.ParseQuery("limit=25&page=2&sort=-name&parent=testtemplate1&rootTemplate=p.com.asset&name=Pump&attribute=a1:42&attribute=a2")
+
+	actual, actualErr := ParseListQuery(values)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, models.ListQuery{
+		Limit:        25,
+		Page:         2,
+		Sort:         "name",
+		Descending:   true,
+		Parent:       "testtemplate1",
+		RootTemplate: "p.com.asset",
+		NamePrefix:   "Pump",
+		Attributes: []models.AttributeFilter{
+			{ID: "a1", Value: "42"},
+			{ID: "a2", Value: ""},
+		},
+	}, actual)
+}
+
+func TestParseListQuery_Defaults_SortsByExternalIdWithDefaultLimit(t *testing.T) {
+	actual, actualErr := ParseListQuery(url.Values{})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, "externalId", actual.Sort)
+	assert.Equal(t, int64(DefaultListLimit), actual.Limit)
+}
+
+func TestParseListQuery_Cursor_DecodesMatchingSort(t *testing.T) {
+	token := models.ListCursor{Sort: "name", Descending: true, Value: "Pump", ExternalId: "pump2"}.Encode()
+
+	actual, actualErr := ParseListQuery(url.Values{"sort": {"-name"}, "cursor": {token}})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, &models.ListCursor{Sort: "name", Descending: true, Value: "Pump", ExternalId: "pump2"}, actual.Cursor)
+}
+
+func TestParseListQuery_Invalid_ReturnsError(t *testing.T) {
+	token := models.ListCursor{Sort: "name", Value: "Pump", ExternalId: "pump2"}.Encode()
+	for _, rawQuery := range []string{"limit=0", "limit=5000", "limit=abc", "page=0", "sort=color", "cursor=not-a-cursor!", "attribute=:42", "sort=-name&cursor=" + token, "sort=name&page=2&cursor=" + token} {
+		values, _ := url.ParseQuery(rawQuery)
+		_, actualErr := ParseListQuery(values)
+		assert.ErrorIs(t, actualErr, ErrInvalidListQuery, rawQuery)
+	}
+}
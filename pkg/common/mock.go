@@ -0,0 +1,51 @@
+package common
+
+import (
+	"api/pkg/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) GetAttributeDropdown() ([]models.Dropdown, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Dropdown), args.Error(1)
+}
+
+func (m *MockService) GetMetricTypeDropdown() ([]models.Dropdown, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Dropdown), args.Error(1)
+}
+
+func (m *MockService) GetUnitDropdown() ([]models.Dropdown, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Dropdown), args.Error(1)
+}
+
+func (m *MockService) GetRelationships() ([]models.Relationship, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Relationship), args.Error(1)
+}
+
+func (m *MockService) GetRelationship(id string) (models.Relationship, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return models.Relationship{}, args.Error(1)
+	}
+	return args.Get(0).(models.Relationship), args.Error(1)
+}
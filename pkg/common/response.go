@@ -0,0 +1,60 @@
+package common
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ErrorCodeInvalidRequest       = "invalid_request"
+	ErrorCodeValidationFailed     = "validation_failed"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeConflict             = "conflict"
+	ErrorCodeInternal             = "internal_error"
+	ErrorCodeUnauthorized         = "unauthorized"
+	ErrorCodeForbidden            = "forbidden"
+	ErrorCodePreconditionFailed   = "precondition_failed"
+	ErrorCodePreconditionRequired = "precondition_required"
+)
+
+type ErrorResponse struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func RespondWithError(context *gin.Context, status int, code string, message string, details interface{}) {
+	context.JSON(status, gin.H{"error": ErrorResponse{
+		Code:    code,
+		Message: message,
+		Details: details,
+	}})
+}
+
+func RespondWithPreconditionError(context *gin.Context, err error) {
+	if errors.Is(err, ErrPreconditionRequired) {
+		RespondWithError(context, http.StatusPreconditionRequired, ErrorCodePreconditionRequired, err.Error(), nil)
+		return
+	}
+	RespondWithError(context, http.StatusBadRequest, ErrorCodeInvalidRequest, err.Error(), nil)
+}
+
+func RespondWithServiceError(context *gin.Context, err error) {
+	var validationErr *models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		RespondWithError(context, http.StatusBadRequest, ErrorCodeValidationFailed, "validation failed", validationErr.Errors)
+	case errors.Is(err, db.ErrPreconditionFailed):
+		RespondWithError(context, http.StatusPreconditionFailed, ErrorCodePreconditionFailed, err.Error(), nil)
+	case errors.Is(err, db.ErrNotFound):
+		RespondWithError(context, http.StatusNotFound, ErrorCodeNotFound, err.Error(), nil)
+	case errors.Is(err, db.ErrDuplicateExternalId), errors.Is(err, db.ErrVersionConflict):
+		RespondWithError(context, http.StatusConflict, ErrorCodeConflict, err.Error(), nil)
+	default:
+		RespondWithError(context, http.StatusInternalServerError, ErrorCodeInternal, "internal server error", nil)
+	}
+}
@@ -1,12 +1,17 @@
 package common
 
 import (
+	"api/pkg/models"
+
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(r *gin.Engine, commonController Controller) {
-	r.GET("/api/v1/attribute-types", commonController.GetAttributeTypes)
-	r.GET("/api/v1/metric-types", commonController.GetMetricTypes)
-	r.GET("/api/v1/units", commonController.GetUnits)
-	r.GET("/api/v1/relationships", commonController.GetRelationships)
+func RegisterRoutes(r *gin.Engine, commonController Controller, authorizer Authorizer) {
+	types := r.Group("", authorizer.Require(models.PermissionTemplatesRead))
+	types.GET("/api/v1/attribute-types", commonController.GetAttributeTypes)
+	types.GET("/api/v1/metric-types", commonController.GetMetricTypes)
+	types.GET("/api/v1/units", commonController.GetUnits)
+
+	relationships := r.Group("", authorizer.Require(models.PermissionRelationshipsRead))
+	relationships.GET("/api/v1/relationships", commonController.GetRelationships)
 }
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+type StreamWriter struct {
+	context     *gin.Context
+	contentType string
+	filename    string
+}
+
+func NewStreamWriter(context *gin.Context, contentType string, filename string) *StreamWriter {
+	return &StreamWriter{
+		context:     context,
+		contentType: contentType,
+		filename:    filename,
+	}
+}
+
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.writeHeader()
+	return w.context.Writer.Write(p)
+}
+
+func (w *StreamWriter) Close() {
+	w.writeHeader()
+	w.context.Writer.WriteHeaderNow()
+}
+
+func (w *StreamWriter) writeHeader() {
+	if w.context.Writer.Written() {
+		return
+	}
+	w.context.Header("Content-Type", w.contentType)
+	w.context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+	w.context.Status(http.StatusOK)
+}
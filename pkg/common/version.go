@@ -0,0 +1,49 @@
+package common
+
+import (
+	"api/pkg/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+var (
+	ErrInvalidVersion       = errors.New("invalid version")
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+)
+
+func ParseVersion(name string, value string) (int64, error) {
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer", ErrInvalidVersion, name)
+	}
+	return version, nil
+}
+
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(max(version, models.InitialVersion), 10))
+}
+
+func ParseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	switch {
+	case header == "":
+		return 0, ErrPreconditionRequired
+	case header == "*":
+		return 0, nil
+	case strings.HasPrefix(header, "W/"):
+		return 0, fmt.Errorf("%w: If-Match must be a strong entity tag", ErrInvalidVersion)
+	}
+
+	value, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, fmt.Errorf("%w: If-Match must be a quoted entity tag", ErrInvalidVersion)
+	}
+	return ParseVersion("If-Match", value)
+}
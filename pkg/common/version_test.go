@@ -0,0 +1,30 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIfMatch_ValidTags_ReturnsVersion(t *testing.T) {
+	for header, expected := range map[string]int64{`"3"`: 3, ` "12" `: 12, "*": 0} {
+		actual, actualErr := ParseIfMatch(header)
+		assert.Nil(t, actualErr, header)
+		assert.Equal(t, expected, actual, header)
+	}
+}
+
+func TestParseIfMatch_InvalidTags_ReturnsError(t *testing.T) {
+	_, actualErr := ParseIfMatch("")
+	assert.ErrorIs(t, actualErr, ErrPreconditionRequired)
+
+	for _, header := range []string{`W/"3"`, "3", `"0"`, `"abc"`} {
+		_, actualErr := ParseIfMatch(header)
+		assert.ErrorIs(t, actualErr, ErrInvalidVersion, header)
+	}
+}
+
+func TestETag_LegacyVersion_ReturnsInitialVersion(t *testing.T) {
+	assert.Equal(t, `"1"`, ETag(0))
+	assert.Equal(t, `"7"`, ETag(7))
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"api/pkg/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidAuditQuery = errors.New("invalid audit query")
+
+const (
+	auditEventsCollection = "audit_events"
+	defaultAuditLimit     = 100
+	maxAuditLimit         = 1000
+)
+
+func ParseAuditQuery(values url.Values) (models.AuditQuery, error) {
+	query := models.AuditQuery{
+		Entity: strings.ToLower(values.Get("entity")),
+		Kind:   values.Get("kind"),
+		Limit:  defaultAuditLimit,
+	}
+
+	if query.Kind != "" && !slices.Contains([]string{models.AuditEntityTemplate, models.AuditEntityInstance}, query.Kind) {
+		return query, fmt.Errorf("%w: kind must be one of template, instance", ErrInvalidAuditQuery)
+	}
+
+	if from := values.Get("from"); from != "" {
+		parsedFrom, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, fmt.Errorf("%w: from must be an RFC3339 timestamp", ErrInvalidAuditQuery)
+		}
+		query.From = parsedFrom.UTC()
+	}
+	if to := values.Get("to"); to != "" {
+		parsedTo, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, fmt.Errorf("%w: to must be an RFC3339 timestamp", ErrInvalidAuditQuery)
+		}
+		query.To = parsedTo.UTC()
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, fmt.Errorf("%w: from must be before to", ErrInvalidAuditQuery)
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsedLimit, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxAuditLimit {
+			return query, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidAuditQuery, maxAuditLimit)
+		}
+		query.Limit = parsedLimit
+	}
+
+	return query, nil
+}
+
+func (r *repository) EnsureAuditIndexes() error {
+	collection := r.client.Database("buildifyy").Collection(auditEventsCollection)
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "entityExternalId", Value: 1}, {Key: "timestamp", Value: -1}},
+		Options: options.Index().SetName("tenant_entity_timestamp"),
+	}
+	if _, err := collection.Indexes().CreateOne(r.ctx, index); err != nil {
+		log.Println("error creating audit index: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) AddAuditEvents(events []models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		documents = append(documents, event)
+	}
+
+	collection := r.client.Database("buildifyy").Collection(auditEventsCollection)
+	if _, err := collection.InsertMany(r.ctx, documents); err != nil {
+		log.Println("error inserting audit events to database: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetAuditEvents(filter primitive.D, limit int64) ([]models.AuditEvent, error) {
+	collection := r.client.Database("buildifyy").Collection(auditEventsCollection)
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := collection.Find(r.ctx, filter, findOptions)
+	if err != nil {
+		log.Println("error finding audit events in database: ", err)
+		return nil, err
+	}
+
+	results := make([]models.AuditEvent, 0)
+	if err := cursor.All(r.ctx, &results); err != nil {
+		log.Println("error parsing all data from database: ", err)
+		return nil, err
+	}
+
+	return results, nil
+}
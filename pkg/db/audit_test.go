@@ -0,0 +1,39 @@
+package db
+
+import (
+	"api/pkg/models"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuditQuery_Success_ParsesAllParameters(t *testing.T) {
+	values, _ := url.ParseQuery("entity=Floor1&kind=instance&from=2024-03-01T00:00:00Z&to=2024-03-02T00:00:00Z&limit=20")
+
+	actual, actualErr := ParseAuditQuery(values)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, models.AuditQuery{
+		Entity: "floor1",
+		Kind:   models.AuditEntityInstance,
+		From:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		Limit:  20,
+	}, actual)
+}
+
+func TestParseAuditQuery_Invalid_ReturnsError(t *testing.T) {
+	for _, rawQuery := range []string{
+		"kind=relationship",
+		"from=yesterday",
+		"to=2024-03-01",
+		"from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+		"limit=0",
+		"limit=5000",
+	} {
+		values, _ := url.ParseQuery(rawQuery)
+		_, actualErr := ParseAuditQuery(values)
+		assert.ErrorIs(t, actualErr, ErrInvalidAuditQuery, rawQuery)
+	}
+}
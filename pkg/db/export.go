@@ -0,0 +1,82 @@
+package db
+
+import (
+	"api/pkg/models"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *repository) StreamInstances(filter primitive.D, fn func(instance models.Instance) error) error {
+	collection := r.client.Database("buildifyy").Collection("instances")
+	cursor, err := collection.Find(r.ctx, filter, options.Find().SetSort(bson.D{{Key: "basicInformation.externalId", Value: 1}}))
+	if err != nil {
+		log.Println("error finding data in database: ", err)
+		return err
+	}
+	defer cursor.Close(r.ctx)
+
+	for cursor.Next(r.ctx) {
+		var instance models.Instance
+		if err := cursor.Decode(&instance); err != nil {
+			log.Println("error decoding data from database: ", err)
+			return err
+		}
+		if err := fn(instance); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		log.Println("error iterating data from database: ", err)
+		return err
+	}
+	return nil
+}
+
+func (r *repository) StreamTemplateHierarchy(tenantId string, fn func(template models.Template) error) error {
+	collection := r.client.Database("buildifyy").Collection("templates")
+	cursor, err := collection.Aggregate(r.ctx, templateHierarchyPipeline(tenantId))
+	if err != nil {
+		log.Println("error aggregating templates in database: ", err)
+		return err
+	}
+	defer cursor.Close(r.ctx)
+
+	for cursor.Next(r.ctx) {
+		var template models.Template
+		if err := cursor.Decode(&template); err != nil {
+			log.Println("error decoding data from database: ", err)
+			return err
+		}
+		if err := fn(template); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		log.Println("error iterating data from database: ", err)
+		return err
+	}
+	return nil
+}
+
+func templateHierarchyPipeline(tenantId string) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "tenantId", Value: tenantId}}}},
+		{{Key: "$graphLookup", Value: bson.D{
+			{Key: "from", Value: "templates"},
+			{Key: "startWith", Value: "$basicInformation.parent"},
+			{Key: "connectFromField", Value: "basicInformation.parent"},
+			{Key: "connectToField", Value: "basicInformation.externalId"},
+			{Key: "as", Value: "ancestors"},
+			{Key: "restrictSearchWithMatch", Value: bson.D{{Key: "tenantId", Value: tenantId}}},
+		}}},
+		{{Key: "$set", Value: bson.D{{Key: "depth", Value: bson.D{{Key: "$size", Value: "$ancestors"}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "depth", Value: 1}, {Key: "basicInformation.externalId", Value: 1}}}},
+		{{Key: "$unset", Value: bson.A{"ancestors", "depth"}}},
+	}
+}
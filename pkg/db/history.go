@@ -0,0 +1,244 @@
+package db
+
+import (
+	"api/pkg/models"
+	"bytes"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrVersionConflict    = errors.New("document was modified concurrently")
+	ErrPreconditionFailed = errors.New("document version does not match")
+)
+
+const (
+	templateHistoryCollection = "template_history"
+	instanceHistoryCollection = "instance_history"
+)
+
+func (r *repository) EnsureHistoryIndexes() error {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "basicInformation.externalId", Value: 1}, {Key: "version", Value: -1}, {Key: "deletedAt", Value: 1}},
+		Options: options.Index().SetName("tenant_externalId_version_deletedAt").SetUnique(true),
+	}
+	for _, collectionName := range []string{templateHistoryCollection, instanceHistoryCollection} {
+		collection := r.client.Database("buildifyy").Collection(collectionName)
+		if _, err := collection.Indexes().CreateOne(r.ctx, index); err != nil {
+			log.Println("error creating history index: ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) GetTemplateRevisions(filter primitive.D) ([]models.TemplateRevision, error) {
+	collection := r.client.Database("buildifyy").Collection(templateHistoryCollection)
+	cursor, err := collection.Find(r.ctx, activeRevisions(filter), options.Find().SetSort(bson.D{{Key: "version", Value: -1}}))
+	if err != nil {
+		log.Println("error finding template revisions in database: ", err)
+		return nil, err
+	}
+
+	results := make([]models.TemplateRevision, 0)
+	if err := cursor.All(r.ctx, &results); err != nil {
+		log.Println("error parsing all data from database: ", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *repository) GetInstanceRevisions(filter primitive.D) ([]models.InstanceRevision, error) {
+	collection := r.client.Database("buildifyy").Collection(instanceHistoryCollection)
+	cursor, err := collection.Find(r.ctx, activeRevisions(filter), options.Find().SetSort(bson.D{{Key: "version", Value: -1}}))
+	if err != nil {
+		log.Println("error finding instance revisions in database: ", err)
+		return nil, err
+	}
+
+	results := make([]models.InstanceRevision, 0)
+	if err := cursor.All(r.ctx, &results); err != nil {
+		log.Println("error parsing all data from database: ", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func VersionFilter(filter primitive.D, version int64) primitive.D {
+	if version == 0 {
+		return filter
+	}
+
+	versionFilter := withoutVersion(filter)
+	if version == models.InitialVersion {
+		return append(versionFilter, bson.E{Key: "version", Value: bson.D{{Key: "$in", Value: bson.A{version, nil}}}})
+	}
+	return append(versionFilter, bson.E{Key: "version", Value: version})
+}
+
+func (r *repository) replaceMissing(collectionName string, filter primitive.D, data interface{}) error {
+	if !hasVersion(filter) {
+		return r.replace(collectionName, filter, data)
+	}
+
+	collection := r.client.Database("buildifyy").Collection(collectionName)
+	count, err := collection.CountDocuments(r.ctx, withoutVersion(filter))
+	if err != nil {
+		log.Println("error counting data in database: ", err)
+		return err
+	}
+	if count > 0 {
+		return ErrPreconditionFailed
+	}
+
+	return ErrNotFound
+}
+
+func (r *repository) replaceRevision(collectionName string, historyCollectionName string, filter primitive.D, current interface{}, version int64, revision interface{}, data interface{}) (int64, error) {
+	currentDocument, err := versionedDocument(current, version)
+	if err != nil {
+		log.Println("error encoding current document: ", err)
+		return 0, err
+	}
+	replacement, err := versionedDocument(data, version)
+	if err != nil {
+		log.Println("error encoding replacement document: ", err)
+		return 0, err
+	}
+	if sameDocument(currentDocument, replacement) {
+		return version, nil
+	}
+
+	collection := r.client.Database("buildifyy").Collection(collectionName)
+	result, err := collection.ReplaceOne(r.ctx, VersionFilter(filter, version), setVersion(replacement, version+1))
+	if err != nil {
+		log.Println("error replacing data in database")
+		return 0, err
+	}
+	if result.MatchedCount == 0 {
+		if hasVersion(filter) {
+			return 0, ErrPreconditionFailed
+		}
+		return 0, ErrVersionConflict
+	}
+
+	history := r.client.Database("buildifyy").Collection(historyCollectionName)
+	if _, err := history.InsertOne(r.ctx, revision); err != nil {
+		log.Println("error archiving revision in database: ", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return 0, ErrVersionConflict
+		}
+		return 0, err
+	}
+
+	return version + 1, nil
+}
+
+func (r *repository) tombstoneTemplates(templates []models.Template) error {
+	deletedAt := time.Now().UTC()
+	for _, template := range templates {
+		template.Version = max(template.Version, models.InitialVersion)
+		revision := models.TemplateRevision{Template: template, ArchivedAt: deletedAt, DeletedAt: &deletedAt}
+		if err := r.tombstone(templateHistoryCollection, template.TenantID, template.BasicInformation.ExternalID, revision, deletedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) tombstoneInstances(instances []models.Instance) error {
+	deletedAt := time.Now().UTC()
+	for _, instance := range instances {
+		instance.Version = max(instance.Version, models.InitialVersion)
+		revision := models.InstanceRevision{Instance: instance, ArchivedAt: deletedAt, DeletedAt: &deletedAt}
+		if err := r.tombstone(instanceHistoryCollection, instance.TenantID, instance.BasicInformation.ExternalId, revision, deletedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tombstone archives the deleted document and marks its earlier revisions as
+// deleted, so a document re-created under the same externalId starts a fresh history.
+func (r *repository) tombstone(historyCollectionName string, tenantId string, externalId string, revision interface{}, deletedAt time.Time) error {
+	history := r.client.Database("buildifyy").Collection(historyCollectionName)
+	filter := bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "basicInformation.externalId", Value: externalId},
+		{Key: "deletedAt", Value: nil},
+	}
+	if _, err := history.UpdateMany(r.ctx, filter, bson.D{{Key: "$set", Value: bson.D{{Key: "deletedAt", Value: deletedAt}}}}); err != nil {
+		log.Println("error marking history as deleted in database: ", err)
+		return err
+	}
+
+	if _, err := history.InsertOne(r.ctx, revision); err != nil {
+		log.Println("error archiving deleted revision in database: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func activeRevisions(filter primitive.D) primitive.D {
+	return append(append(primitive.D{}, filter...), bson.E{Key: "deletedAt", Value: nil})
+}
+
+func versionedDocument(data interface{}, version int64) (bson.D, error) {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var document bson.D
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	return setVersion(document, version), nil
+}
+
+func setVersion(document bson.D, version int64) bson.D {
+	for i := range document {
+		if document[i].Key == "version" {
+			document[i].Value = version
+			return document
+		}
+	}
+	return append(document, bson.E{Key: "version", Value: version})
+}
+
+func sameDocument(a bson.D, b bson.D) bool {
+	rawA, errA := bson.Marshal(a)
+	rawB, errB := bson.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
+
+func hasVersion(filter primitive.D) bool {
+	for _, element := range filter {
+		if element.Key == "version" {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutVersion(filter primitive.D) primitive.D {
+	result := make(primitive.D, 0, len(filter)+1)
+	for _, element := range filter {
+		if element.Key != "version" {
+			result = append(result, element)
+		}
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"api/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestVersionedDocument_IgnoresIncomingVersion(t *testing.T) {
+	current := models.Template{TenantID: "the-binary", BasicInformation: models.TemplateBasicInformation{ExternalID: "c.pump", Name: "Pump"}, Version: 4}
+	unchanged := current
+	unchanged.Version = 0
+	renamed := current
+	renamed.BasicInformation.Name = "Water pump"
+
+	currentDocument, currentErr := versionedDocument(current, 4)
+	unchangedDocument, unchangedErr := versionedDocument(&unchanged, 4)
+	renamedDocument, renamedErr := versionedDocument(renamed, 4)
+	assert.Nil(t, currentErr)
+	assert.Nil(t, unchangedErr)
+	assert.Nil(t, renamedErr)
+
+	assert.True(t, sameDocument(currentDocument, unchangedDocument))
+	assert.False(t, sameDocument(currentDocument, renamedDocument))
+	assert.Contains(t, setVersion(renamedDocument, 5), bson.E{Key: "version", Value: int64(5)})
+}
+
+func TestVersionFilter_LegacyVersion_MatchesMissingField(t *testing.T) {
+	filter := bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "version", Value: int64(9)}}
+
+	assert.Equal(t, filter, VersionFilter(filter, 0))
+	assert.Equal(t, bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "version", Value: int64(3)}}, VersionFilter(filter, 3))
+	assert.Equal(t, bson.D{
+		{Key: "tenantId", Value: "the-binary"},
+		{Key: "version", Value: bson.D{{Key: "$in", Value: bson.A{int64(1), nil}}}},
+	}, VersionFilter(filter, models.InitialVersion))
+}
+
+func TestActiveRevisions_ExcludesTombstonedHistory(t *testing.T) {
+	filter := bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "c.pump"}}
+
+	assert.Equal(t, bson.D{
+		{Key: "tenantId", Value: "the-binary"},
+		{Key: "basicInformation.externalId", Value: "c.pump"},
+		{Key: "deletedAt", Value: nil},
+	}, activeRevisions(filter))
+	assert.Len(t, filter, 2)
+}
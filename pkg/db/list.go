@@ -0,0 +1,140 @@
+package db
+
+import (
+	"api/pkg/models"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var sortFields = map[string]string{
+	"externalId":   "basicInformation.externalId",
+	"name":         "basicInformation.name",
+	"parent":       "basicInformation.parent",
+	"rootTemplate": "basicInformation.rootTemplate",
+}
+
+func listFilter(query models.ListQuery) primitive.D {
+	filter := bson.D{{Key: "tenantId", Value: query.TenantID}}
+	if query.Parent != "" {
+		filter = append(filter, bson.E{Key: "basicInformation.parent", Value: query.Parent})
+	}
+	if query.RootTemplate != "" {
+		filter = append(filter, bson.E{Key: "basicInformation.rootTemplate", Value: query.RootTemplate})
+	}
+	if query.NamePrefix != "" {
+		filter = append(filter, bson.E{Key: "basicInformation.name", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.NamePrefix), Options: "i"}})
+	}
+
+	attributeFilters := make(bson.A, 0)
+	for _, attribute := range query.Attributes {
+		match := bson.D{{Key: "id", Value: attribute.ID}}
+		if attribute.Value != "" {
+			match = append(match, bson.E{Key: "value", Value: bson.D{{Key: "$in", Value: attributeValueCandidates(attribute.Value)}}})
+		}
+		attributeFilters = append(attributeFilters, bson.D{{Key: "attributes", Value: bson.D{{Key: "$elemMatch", Value: match}}}})
+	}
+	if len(attributeFilters) > 0 {
+		filter = append(filter, bson.E{Key: "$and", Value: attributeFilters})
+	}
+
+	return filter
+}
+
+func attributeValueCandidates(value string) bson.A {
+	candidates := bson.A{value}
+	if integerValue, err := strconv.Atoi(value); err == nil {
+		candidates = append(candidates, integerValue)
+	}
+	if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+		candidates = append(candidates, floatValue)
+	}
+	if booleanValue, err := strconv.ParseBool(strings.ToLower(value)); err == nil {
+		candidates = append(candidates, booleanValue)
+	}
+	return candidates
+}
+
+func pageFilter(filter primitive.D, query models.ListQuery) primitive.D {
+	cursor := query.Cursor
+	if cursor == nil {
+		return filter
+	}
+
+	operator := "$gt"
+	if query.Descending {
+		operator = "$lt"
+	}
+
+	field := sortFields[query.Sort]
+	if field == sortFields["externalId"] {
+		return append(filter, bson.E{Key: field, Value: bson.D{{Key: operator, Value: cursor.ExternalId}}})
+	}
+
+	return append(filter, bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: field, Value: bson.D{{Key: operator, Value: cursor.Value}}}},
+		bson.D{{Key: field, Value: cursor.Value}, {Key: "basicInformation.externalId", Value: bson.D{{Key: operator, Value: cursor.ExternalId}}}},
+	}})
+}
+
+func findOptions(query models.ListQuery) *options.FindOptions {
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+
+	sort := bson.D{{Key: sortFields[query.Sort], Value: direction}}
+	if query.Sort != "externalId" {
+		sort = append(sort, bson.E{Key: "basicInformation.externalId", Value: direction})
+	}
+
+	opts := options.Find().SetSort(sort)
+	if query.Limit > 0 {
+		opts.SetLimit(query.Limit)
+	}
+	if query.Page > 1 && query.Cursor == nil {
+		opts.SetSkip((query.Page - 1) * query.Limit)
+	}
+	return opts
+}
+
+func nextPageToken(query models.ListQuery, count int, lastSortValue string, lastExternalId string) string {
+	if query.Limit == 0 || int64(count) < query.Limit {
+		return ""
+	}
+
+	return models.ListCursor{
+		Sort:       query.Sort,
+		Descending: query.Descending,
+		Value:      lastSortValue,
+		ExternalId: lastExternalId,
+	}.Encode()
+}
+
+func instanceSortValue(instance models.Instance, sort string) string {
+	switch sort {
+	case "name":
+		return instance.BasicInformation.Name
+	case "parent":
+		return instance.BasicInformation.Parent
+	case "rootTemplate":
+		return instance.BasicInformation.RootTemplate
+	}
+	return instance.BasicInformation.ExternalId
+}
+
+func templateSortValue(template models.Template, sort string) string {
+	switch sort {
+	case "name":
+		return template.BasicInformation.Name
+	case "parent":
+		return template.BasicInformation.Parent
+	case "rootTemplate":
+		return template.BasicInformation.RootTemplate
+	}
+	return template.BasicInformation.ExternalID
+}
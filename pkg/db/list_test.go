@@ -0,0 +1,48 @@
+package db
+
+import (
+	"api/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestListFilter_TranslatesFieldFilters(t *testing.T) {
+	query := models.ListQuery{
+		TenantID:   "the-binary",
+		Parent:     "testtemplate1",
+		NamePrefix: "Pump.",
+		Attributes: []models.AttributeFilter{{ID: "a1", Value: "true"}},
+	}
+
+	actual := listFilter(query)
+	assert.Equal(t, bson.D{
+		{Key: "tenantId", Value: "the-binary"},
+		{Key: "basicInformation.parent", Value: "testtemplate1"},
+		{Key: "basicInformation.name", Value: primitive.Regex{Pattern: "^Pump\\.", Options: "i"}},
+		{Key: "$and", Value: bson.A{
+			bson.D{{Key: "attributes", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+				{Key: "id", Value: "a1"},
+				{Key: "value", Value: bson.D{{Key: "$in", Value: bson.A{"true", true}}}},
+			}}}}},
+		}},
+	}, actual)
+}
+
+func TestPageFilter_Cursor_ContinuesAfterLastItem(t *testing.T) {
+	query := models.ListQuery{Limit: 2, Sort: "name"}
+	query.Cursor, _ = models.DecodeListCursor(nextPageToken(query, 2, "Pump", "pump2"))
+
+	actual := pageFilter(bson.D{}, query)
+	assert.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "basicInformation.name", Value: bson.D{{Key: "$gt", Value: "Pump"}}}},
+		bson.D{{Key: "basicInformation.name", Value: "Pump"}, {Key: "basicInformation.externalId", Value: bson.D{{Key: "$gt", Value: "pump2"}}}},
+	}}}, actual)
+}
+
+func TestNextPageToken_LastPage_ReturnsEmptyToken(t *testing.T) {
+	assert.Equal(t, "", nextPageToken(models.ListQuery{Limit: 10}, 3, "", "pump3"))
+	assert.Equal(t, "", nextPageToken(models.ListQuery{}, 3, "", "pump3"))
+}
@@ -17,6 +17,10 @@ func (m *MockedDbRepository) Ping() error {
 	return args.Error(0)
 }
 
+func (m *MockedDbRepository) WithTransaction(fn func(tx Repository) error) error {
+	return fn(m)
+}
+
 func (m *MockedDbRepository) AddOne(collectionName string, data interface{}) error {
 	args := m.Called(collectionName, data)
 	return args.Error(0)
@@ -38,6 +42,22 @@ func (m *MockedDbRepository) GetAllInstances(filter primitive.D, options *option
 	return args.Get(0).([]models.Instance), args.Error(1)
 }
 
+func (m *MockedDbRepository) ListTemplates(query models.ListQuery) (*models.TemplatePage, error) {
+	args := m.Called(query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TemplatePage), args.Error(1)
+}
+
+func (m *MockedDbRepository) ListInstances(query models.ListQuery) (*models.InstancePage, error) {
+	args := m.Called(query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InstancePage), args.Error(1)
+}
+
 func (m *MockedDbRepository) GetTemplate(filter primitive.D) (*models.Template, error) {
 	args := m.Called(filter)
 	if args.Get(0) == nil {
@@ -70,12 +90,152 @@ func (m *MockedDbRepository) GetRelationships(filter primitive.D, collection str
 	return args.Get(0).([]models.Relationship), args.Error(1)
 }
 
-func (m *MockedDbRepository) ReplaceTemplate(filter primitive.D, data interface{}) error {
+func (m *MockedDbRepository) ReplaceTemplate(filter primitive.D, data interface{}) (int64, error) {
 	args := m.Called(filter, data)
-	return args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockedDbRepository) ReplaceInstance(filter primitive.D, data interface{}) error {
+func (m *MockedDbRepository) ReplaceInstance(filter primitive.D, data interface{}) (int64, error) {
 	args := m.Called(filter, data)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockedDbRepository) DeleteInstance(filter primitive.D) error {
+	args := m.Called(filter)
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) DeleteInstances(filter primitive.D) error {
+	args := m.Called(filter)
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) DeleteTemplates(filter primitive.D) error {
+	args := m.Called(filter)
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) EnsureTextIndexes() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) Search(collectionName string, tenantId string, text string, limit int64) ([]models.SearchHit, error) {
+	args := m.Called(collectionName, tenantId, text, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SearchHit), args.Error(1)
+}
+
+func (m *MockedDbRepository) EnsureMetricPointsCollection() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) AddMetricPoints(points []models.MetricPoint) error {
+	args := m.Called(points)
 	return args.Error(0)
 }
+
+func (m *MockedDbRepository) GetLatestMetricPoints(filter primitive.D) ([]models.MetricPoint, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MetricPoint), args.Error(1)
+}
+
+func (m *MockedDbRepository) GetMetricSeries(filter primitive.D, query models.SeriesQuery) ([]models.SeriesBucket, error) {
+	args := m.Called(filter, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SeriesBucket), args.Error(1)
+}
+
+func (m *MockedDbRepository) StreamInstances(filter primitive.D, fn func(instance models.Instance) error) error {
+	args := m.Called(filter)
+	if instances, ok := args.Get(0).([]models.Instance); ok {
+		for _, instance := range instances {
+			if err := fn(instance); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockedDbRepository) StreamTemplateHierarchy(tenantId string, fn func(template models.Template) error) error {
+	args := m.Called(tenantId)
+	if templates, ok := args.Get(0).([]models.Template); ok {
+		for _, template := range templates {
+			if err := fn(template); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockedDbRepository) EnsureRoleAssignmentIndexes() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) GetRoleAssignments(filter primitive.D) ([]models.RoleAssignment, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RoleAssignment), args.Error(1)
+}
+
+func (m *MockedDbRepository) UpsertRoleAssignment(assignment models.RoleAssignment) error {
+	args := m.Called(assignment)
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) DeleteRoleAssignment(filter primitive.D) error {
+	args := m.Called(filter)
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) EnsureAuditIndexes() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) AddAuditEvents(events []models.AuditEvent) error {
+	args := m.Called(events)
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) GetAuditEvents(filter primitive.D, limit int64) ([]models.AuditEvent, error) {
+	args := m.Called(filter, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+func (m *MockedDbRepository) EnsureHistoryIndexes() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockedDbRepository) GetTemplateRevisions(filter primitive.D) ([]models.TemplateRevision, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TemplateRevision), args.Error(1)
+}
+
+func (m *MockedDbRepository) GetInstanceRevisions(filter primitive.D) ([]models.InstanceRevision, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.InstanceRevision), args.Error(1)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"log"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,44 +14,141 @@ import (
 )
 
 var ErrDuplicateExternalId = errors.New("externalId already exists")
+var ErrNotFound = errors.New("document not found")
+var ErrRollback = errors.New("transaction rolled back")
 
 type Repository interface {
 	Ping() error
+	WithTransaction(fn func(tx Repository) error) error
 	AddOne(collectionName string, data interface{}) error
 	GetAllTemplates(filter primitive.D, options *options.FindOptions) ([]models.Template, error)
 	GetAllInstances(filter primitive.D, options *options.FindOptions) ([]models.Instance, error)
+	ListTemplates(query models.ListQuery) (*models.TemplatePage, error)
+	ListInstances(query models.ListQuery) (*models.InstancePage, error)
 	GetTemplate(filter primitive.D) (*models.Template, error)
 	GetInstance(filter primitive.D) (*models.Instance, error)
 	GetTypeDropdownValues(collection string) ([]models.Dropdown, error)
 	GetRelationships(filter primitive.D, collection string) ([]models.Relationship, error)
-	ReplaceTemplate(filter primitive.D, data interface{}) error
-	ReplaceInstance(filter primitive.D, data interface{}) error
+	ReplaceTemplate(filter primitive.D, data interface{}) (int64, error)
+	ReplaceInstance(filter primitive.D, data interface{}) (int64, error)
+	DeleteInstance(filter primitive.D) error
+	DeleteInstances(filter primitive.D) error
+	DeleteTemplates(filter primitive.D) error
+	EnsureTextIndexes() error
+	Search(collectionName string, tenantId string, text string, limit int64) ([]models.SearchHit, error)
+	EnsureMetricPointsCollection() error
+	AddMetricPoints(points []models.MetricPoint) error
+	GetLatestMetricPoints(filter primitive.D) ([]models.MetricPoint, error)
+	GetMetricSeries(filter primitive.D, query models.SeriesQuery) ([]models.SeriesBucket, error)
+	StreamInstances(filter primitive.D, fn func(instance models.Instance) error) error
+	StreamTemplateHierarchy(tenantId string, fn func(template models.Template) error) error
+	EnsureRoleAssignmentIndexes() error
+	GetRoleAssignments(filter primitive.D) ([]models.RoleAssignment, error)
+	UpsertRoleAssignment(assignment models.RoleAssignment) error
+	DeleteRoleAssignment(filter primitive.D) error
+	EnsureHistoryIndexes() error
+	GetTemplateRevisions(filter primitive.D) ([]models.TemplateRevision, error)
+	GetInstanceRevisions(filter primitive.D) ([]models.InstanceRevision, error)
+	EnsureAuditIndexes() error
+	AddAuditEvents(events []models.AuditEvent) error
+	GetAuditEvents(filter primitive.D, limit int64) ([]models.AuditEvent, error)
 }
 
 type repository struct {
 	client *mongo.Client
+	ctx    context.Context
 }
 
 func NewRepository(client *mongo.Client) Repository {
 	return &repository{
 		client: client,
+		ctx:    context.Background(),
 	}
 }
 
-func (r *repository) ReplaceTemplate(filter primitive.D, data interface{}) error {
-	collection := r.client.Database("buildifyy").Collection("templates")
-	_, err := collection.ReplaceOne(context.Background(), filter, data)
+func (r *repository) WithTransaction(fn func(tx Repository) error) error {
+	if _, ok := r.ctx.(mongo.SessionContext); ok {
+		return fn(r)
+	}
+
+	session, err := r.client.StartSession()
 	if err != nil {
-		log.Println("error replacing data in database")
+		log.Println("error starting database session: ", err)
+		return err
+	}
+	defer session.EndSession(r.ctx)
+
+	_, err = session.WithTransaction(r.ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		return nil, fn(&repository{
+			client: r.client,
+			ctx:    sessionContext,
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, ErrRollback) {
+			log.Println("error running database transaction: ", err)
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (r *repository) ReplaceInstance(filter primitive.D, data interface{}) error {
-	collection := r.client.Database("buildifyy").Collection("instances")
-	_, err := collection.ReplaceOne(context.Background(), filter, data)
+func (r *repository) inTransaction(fn func(tx *repository) error) error {
+	return r.WithTransaction(func(tx Repository) error {
+		return fn(tx.(*repository))
+	})
+}
+
+func (r *repository) ReplaceTemplate(filter primitive.D, data interface{}) (int64, error) {
+	var version int64
+	err := r.inTransaction(func(tx *repository) error {
+		existingTemplate, err := tx.GetTemplate(filter)
+		if errors.Is(err, ErrNotFound) {
+			return tx.replaceMissing("templates", filter, data)
+		}
+		if err != nil {
+			return err
+		}
+
+		existingTemplate.Version = max(existingTemplate.Version, models.InitialVersion)
+		revision := models.TemplateRevision{Template: *existingTemplate, ArchivedAt: time.Now().UTC()}
+		version, err = tx.replaceRevision("templates", templateHistoryCollection, filter, existingTemplate, existingTemplate.Version, revision, data)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func (r *repository) ReplaceInstance(filter primitive.D, data interface{}) (int64, error) {
+	var version int64
+	err := r.inTransaction(func(tx *repository) error {
+		existingInstance, err := tx.GetInstance(filter)
+		if errors.Is(err, ErrNotFound) {
+			return tx.replaceMissing("instances", filter, data)
+		}
+		if err != nil {
+			return err
+		}
+
+		existingInstance.Version = max(existingInstance.Version, models.InitialVersion)
+		revision := models.InstanceRevision{Instance: *existingInstance, ArchivedAt: time.Now().UTC()}
+		version, err = tx.replaceRevision("instances", instanceHistoryCollection, filter, existingInstance, existingInstance.Version, revision, data)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func (r *repository) replace(collectionName string, filter primitive.D, data interface{}) error {
+	collection := r.client.Database("buildifyy").Collection(collectionName)
+	_, err := collection.ReplaceOne(r.ctx, filter, data)
 	if err != nil {
 		log.Println("error replacing data in database")
 		return err
@@ -59,19 +157,75 @@ func (r *repository) ReplaceInstance(filter primitive.D, data interface{}) error
 	return nil
 }
 
+func (r *repository) DeleteInstance(filter primitive.D) error {
+	return r.inTransaction(func(tx *repository) error {
+		instance, err := tx.GetInstance(filter)
+		if err != nil {
+			return err
+		}
+
+		collection := tx.client.Database("buildifyy").Collection("instances")
+		result, err := collection.DeleteOne(tx.ctx, filter)
+		if err != nil {
+			log.Println("error deleting data from database: ", err)
+			return err
+		}
+
+		if result.DeletedCount == 0 {
+			return ErrNotFound
+		}
+
+		return tx.tombstoneInstances([]models.Instance{*instance})
+	})
+}
+
+func (r *repository) DeleteInstances(filter primitive.D) error {
+	return r.inTransaction(func(tx *repository) error {
+		instances, err := tx.GetAllInstances(filter, nil)
+		if err != nil {
+			return err
+		}
+
+		collection := tx.client.Database("buildifyy").Collection("instances")
+		if _, err := collection.DeleteMany(tx.ctx, filter); err != nil {
+			log.Println("error deleting data from database: ", err)
+			return err
+		}
+
+		return tx.tombstoneInstances(instances)
+	})
+}
+
+func (r *repository) DeleteTemplates(filter primitive.D) error {
+	return r.inTransaction(func(tx *repository) error {
+		templates, err := tx.GetAllTemplates(filter, nil)
+		if err != nil {
+			return err
+		}
+
+		collection := tx.client.Database("buildifyy").Collection("templates")
+		if _, err := collection.DeleteMany(tx.ctx, filter); err != nil {
+			log.Println("error deleting data from database: ", err)
+			return err
+		}
+
+		return tx.tombstoneTemplates(templates)
+	})
+}
+
 func (r *repository) GetRelationships(filter primitive.D, collection string) ([]models.Relationship, error) {
 	c := r.client.Database("buildifyy").Collection(collection)
 	if filter == nil {
 		filter = primitive.D{}
 	}
-	cursor, err := c.Find(context.Background(), filter, nil)
+	cursor, err := c.Find(r.ctx, filter, nil)
 	if err != nil {
 		log.Println("error finding relationships in database: ", err)
 		return nil, err
 	}
 
 	var results []models.Relationship
-	if err := cursor.All(context.Background(), &results); err != nil {
+	if err := cursor.All(r.ctx, &results); err != nil {
 		log.Println("error parsing all data from database: ", err)
 		return nil, err
 	}
@@ -82,14 +236,14 @@ func (r *repository) GetRelationships(filter primitive.D, collection string) ([]
 func (r *repository) GetTypeDropdownValues(collection string) ([]models.Dropdown, error) {
 	c := r.client.Database("buildifyy").Collection(collection)
 	opts := options.Find().SetSort(bson.D{{Key: "label", Value: 1}})
-	cursor, err := c.Find(context.Background(), bson.D{}, opts)
+	cursor, err := c.Find(r.ctx, bson.D{}, opts)
 	if err != nil {
 		log.Println("error finding dropdown values in database: ", err)
 		return nil, err
 	}
 
 	var results []models.Dropdown
-	if err := cursor.All(context.Background(), &results); err != nil {
+	if err := cursor.All(r.ctx, &results); err != nil {
 		log.Println("error parsing all data from database: ", err)
 		return nil, err
 	}
@@ -98,7 +252,7 @@ func (r *repository) GetTypeDropdownValues(collection string) ([]models.Dropdown
 }
 
 func (r *repository) Ping() error {
-	if err := r.client.Database("admin").RunCommand(context.Background(), bson.D{{"ping", 1}}).Err(); err != nil {
+	if err := r.client.Database("admin").RunCommand(r.ctx, bson.D{{Key: "ping", Value: 1}}).Err(); err != nil {
 		log.Println("error pinging database: ", err)
 		return err
 	}
@@ -108,14 +262,14 @@ func (r *repository) Ping() error {
 
 func (r *repository) GetAllTemplates(filter primitive.D, options *options.FindOptions) ([]models.Template, error) {
 	collection := r.client.Database("buildifyy").Collection("templates")
-	cursor, err := collection.Find(context.Background(), filter, options)
+	cursor, err := collection.Find(r.ctx, filter, options)
 	if err != nil {
 		log.Println("error finding data in database: ", err)
 		return nil, err
 	}
 
 	var results []models.Template
-	if err := cursor.All(context.Background(), &results); err != nil {
+	if err := cursor.All(r.ctx, &results); err != nil {
 		log.Println("error parsing all data from database: ", err)
 		return nil, err
 	}
@@ -125,14 +279,14 @@ func (r *repository) GetAllTemplates(filter primitive.D, options *options.FindOp
 
 func (r *repository) GetAllInstances(filter primitive.D, options *options.FindOptions) ([]models.Instance, error) {
 	collection := r.client.Database("buildifyy").Collection("instances")
-	cursor, err := collection.Find(context.Background(), filter, options)
+	cursor, err := collection.Find(r.ctx, filter, options)
 	if err != nil {
 		log.Println("error finding data in database: ", err)
 		return nil, err
 	}
 
 	var results []models.Instance
-	if err := cursor.All(context.Background(), &results); err != nil {
+	if err := cursor.All(r.ctx, &results); err != nil {
 		log.Println("error parsing all data from database: ", err)
 		return nil, err
 	}
@@ -140,12 +294,75 @@ func (r *repository) GetAllInstances(filter primitive.D, options *options.FindOp
 	return results, nil
 }
 
+func (r *repository) ListTemplates(query models.ListQuery) (*models.TemplatePage, error) {
+	collection := r.client.Database("buildifyy").Collection("templates")
+	filter := listFilter(query)
+
+	total, err := collection.CountDocuments(r.ctx, filter)
+	if err != nil {
+		log.Println("error counting data in database: ", err)
+		return nil, err
+	}
+
+	templates, err := r.GetAllTemplates(pageFilter(filter, query), findOptions(query))
+	if err != nil {
+		return nil, err
+	}
+	if templates == nil {
+		templates = make([]models.Template, 0)
+	}
+
+	page := &models.TemplatePage{
+		Items: templates,
+		Total: total,
+	}
+	if len(templates) > 0 {
+		last := templates[len(templates)-1]
+		page.NextPageToken = nextPageToken(query, len(templates), templateSortValue(last, query.Sort), last.BasicInformation.ExternalID)
+	}
+
+	return page, nil
+}
+
+func (r *repository) ListInstances(query models.ListQuery) (*models.InstancePage, error) {
+	collection := r.client.Database("buildifyy").Collection("instances")
+	filter := listFilter(query)
+
+	total, err := collection.CountDocuments(r.ctx, filter)
+	if err != nil {
+		log.Println("error counting data in database: ", err)
+		return nil, err
+	}
+
+	instances, err := r.GetAllInstances(pageFilter(filter, query), findOptions(query))
+	if err != nil {
+		return nil, err
+	}
+	if instances == nil {
+		instances = make([]models.Instance, 0)
+	}
+
+	page := &models.InstancePage{
+		Items: instances,
+		Total: total,
+	}
+	if len(instances) > 0 {
+		last := instances[len(instances)-1]
+		page.NextPageToken = nextPageToken(query, len(instances), instanceSortValue(last, query.Sort), last.BasicInformation.ExternalId)
+	}
+
+	return page, nil
+}
+
 func (r *repository) GetInstance(filter primitive.D) (*models.Instance, error) {
 	collection := r.client.Database("buildifyy").Collection("instances")
 
 	var result models.Instance
-	if err := collection.FindOne(context.Background(), filter).Decode(&result); err != nil {
+	if err := collection.FindOne(r.ctx, filter).Decode(&result); err != nil {
 		log.Println("error finding data in database: ", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -156,8 +373,11 @@ func (r *repository) GetTemplate(filter primitive.D) (*models.Template, error) {
 	collection := r.client.Database("buildifyy").Collection("templates")
 
 	var result models.Template
-	if err := collection.FindOne(context.Background(), filter).Decode(&result); err != nil {
+	if err := collection.FindOne(r.ctx, filter).Decode(&result); err != nil {
 		log.Println("error finding data in database: ", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -166,7 +386,7 @@ func (r *repository) GetTemplate(filter primitive.D) (*models.Template, error) {
 
 func (r *repository) AddOne(collectionName string, data interface{}) error {
 	collection := r.client.Database("buildifyy").Collection(collectionName)
-	_, err := collection.InsertOne(context.Background(), data)
+	_, err := collection.InsertOne(r.ctx, data)
 	if err != nil {
 		log.Println("error inserting data to database")
 		if mongo.IsDuplicateKeyError(err) {
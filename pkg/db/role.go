@@ -0,0 +1,69 @@
+package db
+
+import (
+	"api/pkg/models"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const roleAssignmentsCollection = "role_assignments"
+
+func (r *repository) EnsureRoleAssignmentIndexes() error {
+	collection := r.client.Database("buildifyy").Collection(roleAssignmentsCollection)
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "subject", Value: 1}},
+		Options: options.Index().SetName("tenant_subject").SetUnique(true),
+	}
+	if _, err := collection.Indexes().CreateOne(r.ctx, index); err != nil {
+		log.Println("error creating role assignment index: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetRoleAssignments(filter primitive.D) ([]models.RoleAssignment, error) {
+	collection := r.client.Database("buildifyy").Collection(roleAssignmentsCollection)
+	cursor, err := collection.Find(r.ctx, filter, options.Find().SetSort(bson.D{{Key: "subject", Value: 1}}))
+	if err != nil {
+		log.Println("error finding role assignments in database: ", err)
+		return nil, err
+	}
+
+	var results []models.RoleAssignment
+	if err := cursor.All(r.ctx, &results); err != nil {
+		log.Println("error parsing all data from database: ", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *repository) UpsertRoleAssignment(assignment models.RoleAssignment) error {
+	collection := r.client.Database("buildifyy").Collection(roleAssignmentsCollection)
+	filter := bson.D{{Key: "tenantId", Value: assignment.TenantID}, {Key: "subject", Value: assignment.Subject}}
+	if _, err := collection.ReplaceOne(r.ctx, filter, assignment, options.Replace().SetUpsert(true)); err != nil {
+		log.Println("error upserting role assignment in database: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteRoleAssignment(filter primitive.D) error {
+	collection := r.client.Database("buildifyy").Collection(roleAssignmentsCollection)
+	result, err := collection.DeleteOne(r.ctx, filter)
+	if err != nil {
+		log.Println("error deleting role assignment from database: ", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"api/pkg/models"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const prefixMatchScore = 0.5
+
+type searchDocument struct {
+	BasicInformation struct {
+		Name         string `bson:"name"`
+		ExternalId   string `bson:"externalId"`
+		Parent       string `bson:"parent"`
+		RootTemplate string `bson:"rootTemplate"`
+	} `bson:"basicInformation"`
+	Score float64 `bson:"score"`
+}
+
+func (r *repository) EnsureTextIndexes() error {
+	// The name index backs the case-insensitive prefix fallback in Search.
+	namePrefixIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "basicInformation.name", Value: 1}},
+		Options: options.Index().SetName("search_name_prefix"),
+	}
+	indexes := map[string][]mongo.IndexModel{
+		"templates": {
+			{
+				Keys: bson.D{
+					{Key: "tenantId", Value: 1},
+					{Key: "basicInformation.name", Value: "text"},
+					{Key: "basicInformation.externalId", Value: "text"},
+				},
+				Options: options.Index().SetName("search_text").SetWeights(bson.D{
+					{Key: "basicInformation.name", Value: 10},
+					{Key: "basicInformation.externalId", Value: 5},
+				}),
+			},
+			namePrefixIndex,
+		},
+		"instances": {
+			{
+				Keys: bson.D{
+					{Key: "tenantId", Value: 1},
+					{Key: "basicInformation.name", Value: "text"},
+					{Key: "basicInformation.externalId", Value: "text"},
+					{Key: "attributes.value", Value: "text"},
+				},
+				Options: options.Index().SetName("search_text").SetWeights(bson.D{
+					{Key: "basicInformation.name", Value: 10},
+					{Key: "basicInformation.externalId", Value: 5},
+					{Key: "attributes.value", Value: 1},
+				}),
+			},
+			namePrefixIndex,
+		},
+	}
+
+	for collectionName, collectionIndexes := range indexes {
+		collection := r.client.Database("buildifyy").Collection(collectionName)
+		if _, err := collection.Indexes().CreateMany(r.ctx, collectionIndexes); err != nil {
+			log.Printf("error creating search indexes on %s: %s", collectionName, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) Search(collectionName string, tenantId string, text string, limit int64) ([]models.SearchHit, error) {
+	collection := r.client.Database("buildifyy").Collection(collectionName)
+
+	textFilter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "$text", Value: bson.D{{Key: "$search", Value: text}}}}
+	textOptions := options.Find().
+		SetProjection(bson.D{{Key: "basicInformation", Value: 1}, {Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}).
+		SetSort(bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}).
+		SetLimit(limit)
+	textMatches, err := r.findSearchDocuments(collection, textFilter, textOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixOptions := options.Find().
+		SetProjection(bson.D{{Key: "basicInformation", Value: 1}}).
+		SetSort(bson.D{{Key: "basicInformation.externalId", Value: 1}}).
+		SetLimit(limit)
+	prefixMatches, err := r.findSearchDocuments(collection, prefixFilter(tenantId, text), prefixOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]models.SearchHit, 0, len(textMatches)+len(prefixMatches))
+	for _, document := range textMatches {
+		hits = append(hits, searchHit(collectionName, document, document.Score))
+	}
+	for _, document := range prefixMatches {
+		if slices.ContainsFunc(hits, func(hit models.SearchHit) bool {
+			return hit.ExternalId == document.BasicInformation.ExternalId
+		}) {
+			continue
+		}
+		hits = append(hits, searchHit(collectionName, document, prefixMatchScore))
+	}
+
+	return hits, nil
+}
+
+func prefixFilter(tenantId string, text string) primitive.D {
+	return bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "basicInformation.name", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(text), Options: "i"}}},
+			bson.D{{Key: "basicInformation.externalId", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(strings.ToLower(text))}}},
+		}},
+	}
+}
+
+func (r *repository) findSearchDocuments(collection *mongo.Collection, filter primitive.D, opts *options.FindOptions) ([]searchDocument, error) {
+	cursor, err := collection.Find(r.ctx, filter, opts)
+	if err != nil {
+		log.Println("error searching data in database: ", err)
+		return nil, err
+	}
+
+	var results []searchDocument
+	if err := cursor.All(r.ctx, &results); err != nil {
+		log.Println("error parsing all data from database: ", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func searchHit(collectionName string, document searchDocument, score float64) models.SearchHit {
+	kind := "instance"
+	if collectionName == "templates" {
+		kind = "template"
+	}
+
+	return models.SearchHit{
+		Kind:         kind,
+		ExternalId:   document.BasicInformation.ExternalId,
+		Name:         document.BasicInformation.Name,
+		Parent:       document.BasicInformation.Parent,
+		RootTemplate: document.BasicInformation.RootTemplate,
+		Score:        score,
+	}
+}
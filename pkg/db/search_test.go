@@ -0,0 +1,20 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPrefixFilter_MatchesNameCaseInsensitively(t *testing.T) {
+	actual := prefixFilter("the-binary", "Pump.A")
+	assert.Equal(t, bson.D{
+		{Key: "tenantId", Value: "the-binary"},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "basicInformation.name", Value: primitive.Regex{Pattern: "^Pump\\.A", Options: "i"}}},
+			bson.D{{Key: "basicInformation.externalId", Value: primitive.Regex{Pattern: "^pump\\.a"}}},
+		}},
+	}, actual)
+}
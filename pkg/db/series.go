@@ -0,0 +1,213 @@
+package db
+
+import (
+	"api/pkg/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidSeriesQuery = errors.New("invalid series query")
+
+const (
+	metricPointsCollection   = "metric_points"
+	namespaceExistsErrorCode = 48
+	defaultSeriesSpan        = 24 * time.Hour
+	defaultSeriesBuckets     = 100
+	maxSeriesBuckets         = 10000
+)
+
+var seriesAggregations = map[string]string{
+	"avg":  "$avg",
+	"min":  "$min",
+	"max":  "$max",
+	"sum":  "$sum",
+	"last": "$last",
+}
+
+func ParseSeriesQuery(values url.Values, now time.Time) (models.SeriesQuery, error) {
+	query := models.SeriesQuery{
+		To:          now.UTC(),
+		Aggregation: "avg",
+		Unit:        values.Get("unit"),
+	}
+
+	if to := values.Get("to"); to != "" {
+		parsedTo, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, fmt.Errorf("%w: to must be an RFC3339 timestamp", ErrInvalidSeriesQuery)
+		}
+		query.To = parsedTo.UTC()
+	}
+
+	query.From = query.To.Add(-defaultSeriesSpan)
+	if from := values.Get("from"); from != "" {
+		parsedFrom, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, fmt.Errorf("%w: from must be an RFC3339 timestamp", ErrInvalidSeriesQuery)
+		}
+		query.From = parsedFrom.UTC()
+	}
+	if !query.From.Before(query.To) {
+		return query, fmt.Errorf("%w: from must be before to", ErrInvalidSeriesQuery)
+	}
+
+	span := query.To.Sub(query.From)
+	query.Interval = max(time.Second, (span / defaultSeriesBuckets).Truncate(time.Second))
+	if interval := values.Get("interval"); interval != "" {
+		parsedInterval, err := time.ParseDuration(interval)
+		if err != nil || parsedInterval < time.Second {
+			return query, fmt.Errorf("%w: interval must be a duration of at least 1s", ErrInvalidSeriesQuery)
+		}
+		query.Interval = parsedInterval
+	}
+	if span/query.Interval >= maxSeriesBuckets {
+		return query, fmt.Errorf("%w: at most %d buckets can be requested", ErrInvalidSeriesQuery, maxSeriesBuckets)
+	}
+
+	if aggregation := values.Get("agg"); aggregation != "" {
+		if _, ok := seriesAggregations[aggregation]; !ok {
+			return query, fmt.Errorf("%w: agg must be one of avg, min, max, sum, last", ErrInvalidSeriesQuery)
+		}
+		query.Aggregation = aggregation
+	}
+
+	return query, nil
+}
+
+func (r *repository) EnsureMetricPointsCollection() error {
+	timeSeriesOptions := options.TimeSeries().SetTimeField("timestamp").SetMetaField("meta").SetGranularity("seconds")
+	err := r.client.Database("buildifyy").CreateCollection(r.ctx, metricPointsCollection, options.CreateCollection().SetTimeSeriesOptions(timeSeriesOptions))
+	if err != nil {
+		var commandErr mongo.CommandError
+		if errors.As(err, &commandErr) && commandErr.Code == namespaceExistsErrorCode {
+			return nil
+		}
+		log.Println("error creating metric points collection: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) AddMetricPoints(points []models.MetricPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(points))
+	for _, point := range points {
+		documents = append(documents, point)
+	}
+
+	collection := r.client.Database("buildifyy").Collection(metricPointsCollection)
+	if _, err := collection.InsertMany(r.ctx, documents); err != nil {
+		log.Println("error inserting metric points to database: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) GetLatestMetricPoints(filter primitive.D) ([]models.MetricPoint, error) {
+	collection := r.client.Database("buildifyy").Collection(metricPointsCollection)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "instanceId", Value: "$meta.instanceId"}, {Key: "metricId", Value: "$meta.metricId"}}},
+			{Key: "point", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$point"}}}},
+	}
+
+	cursor, err := collection.Aggregate(r.ctx, pipeline)
+	if err != nil {
+		log.Println("error aggregating metric points in database: ", err)
+		return nil, err
+	}
+
+	var results []models.MetricPoint
+	if err := cursor.All(r.ctx, &results); err != nil {
+		log.Println("error parsing all data from database: ", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *repository) GetMetricSeries(filter primitive.D, query models.SeriesQuery) ([]models.SeriesBucket, error) {
+	collection := r.client.Database("buildifyy").Collection(metricPointsCollection)
+	cursor, err := collection.Aggregate(r.ctx, seriesPipeline(filter, query))
+	if err != nil {
+		log.Println("error aggregating metric series in database: ", err)
+		return nil, err
+	}
+
+	var results []models.SeriesBucket
+	if err := cursor.All(r.ctx, &results); err != nil {
+		log.Println("error parsing all data from database: ", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func seriesPipeline(filter primitive.D, query models.SeriesQuery) mongo.Pipeline {
+	match := append(slices.Clone(filter), bson.E{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: query.From}, {Key: "$lt", Value: query.To}}})
+	bucket := bson.D{{Key: "$subtract", Value: bson.A{
+		"$timestamp",
+		bson.D{{Key: "$mod", Value: bson.A{
+			bson.D{{Key: "$subtract", Value: bson.A{"$timestamp", query.From}}},
+			query.Interval.Milliseconds(),
+		}}},
+	}}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: 1}}}},
+	}
+	if len(query.Conversions) > 0 {
+		branches := bson.A{}
+		for _, conversion := range query.Conversions {
+			branches = append(branches, bson.D{
+				{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{"$meta.unit", conversion.Unit}}}},
+				{Key: "then", Value: bson.D{{Key: "$add", Value: bson.A{
+					bson.D{{Key: "$multiply", Value: bson.A{"$value", conversion.Factor}}},
+					conversion.Offset,
+				}}}},
+			})
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: bson.D{{Key: "$switch", Value: bson.D{
+			{Key: "branches", Value: branches},
+			{Key: "default", Value: "$value"},
+		}}}}}}})
+	}
+
+	// Points are aggregated per instance first so an instance that reports more often
+	// does not outweigh the others when buckets are combined across instances.
+	aggregation := seriesAggregations[query.Aggregation]
+	return append(pipeline,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "instanceId", Value: "$meta.instanceId"}, {Key: "bucket", Value: bucket}}},
+			{Key: "value", Value: bson.D{{Key: aggregation, Value: "$value"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "timestamp", Value: bson.D{{Key: "$max", Value: "$timestamp"}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: 1}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$_id.bucket"},
+			{Key: "value", Value: bson.D{{Key: aggregation, Value: "$value"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	)
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"api/pkg/models"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseSeriesQuery_Success_ParsesAllParameters(t *testing.T) {
+	values, _ := url.ParseQuery("from=2024-03-01T00:00:00Z&to=2024-03-01T12:00:00%2B01:00&interval=15m&agg=max")
+
+	actual, actualErr := ParseSeriesQuery(values, time.Now())
+	assert.Nil(t, actualErr)
+	assert.Equal(t, models.SeriesQuery{
+		From:        time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC),
+		Interval:    15 * time.Minute,
+		Aggregation: "max",
+	}, actual)
+}
+
+func TestParseSeriesQuery_Defaults_LastDayInHundredBucketsAveraged(t *testing.T) {
+	now := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	actual, actualErr := ParseSeriesQuery(url.Values{}, now)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, models.SeriesQuery{
+		From:        time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:          now,
+		Interval:    14*time.Minute + 24*time.Second,
+		Aggregation: "avg",
+	}, actual)
+}
+
+func TestParseSeriesQuery_Invalid_ReturnsError(t *testing.T) {
+	for _, rawQuery := range []string{
+		"from=yesterday",
+		"to=2024-03-01",
+		"from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+		"interval=abc",
+		"interval=500ms",
+		"from=2024-01-01T00:00:00Z&to=2024-03-01T00:00:00Z&interval=1s",
+		"agg=median",
+	} {
+		values, _ := url.ParseQuery(rawQuery)
+		_, actualErr := ParseSeriesQuery(values, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))
+		assert.ErrorIs(t, actualErr, ErrInvalidSeriesQuery, rawQuery)
+	}
+}
+
+func TestSeriesPipeline_BucketsFromQueryStart(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	filter := bson.D{{Key: "meta.tenantId", Value: "the-binary"}}
+
+	actual := seriesPipeline(filter, models.SeriesQuery{From: from, To: to, Interval: 5 * time.Minute, Aggregation: "last"})
+	assert.Equal(t, bson.D{{Key: "$match", Value: bson.D{
+		{Key: "meta.tenantId", Value: "the-binary"},
+		{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}},
+	}}}, actual[0])
+	assert.Equal(t, bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: bson.D{
+			{Key: "instanceId", Value: "$meta.instanceId"},
+			{Key: "bucket", Value: bson.D{{Key: "$subtract", Value: bson.A{
+				"$timestamp",
+				bson.D{{Key: "$mod", Value: bson.A{bson.D{{Key: "$subtract", Value: bson.A{"$timestamp", from}}}, int64(300000)}}},
+			}}}},
+		}},
+		{Key: "value", Value: bson.D{{Key: "$last", Value: "$value"}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		{Key: "timestamp", Value: bson.D{{Key: "$max", Value: "$timestamp"}}},
+	}}}, actual[2])
+	assert.Len(t, filter, 1)
+}
+
+func TestSeriesPipeline_UnequalSamplingRates_CombinesPerInstanceAggregates(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	filter := bson.D{{Key: "meta.instanceId", Value: bson.D{{Key: "$in", Value: bson.A{"pump1", "pump2"}}}}}
+
+	// pump1 reports every second and pump2 once a minute; each must weigh once per bucket,
+	// so points are averaged per instance before the instance averages are averaged.
+	actual := seriesPipeline(filter, models.SeriesQuery{From: from, To: from.Add(time.Hour), Interval: time.Minute, Aggregation: "avg"})
+	assert.Len(t, actual, 6)
+	assert.Equal(t, "$meta.instanceId", actual[2][0].Value.(bson.D)[0].Value.(bson.D)[0].Value)
+	assert.Equal(t, bson.D{{Key: "$avg", Value: "$value"}}, actual[2][0].Value.(bson.D)[1].Value)
+	assert.Equal(t, bson.D{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: 1}}}}, actual[3])
+	assert.Equal(t, bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: "$_id.bucket"},
+		{Key: "value", Value: bson.D{{Key: "$avg", Value: "$value"}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+	}}}, actual[4])
+	assert.Equal(t, bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}}, actual[5])
+}
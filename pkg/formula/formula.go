@@ -0,0 +1,459 @@
+package formula
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrSyntax           = errors.New("formula syntax error")
+	ErrUnknownReference = errors.New("unknown reference")
+	ErrNoValues         = errors.New("no values to aggregate")
+	ErrDivisionByZero   = errors.New("division by zero")
+	ErrCycle            = errors.New("formula cycle")
+)
+
+var aggregateFunctions = []string{"sum", "avg", "min", "max", "count"}
+
+type Resolver interface {
+	Value(name string) (float64, error)
+	Aggregate(function string, relationship string, name string) (float64, error)
+}
+
+type Formula struct {
+	source string
+	root   node
+}
+
+func Parse(source string) (*Formula, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEnd {
+		return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, p.peek().text, p.peek().position)
+	}
+
+	return &Formula{source: source, root: root}, nil
+}
+
+func (f *Formula) String() string {
+	return f.source
+}
+
+func (f *Formula) Evaluate(resolver Resolver) (float64, error) {
+	return f.root.evaluate(resolver)
+}
+
+func (f *Formula) References() []string {
+	references := make([]string, 0)
+	f.root.collectReferences(&references)
+	return references
+}
+
+func (f *Formula) Relationships() []string {
+	relationships := make([]string, 0)
+	f.root.collectRelationships(&relationships)
+	return relationships
+}
+
+func Order(formulas map[string]*Formula) ([]string, error) {
+	names := make([]string, 0, len(formulas))
+	for name := range formulas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	states := make(map[string]int, len(names))
+	ordered := make([]string, 0, len(names))
+	path := make([]string, 0)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch states[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, name):]), name)
+			return fmt.Errorf("%w: %s", ErrCycle, strings.Join(cycle, " -> "))
+		}
+
+		states[name] = visiting
+		path = append(path, name)
+		for _, reference := range formulas[name].References() {
+			if _, ok := formulas[reference]; ok {
+				if err := visit(reference); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		states[name] = visited
+		ordered = append(ordered, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+type node interface {
+	evaluate(resolver Resolver) (float64, error)
+	collectReferences(references *[]string)
+	collectRelationships(relationships *[]string)
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n numberNode) evaluate(Resolver) (float64, error) {
+	return n.value, nil
+}
+
+func (n numberNode) collectReferences(*[]string) {}
+
+func (n numberNode) collectRelationships(*[]string) {}
+
+type referenceNode struct {
+	name string
+}
+
+func (n referenceNode) evaluate(resolver Resolver) (float64, error) {
+	return resolver.Value(n.name)
+}
+
+func (n referenceNode) collectReferences(references *[]string) {
+	if !slices.Contains(*references, n.name) {
+		*references = append(*references, n.name)
+	}
+}
+
+func (n referenceNode) collectRelationships(*[]string) {}
+
+type negateNode struct {
+	operand node
+}
+
+func (n negateNode) evaluate(resolver Resolver) (float64, error) {
+	value, err := n.operand.evaluate(resolver)
+	return -value, err
+}
+
+func (n negateNode) collectReferences(references *[]string) {
+	n.operand.collectReferences(references)
+}
+
+func (n negateNode) collectRelationships(relationships *[]string) {
+	n.operand.collectRelationships(relationships)
+}
+
+type binaryNode struct {
+	operator    byte
+	left, right node
+}
+
+func (n binaryNode) evaluate(resolver Resolver) (float64, error) {
+	left, err := n.left.evaluate(resolver)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.evaluate(resolver)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.operator {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		if right == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return left / right, nil
+	}
+	return 0, fmt.Errorf("%w: unknown operator %q", ErrSyntax, n.operator)
+}
+
+func (n binaryNode) collectReferences(references *[]string) {
+	n.left.collectReferences(references)
+	n.right.collectReferences(references)
+}
+
+func (n binaryNode) collectRelationships(relationships *[]string) {
+	n.left.collectRelationships(relationships)
+	n.right.collectRelationships(relationships)
+}
+
+type aggregateNode struct {
+	function     string
+	relationship string
+	name         string
+}
+
+func (n aggregateNode) evaluate(resolver Resolver) (float64, error) {
+	return resolver.Aggregate(n.function, n.relationship, n.name)
+}
+
+func (n aggregateNode) collectReferences(*[]string) {}
+
+func (n aggregateNode) collectRelationships(relationships *[]string) {
+	if !slices.Contains(*relationships, n.relationship) {
+		*relationships = append(*relationships, n.relationship)
+	}
+}
+
+func Aggregate(function string, values []float64) (float64, error) {
+	if function == "count" {
+		return float64(len(values)), nil
+	}
+	if len(values) == 0 {
+		if function == "sum" {
+			return 0, nil
+		}
+		return 0, ErrNoValues
+	}
+
+	result := values[0]
+	switch function {
+	case "sum", "avg":
+		for _, value := range values[1:] {
+			result += value
+		}
+		if function == "avg" {
+			result /= float64(len(values))
+		}
+	case "min":
+		result = slices.Min(values)
+	case "max":
+		result = slices.Max(values)
+	default:
+		return 0, fmt.Errorf("%w: unknown function %q", ErrSyntax, function)
+	}
+	return result, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenIdentifier
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	position int
+}
+
+func tokenize(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), position: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: string(runes[start:i]), position: start})
+		case r == '[':
+			end := slices.Index(runes[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("%w: unterminated [ at position %d", ErrSyntax, i)
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, text: strings.TrimSpace(string(runes[i+1 : i+end])), position: i})
+			i += end + 1
+		case r == '"' || r == '\'':
+			end := slices.Index(runes[i+1:], r)
+			if end == -1 {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrSyntax, i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[i+1 : i+1+end]), position: i})
+			i += end + 2
+		case strings.ContainsRune("+-*/(),", r):
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r), position: i})
+			i++
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, r, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, position: len(runes)}), nil
+}
+
+type parser struct {
+	tokens   []token
+	position int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEnd {
+		p.position++
+	}
+	return t
+}
+
+func (p *parser) isOperator(operators string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && strings.Contains(operators, t.text)
+}
+
+func (p *parser) expect(operator string) error {
+	t := p.next()
+	if t.kind != tokenOperator || t.text != operator {
+		return fmt.Errorf("%w: expected %q at position %d", ErrSyntax, operator, t.position)
+	}
+	return nil
+}
+
+func (p *parser) parseExpression() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+-") {
+		operator := p.next().text[0]
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*/") {
+		operator := p.next().text[0]
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: operator, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negateNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q at position %d", ErrSyntax, t.text, t.position)
+		}
+		return numberNode{value: value}, nil
+	case tokenIdentifier:
+		if p.isOperator("(") {
+			return p.parseAggregate(t)
+		}
+		return referenceNode{name: t.text}, nil
+	case tokenOperator:
+		if t.text == "(" {
+			expression, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return expression, nil
+		}
+	case tokenEnd:
+		return nil, fmt.Errorf("%w: unexpected end of formula", ErrSyntax)
+	}
+	return nil, fmt.Errorf("%w: unexpected %q at position %d", ErrSyntax, t.text, t.position)
+}
+
+func (p *parser) parseAggregate(function token) (node, error) {
+	name := strings.ToLower(function.text)
+	if !slices.Contains(aggregateFunctions, name) {
+		return nil, fmt.Errorf("%w: unknown function %q at position %d", ErrSyntax, function.text, function.position)
+	}
+	p.next()
+
+	arguments := make([]string, 0, 2)
+	for !p.isOperator(")") {
+		if len(arguments) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		argument := p.next()
+		if argument.kind != tokenString {
+			return nil, fmt.Errorf("%w: %s expects quoted arguments at position %d", ErrSyntax, name, argument.position)
+		}
+		arguments = append(arguments, argument.text)
+	}
+	p.next()
+
+	expectedArguments := 2
+	if name == "count" {
+		expectedArguments = 1
+	}
+	if len(arguments) != expectedArguments {
+		return nil, fmt.Errorf("%w: %s expects %d arguments but got %d", ErrSyntax, name, expectedArguments, len(arguments))
+	}
+
+	aggregate := aggregateNode{function: name, relationship: arguments[0]}
+	if len(arguments) > 1 {
+		aggregate.name = arguments[1]
+	}
+	return aggregate, nil
+}
@@ -0,0 +1,119 @@
+package formula
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mapResolver struct {
+	values     map[string]float64
+	aggregates map[string][]float64
+}
+
+func (r mapResolver) Value(name string) (float64, error) {
+	value, ok := r.values[name]
+	if !ok {
+		return 0, ErrUnknownReference
+	}
+	return value, nil
+}
+
+func (r mapResolver) Aggregate(function string, relationship string, name string) (float64, error) {
+	return Aggregate(function, r.aggregates[relationship+"/"+name])
+}
+
+func TestParse_Evaluate_ReturnsValue(t *testing.T) {
+	resolver := mapResolver{
+		values: map[string]float64{"floors": 4, "Floor Area": 250},
+		aggregates: map[string][]float64{
+			"contains/area": {100, 50, 25},
+		},
+	}
+
+	tests := map[string]float64{
+		"1 + 2 * 3":                       7,
+		"(1 + 2) * 3":                     9,
+		"-floors + 10":                    6,
+		"floors * [Floor Area]":           1000,
+		`sum("contains", "area")`:         175,
+		`avg('contains', 'area') / 2`:     175.0 / 3 / 2,
+		`max("contains", "area") - 100`:   0,
+		`count("contains") + floors`:      4,
+		`sum("contains", "missing") + 1`:  1,
+		`min("contains", "area") * 2.5`:   62.5,
+		"10 / 4":                          2.5,
+		"floors - floors - floors":        -4,
+		"[Floor Area] / floors / 2":       31.25,
+		`SUM("contains", "area") - 175`:   0,
+		"0.5 * floors":                    2,
+		"floors * (1 + 0.25)":             5,
+		"2 * -floors":                     -8,
+		`count("contains") * 0 + 3 / 1.5`: 2,
+	}
+
+	for source, expected := range tests {
+		formula, err := Parse(source)
+		if !assert.NoError(t, err, source) {
+			continue
+		}
+		actual, err := formula.Evaluate(resolver)
+		assert.NoError(t, err, source)
+		assert.InDelta(t, expected, actual, 1e-9, source)
+	}
+}
+
+func TestParse_InvalidSyntax_ReturnsSyntaxError(t *testing.T) {
+	for _, source := range []string{"", "1 +", "(1 + 2", "floors floors", `sum(contains, "area")`, `sum("contains")`, `median("contains", "area")`, "[Floor Area", `"open`, "1 # 2"} {
+		_, err := Parse(source)
+		assert.ErrorIs(t, err, ErrSyntax, source)
+	}
+}
+
+func TestEvaluate_Errors_ArePropagated(t *testing.T) {
+	resolver := mapResolver{values: map[string]float64{"zero": 0}}
+
+	formula, _ := Parse("1 / zero")
+	_, err := formula.Evaluate(resolver)
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+
+	formula, _ = Parse("unknown + 1")
+	_, err = formula.Evaluate(resolver)
+	assert.ErrorIs(t, err, ErrUnknownReference)
+
+	formula, _ = Parse(`avg("contains", "area")`)
+	_, err = formula.Evaluate(resolver)
+	assert.ErrorIs(t, err, ErrNoValues)
+}
+
+func TestFormula_References_ReturnsLocalNamesOnce(t *testing.T) {
+	formula, err := Parse(`floors * [Floor Area] + floors + sum("contains", "area")`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"floors", "Floor Area"}, formula.References())
+}
+
+func TestFormula_Relationships_ReturnsAggregatedRelationshipsOnce(t *testing.T) {
+	formula, err := Parse(`-count("contains") + sum("contains", "area") / max("feeds", "load")`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"contains", "feeds"}, formula.Relationships())
+}
+
+func TestOrder_SortsByDependencies(t *testing.T) {
+	total, _ := Parse("net + tax")
+	tax, _ := Parse("net * 0.2")
+	net, _ := Parse("gross - 10")
+
+	actual, err := Order(map[string]*Formula{"total": total, "tax": tax, "net": net})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"net", "tax", "total"}, actual)
+}
+
+func TestOrder_Cycle_ReturnsCycleError(t *testing.T) {
+	a, _ := Parse("b + 1")
+	b, _ := Parse("c + 1")
+	c, _ := Parse("a + 1")
+
+	_, err := Order(map[string]*Formula{"a": a, "b": b, "c": c})
+	assert.ErrorIs(t, err, ErrCycle)
+	assert.EqualError(t, err, "formula cycle: a -> b -> c -> a")
+}
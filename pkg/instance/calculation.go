@@ -0,0 +1,7 @@
+package instance
+
+import "api/pkg/calculation"
+
+func (s *service) newCalculator(tenantId string) calculation.Calculator {
+	return calculation.NewCalculator(s.db, s.templateService, s.commonService, tenantId)
+}
@@ -0,0 +1,131 @@
+package instance
+
+import (
+	"api/pkg/calculation"
+	"api/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestService_AddInstance_CalculatedMetrics_AreComputedInDependencyOrder(t *testing.T) {
+	containsId := primitive.NewObjectID()
+	mockService, mockRepository := newCalculationTestService(containsId)
+
+	floor1, floor2 := newFloorInstance("floor1", 120), newFloorInstance("floor2", 80)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&floor1, nil)
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{floor1, floor2}, nil)
+	mockRepository.On("GetAllInstances", filterKey(1, "relationships.target"), mock.Anything).Return([]models.Instance{}, nil)
+	mockRepository.On("AddOne", "instances", mock.MatchedBy(func(instance models.Instance) bool {
+		return assert.ObjectsAreEqual([]models.InstanceMetric{
+			{ID: "floor-count", MetricBehaviour: "Calculated", Value: float64(2)},
+			{ID: "total-area", MetricBehaviour: "Calculated", Value: float64(200)},
+			{ID: "average-area", MetricBehaviour: "Calculated", Value: float64(100)},
+		}, instance.Metrics)
+	})).Return(nil)
+
+	actualErr := mockService.AddInstance("the-binary", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			Name:       "Building 1",
+			ExternalId: "building1",
+			Parent:     "p.com.building",
+		},
+		Relationships: []models.InstanceRelationship{
+			{Target: []interface{}{"floor1", "floor2"}, RelationshipTemplateId: containsId},
+		},
+	})
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateInstance_InputChanges_RecalculatesDependentInstances(t *testing.T) {
+	containsId := primitive.NewObjectID()
+	mockService, mockRepository := newCalculationTestService(containsId)
+
+	floor1, floor2 := newFloorInstance("floor1", 150), newFloorInstance("floor2", 80)
+	building := models.Instance{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId:   "building1",
+			Parent:       "p.com.building",
+			RootTemplate: "p.com.building",
+		},
+		Metrics: []models.InstanceMetric{
+			{ID: "floor-count", MetricBehaviour: "Calculated", Value: float64(2)},
+			{ID: "total-area", MetricBehaviour: "Calculated", Value: float64(200)},
+			{ID: "average-area", MetricBehaviour: "Manual", Value: float64(1)},
+		},
+		Relationships: []models.InstanceRelationship{
+			{ID: "1", Target: primitive.A{"floor1", "floor2"}, RelationshipTemplateId: containsId},
+		},
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&floor1, nil)
+	mockRepository.On("ReplaceInstance", filterKey(1, "basicInformation.externalId"), mock.AnythingOfType("models.Instance")).Return(int64(2), nil).Once()
+	mockRepository.On("GetAllInstances", filterKey(1, "relationships.target"), mock.Anything).Return([]models.Instance{building}, nil).Once()
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{floor1, floor2}, nil)
+	mockRepository.On("ReplaceInstance", filterKey(1, "basicInformation.externalId"), mock.MatchedBy(func(instance *models.Instance) bool {
+		return instance.BasicInformation.ExternalId == "building1" && assert.ObjectsAreEqual([]models.InstanceMetric{
+			{ID: "floor-count", MetricBehaviour: "Calculated", Value: float64(2)},
+			{ID: "total-area", MetricBehaviour: "Calculated", Value: float64(230)},
+			{ID: "average-area", MetricBehaviour: "Manual", Value: float64(1)},
+		}, instance.Metrics)
+	})).Return(int64(2), nil).Once()
+	mockRepository.On("GetAllInstances", filterKey(1, "relationships.target"), mock.Anything).Return([]models.Instance{}, nil).Once()
+
+	_, actualErr := mockService.UpdateInstance("the-binary", "floor1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Floor 1"},
+		Metrics:          []models.InstanceMetric{{ID: "area", MetricBehaviour: "Manual", Value: "150"}},
+	}, 0)
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestCalculator_Calculate_EvaluationFails_KeepsPreviousValue(t *testing.T) {
+	mockService, _ := newCalculationTestService(primitive.NewObjectID())
+	calculator := mockService.newCalculator("the-binary")
+	buildingTemplate, _ := calculator.Template("p.com.building")
+
+	building := models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "building1", Parent: "p.com.building"},
+		Metrics: []models.InstanceMetric{
+			{ID: "floor-count", MetricBehaviour: "Calculated", Value: float64(2)},
+			{ID: "total-area", MetricBehaviour: "Calculated", Value: float64(200)},
+			{ID: "average-area", MetricBehaviour: "Calculated", Value: float64(100)},
+		},
+	}
+
+	assert.True(t, calculator.Calculate(&building, buildingTemplate))
+	assert.Equal(t, []models.InstanceMetric{
+		{ID: "floor-count", MetricBehaviour: "Calculated", Value: float64(0)},
+		{ID: "total-area", MetricBehaviour: "Calculated", Value: float64(0)},
+		{ID: "average-area", MetricBehaviour: "Calculated", Value: float64(100)},
+	}, building.Metrics)
+}
+
+func TestCalculator_Propagate_DoesNotSettle_ReturnsValidationError(t *testing.T) {
+	containsId := primitive.NewObjectID()
+	mockService, mockRepository := newCalculationTestService(containsId)
+
+	staleBuilding := func() []models.Instance {
+		return []models.Instance{{
+			TenantID:         "the-binary",
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "building1", Parent: "p.com.building"},
+			Metrics:          []models.InstanceMetric{{ID: "floor-count", MetricBehaviour: "Calculated", Value: float64(5)}},
+		}}
+	}
+	for i := 0; i <= calculation.MaxRecalculationPasses; i++ {
+		mockRepository.On("GetAllInstances", filterKey(1, "relationships.target"), mock.Anything).Return(staleBuilding(), nil).Once()
+	}
+	mockRepository.On("ReplaceInstance", filterKey(1, "basicInformation.externalId"), mock.AnythingOfType("*models.Instance")).Return(int64(2), nil)
+
+	actualErr := mockService.newCalculator("the-binary").Propagate([]string{"floor1"})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	mockRepository.AssertNumberOfCalls(t, "ReplaceInstance", calculation.MaxRecalculationPasses)
+}
@@ -1,22 +1,45 @@
 package instance
 
 import (
+	"api/pkg/audit"
+	"api/pkg/common"
 	"api/pkg/db"
 	"api/pkg/models"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultGraphDepth = 1
+	maxGraphDepth     = 10
+)
+
 type Controller interface {
 	AddInstance(c *gin.Context)
+	UpdateInstanceById(c *gin.Context)
+	DeleteInstanceById(c *gin.Context)
 	GetCreateInstanceForm(c *gin.Context)
 	GetInstanceList(c *gin.Context)
 	GetInstanceById(context *gin.Context)
+	GetInstanceGraph(context *gin.Context)
 	GetApplicableRelationshipInstances(context *gin.Context)
+	AddMetricPoints(context *gin.Context)
+	AddMetricPointBatch(context *gin.Context)
+	GetMetricSeries(context *gin.Context)
+	GetAggregateMetricSeries(context *gin.Context)
+	ImportInstances(context *gin.Context)
+	ExportInstances(context *gin.Context)
+	GetInstanceVersions(context *gin.Context)
+	GetInstanceVersion(context *gin.Context)
+	DiffInstanceVersions(context *gin.Context)
+	RestoreInstanceVersion(context *gin.Context)
 }
 
 type controller struct {
@@ -35,38 +58,86 @@ func (c *controller) AddInstance(context *gin.Context) {
 
 	if err := context.ShouldBindJSON(&instanceToAdd); err != nil {
 		log.Println("error parsing request body: ", err)
-		context.Status(http.StatusBadRequest)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
 		return
 	}
 
-	if err := c.instanceService.AddInstance(tenantId, instanceToAdd); err != nil {
+	if err := c.instanceService.WithAuditContext(audit.ContextFrom(context)).AddInstance(tenantId, instanceToAdd); err != nil {
 		log.Println("error adding instance: ", err)
-		if strings.Contains(err.Error(), "error validating attribute") || strings.Contains(err.Error(), "is required but not provided") {
-			context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		if errors.Is(err, db.ErrDuplicateExternalId) {
-			context.Status(http.StatusConflict)
+		respondWithInstanceError(context, err)
+		return
+	}
+
+	context.Status(http.StatusCreated)
+}
+
+func (c *controller) UpdateInstanceById(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+	var instanceToUpdate models.Instance
+
+	if err := context.ShouldBindJSON(&instanceToUpdate); err != nil {
+		log.Println("error parsing request body: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	expectedVersion, err := common.ParseIfMatch(context.GetHeader(common.HeaderIfMatch))
+	if err != nil {
+		log.Println("error parsing precondition: ", err)
+		common.RespondWithPreconditionError(context, err)
+		return
+	}
+
+	version, err := c.instanceService.WithAuditContext(audit.ContextFrom(context)).UpdateInstance(tenantId, instanceId, instanceToUpdate, expectedVersion)
+	if err != nil {
+		log.Println("error updating instance: ", err)
+		respondWithInstanceError(context, err)
+		return
+	}
+
+	context.Header(common.HeaderETag, common.ETag(version))
+
+	context.Status(http.StatusOK)
+}
+
+func (c *controller) DeleteInstanceById(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+	restrict := context.Query("restrict") == "true"
+
+	referencingInstances, err := c.instanceService.WithAuditContext(audit.ContextFrom(context)).DeleteInstance(tenantId, instanceId, restrict)
+	if err != nil {
+		log.Println("error deleting instance: ", err)
+		if errors.Is(err, ErrInstanceReferenced) {
+			common.RespondWithError(context, http.StatusConflict, common.ErrorCodeConflict, err.Error(), gin.H{"referencedBy": referencingInstances})
 			return
 		}
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
-	context.Status(http.StatusCreated)
+	context.Status(http.StatusNoContent)
 }
 
 func (c *controller) GetInstanceList(context *gin.Context) {
 	tenantID := context.Param("tenantId")
 
-	res, err := c.instanceService.GetInstances(tenantID)
+	query, err := common.ParseListQuery(context.Request.URL.Query())
 	if err != nil {
-		log.Println("error getting applicable relationship instances: ", err)
-		context.Status(http.StatusInternalServerError)
+		log.Println("error parsing list query: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"data": res})
+	res, err := c.instanceService.GetInstances(tenantID, query)
+	if err != nil {
+		log.Println("error getting instances: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, res)
 }
 
 func (c *controller) GetApplicableRelationshipInstances(context *gin.Context) {
@@ -78,7 +149,7 @@ func (c *controller) GetApplicableRelationshipInstances(context *gin.Context) {
 	res, err := c.instanceService.GetApplicableRelationshipInstances(tenantID, relationshipTemplateId, parentTemplate, instanceExternalIdToExclude)
 	if err != nil {
 		log.Println("error getting instances: ", err)
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
@@ -91,7 +162,7 @@ func (c *controller) GetCreateInstanceForm(context *gin.Context) {
 	res, err := c.instanceService.GetCreateInstanceForm(tenantId, parentExternalId)
 	if err != nil {
 		log.Println("error getting create instance form: ", err)
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
@@ -105,9 +176,300 @@ func (c *controller) GetInstanceById(context *gin.Context) {
 	res, err := c.instanceService.GetInstance(tenantID, instanceId)
 	if err != nil {
 		log.Println("error getting instance: ", err)
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Header(common.HeaderETag, common.ETag(res.Version))
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) GetInstanceGraph(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+
+	depth := defaultGraphDepth
+	if rawDepth := context.Query("depth"); rawDepth != "" {
+		parsedDepth, err := strconv.Atoi(rawDepth)
+		if err != nil || parsedDepth < 0 || parsedDepth > maxGraphDepth {
+			common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, fmt.Sprintf("depth must be an integer between 0 and %d", maxGraphDepth), nil)
+			return
+		}
+		depth = parsedDepth
+	}
+
+	relationshipNames := make([]string, 0)
+	for _, relationship := range context.QueryArray("relationship") {
+		relationshipNames = append(relationshipNames, strings.Split(relationship, ",")...)
+	}
+
+	res, err := c.instanceService.GetInstanceGraph(tenantID, instanceId, depth, relationshipNames)
+	if err != nil {
+		log.Println("error getting instance graph: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) AddMetricPoints(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+	metricId := context.Param("metricId")
+	var batch models.MetricPointBatch
+
+	if err := context.ShouldBindJSON(&batch); err != nil {
+		log.Println("error parsing request body: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	if err := c.instanceService.AddMetricPoints(tenantId, instanceId, metricId, batch.Points); err != nil {
+		log.Println("error adding metric points: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Status(http.StatusCreated)
+}
+
+func (c *controller) AddMetricPointBatch(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	var batch models.MetricPointBatch
+
+	if err := context.ShouldBindJSON(&batch); err != nil {
+		log.Println("error parsing request body: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	if err := c.instanceService.AddMetricPointBatch(tenantId, batch.Points); err != nil {
+		log.Println("error adding metric points: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Status(http.StatusCreated)
+}
+
+func (c *controller) GetMetricSeries(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+	metricId := context.Param("metricId")
+
+	query, err := db.ParseSeriesQuery(context.Request.URL.Query(), time.Now())
+	if err != nil {
+		log.Println("error parsing series query: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	res, err := c.instanceService.GetMetricSeries(tenantId, instanceId, metricId, query)
+	if err != nil {
+		log.Println("error getting metric series: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) GetAggregateMetricSeries(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	metricId := context.Param("metricId")
+
+	query, err := db.ParseSeriesQuery(context.Request.URL.Query(), time.Now())
+	if err != nil {
+		log.Println("error parsing series query: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	scope := models.SeriesScope{
+		Template:      context.Query("template"),
+		Instance:      context.Query("instance"),
+		Relationships: make([]string, 0),
+		Depth:         defaultGraphDepth,
+	}
+	if (scope.Template == "") == (scope.Instance == "") {
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, "exactly one of template or instance must be given", nil)
+		return
+	}
+	if rawDepth := context.Query("depth"); rawDepth != "" {
+		parsedDepth, err := strconv.Atoi(rawDepth)
+		if err != nil || parsedDepth < 1 || parsedDepth > maxGraphDepth {
+			common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, fmt.Sprintf("depth must be an integer between 1 and %d", maxGraphDepth), nil)
+			return
+		}
+		scope.Depth = parsedDepth
+	}
+	for _, relationship := range context.QueryArray("relationship") {
+		scope.Relationships = append(scope.Relationships, strings.Split(relationship, ",")...)
+	}
+
+	res, err := c.instanceService.GetAggregateMetricSeries(tenantId, metricId, scope, query)
+	if err != nil {
+		log.Println("error getting metric series: ", err)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
 	context.JSON(http.StatusOK, gin.H{"data": res})
 }
+
+func (c *controller) ImportInstances(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	importOptions := models.ImportOptions{
+		Format: importFormat(context),
+		Parent: context.Query("parent"),
+		DryRun: context.Query("dryRun") == "true",
+	}
+
+	res, err := c.instanceService.WithAuditContext(audit.ContextFrom(context)).ImportInstances(tenantId, importOptions, context.Request.Body)
+	if err != nil {
+		log.Println("error importing instances: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func importFormat(context *gin.Context) string {
+	if format := context.Query("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	switch context.ContentType() {
+	case common.ContentTypeCSV:
+		return models.ImportFormatCSV
+	case common.ContentTypeNDJSON, "application/jsonl":
+		return models.ImportFormatNDJSON
+	}
+	return ""
+}
+
+func (c *controller) ExportInstances(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	exportOptions := models.ExportOptions{
+		Format:       strings.ToLower(context.DefaultQuery("format", models.ExportFormatNDJSON)),
+		Template:     context.Query("template"),
+		RootTemplate: context.Query("rootTemplate"),
+	}
+
+	contentType := common.ContentTypeNDJSON
+	if exportOptions.Format == models.ExportFormatCSV {
+		contentType = common.ContentTypeCSV
+	}
+	writer := common.NewStreamWriter(context, contentType, "instances."+exportOptions.Format)
+	if err := c.instanceService.ExportInstances(tenantId, exportOptions, writer); err != nil {
+		log.Println("error exporting instances: ", err)
+		if !context.Writer.Written() {
+			common.RespondWithServiceError(context, err)
+		}
+		return
+	}
+
+	writer.Close()
+}
+
+func respondWithInstanceError(context *gin.Context, err error) {
+	var cardinalityErr *CardinalityError
+	if errors.As(err, &cardinalityErr) {
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeValidationFailed, cardinalityErr.Error(), cardinalityErr)
+		return
+	}
+	common.RespondWithServiceError(context, err)
+}
+
+func (c *controller) GetInstanceVersions(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+
+	res, err := c.instanceService.GetInstanceVersions(tenantId, instanceId)
+	if err != nil {
+		log.Println("error getting instance versions: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) GetInstanceVersion(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+
+	version, err := common.ParseVersion("version", context.Param("version"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	res, err := c.instanceService.GetInstanceVersion(tenantId, instanceId, version)
+	if err != nil {
+		log.Println("error getting instance version: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) DiffInstanceVersions(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+
+	from, err := common.ParseVersion("from", context.Query("from"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+	to, err := common.ParseVersion("to", context.Query("to"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	res, err := c.instanceService.DiffInstanceVersions(tenantId, instanceId, from, to)
+	if err != nil {
+		log.Println("error diffing instance versions: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) RestoreInstanceVersion(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	instanceId := context.Param("instanceId")
+
+	version, err := common.ParseVersion("version", context.Param("version"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	expectedVersion, err := common.ParseIfMatch(context.GetHeader(common.HeaderIfMatch))
+	if err != nil {
+		log.Println("error parsing precondition: ", err)
+		common.RespondWithPreconditionError(context, err)
+		return
+	}
+
+	restoredVersion, err := c.instanceService.WithAuditContext(audit.ContextFrom(context)).RestoreInstanceVersion(tenantId, instanceId, version, expectedVersion)
+	if err != nil {
+		log.Println("error restoring instance version: ", err)
+		respondWithInstanceError(context, err)
+		return
+	}
+
+	context.Header(common.HeaderETag, common.ETag(restoredVersion))
+
+	context.Status(http.StatusOK)
+}
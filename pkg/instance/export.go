@@ -0,0 +1,191 @@
+package instance
+
+import (
+	"api/pkg/models"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const exportFlushRows = 100
+
+type exportColumn struct {
+	kind   string
+	id     string
+	header string
+}
+
+func (s *service) ExportInstances(tenantId string, exportOptions models.ExportOptions, w io.Writer) error {
+	filter := bson.D{{Key: "tenantId", Value: tenantId}}
+	switch {
+	case exportOptions.Template != "" && exportOptions.RootTemplate != "":
+		return importValidationError("template", models.ValidationCodeNotAllowed, "only one of template or rootTemplate can be set")
+	case exportOptions.Template != "":
+		filter = append(filter, bson.E{Key: "basicInformation.parent", Value: strings.ToLower(exportOptions.Template)})
+	case exportOptions.RootTemplate != "":
+		filter = append(filter, bson.E{Key: "basicInformation.rootTemplate", Value: strings.ToLower(exportOptions.RootTemplate)})
+	}
+
+	var err error
+	switch exportOptions.Format {
+	case models.ExportFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		err = s.db.StreamInstances(filter, func(instance models.Instance) error {
+			return encoder.Encode(instance)
+		})
+	case models.ExportFormatCSV:
+		err = s.exportCSV(tenantId, exportOptions, filter, w)
+	default:
+		err = importValidationError("format", models.ValidationCodeNotAllowed, "format must be csv or ndjson")
+	}
+	if err != nil {
+		log.Println("error exporting instances: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) exportCSV(tenantId string, exportOptions models.ExportOptions, filter bson.D, w io.Writer) error {
+	templates, err := s.exportTemplates(tenantId, exportOptions)
+	if err != nil {
+		return err
+	}
+	columns := exportColumns(templates)
+
+	writer := csv.NewWriter(w)
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.header)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	rows := 0
+	err = s.db.StreamInstances(filter, func(instance models.Instance) error {
+		if err := writer.Write(csvRecord(instance, columns)); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			writer.Flush()
+			return writer.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *service) exportTemplates(tenantId string, exportOptions models.ExportOptions) ([]models.Template, error) {
+	templateId := strings.ToLower(exportOptions.Template)
+	if templateId == "" {
+		templateId = strings.ToLower(exportOptions.RootTemplate)
+	}
+	if templateId == "" {
+		return nil, importValidationError("template", models.ValidationCodeRequired, "csv exports need a template or rootTemplate")
+	}
+
+	exportTemplate, err := s.templateService.GetTemplate(tenantId, templateId)
+	if err != nil {
+		return nil, err
+	}
+	templates := []models.Template{*exportTemplate}
+	if exportOptions.RootTemplate == "" {
+		return templates, nil
+	}
+
+	descendants, err := s.templateService.GetDescendantTemplates(tenantId, templateId)
+	if err != nil {
+		return nil, err
+	}
+	return append(templates, descendants...), nil
+}
+
+func exportColumns(templates []models.Template) []exportColumn {
+	columns := []exportColumn{
+		{kind: "externalid", header: "externalId"},
+		{kind: "name", header: "name"},
+		{kind: "parent", header: "parent"},
+	}
+	headerTaken := func(header string) bool {
+		return slices.ContainsFunc(columns, func(c exportColumn) bool {
+			return strings.EqualFold(c.header, header)
+		})
+	}
+	hasColumn := func(kind string, id string) bool {
+		return slices.ContainsFunc(columns, func(c exportColumn) bool {
+			return c.kind == kind && c.id == id
+		})
+	}
+
+	for _, template := range templates {
+		for _, attribute := range template.Attributes {
+			if hasColumn("attribute", attribute.ID) {
+				continue
+			}
+			header := attribute.Name
+			if header == "" || headerTaken(header) {
+				header = attribute.ID
+			}
+			columns = append(columns, exportColumn{kind: "attribute", id: attribute.ID, header: header})
+		}
+	}
+	for _, template := range templates {
+		for _, metric := range template.Metrics {
+			if hasColumn("metric", metric.ID) {
+				continue
+			}
+			header := metric.Name
+			if header == "" || headerTaken(header) {
+				header = metric.ID
+			}
+			columns = append(columns, exportColumn{kind: "metric", id: metric.ID, header: header})
+		}
+	}
+
+	return columns
+}
+
+func csvRecord(instance models.Instance, columns []exportColumn) []string {
+	record := make([]string, 0, len(columns))
+	for _, column := range columns {
+		switch column.kind {
+		case "externalid":
+			record = append(record, instance.BasicInformation.ExternalId)
+		case "name":
+			record = append(record, instance.BasicInformation.Name)
+		case "parent":
+			record = append(record, instance.BasicInformation.Parent)
+		case "attribute":
+			attributeIndex := slices.IndexFunc(instance.Attributes, func(a models.InstanceAttribute) bool {
+				return a.ID == column.id
+			})
+			if attributeIndex == -1 {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, models.ValueString(instance.Attributes[attributeIndex].Value))
+		case "metric":
+			metricIndex := slices.IndexFunc(instance.Metrics, func(m models.InstanceMetric) bool {
+				return m.ID == column.id
+			})
+			if metricIndex == -1 {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, models.ValueString(instance.Metrics[metricIndex].Value))
+		}
+	}
+	return record
+}
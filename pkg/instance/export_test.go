@@ -0,0 +1,59 @@
+package instance
+
+import (
+	"api/pkg/models"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestService_ExportInstances_CSV_WritesOneColumnPerMetric(t *testing.T) {
+	mockService, mockRepository := newCalculationTestService(primitive.NewObjectID())
+
+	floor1, floor2 := newFloorInstance("floor1", 120.5), newFloorInstance("floor2", 80)
+	floor1.BasicInformation.Name = "Floor, first"
+	floor2.Metrics = nil
+	mockRepository.On("StreamInstances", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.parent", Value: "p.com.floor"}}).Return([]models.Instance{floor1, floor2}, nil)
+
+	var actual bytes.Buffer
+	actualErr := mockService.ExportInstances("the-binary", models.ExportOptions{Format: models.ExportFormatCSV, Template: "P.com.floor"}, &actual)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, "externalId,name,parent,area\nfloor1,\"Floor, first\",p.com.floor,120.5\nfloor2,,p.com.floor,\n", actual.String())
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ExportInstances_NDJSON_WritesOneInstancePerLine(t *testing.T) {
+	mockService, mockRepository := newCalculationTestService(primitive.NewObjectID())
+
+	mockRepository.On("StreamInstances", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.rootTemplate", Value: "p.com.floor"}}).Return([]models.Instance{newFloorInstance("floor1", 120), newFloorInstance("floor2", 80)}, nil)
+
+	var actual bytes.Buffer
+	actualErr := mockService.ExportInstances("the-binary", models.ExportOptions{Format: models.ExportFormatNDJSON, RootTemplate: "p.com.floor"}, &actual)
+	assert.Nil(t, actualErr)
+
+	lines := strings.Split(strings.TrimSpace(actual.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"externalId":"floor1"`)
+	assert.Contains(t, lines[1], `"externalId":"floor2"`)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ExportInstances_CSVWithoutTemplate_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository := newCalculationTestService(primitive.NewObjectID())
+
+	var actual bytes.Buffer
+	actualErr := mockService.ExportInstances("the-binary", models.ExportOptions{Format: models.ExportFormatCSV}, &actual)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []string{"template"}, fieldsOf(validationErr))
+	assert.Empty(t, actual.String())
+
+	mockRepository.AssertExpectations(t)
+}
@@ -0,0 +1,78 @@
+package instance
+
+import (
+	"api/pkg/audit"
+	"api/pkg/db"
+	"api/pkg/models"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *service) GetInstanceVersions(tenantId string, instanceExternalId string) ([]models.VersionSummary, error) {
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instanceExternalId}}
+	instance, err := s.db.GetInstance(filter)
+	if err != nil {
+		log.Println("error getting instance: ", err)
+		return nil, err
+	}
+
+	revisions, err := s.db.GetInstanceRevisions(filter)
+	if err != nil {
+		log.Println("error getting instance revisions: ", err)
+		return nil, err
+	}
+
+	versions := []models.VersionSummary{{Version: max(instance.Version, models.InitialVersion), Current: true}}
+	for _, revision := range revisions {
+		versions = append(versions, models.VersionSummary{Version: revision.Version, ArchivedAt: &revision.ArchivedAt})
+	}
+	return versions, nil
+}
+
+func (s *service) GetInstanceVersion(tenantId string, instanceExternalId string, version int64) (*models.Instance, error) {
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instanceExternalId}}
+	instance, err := s.db.GetInstance(filter)
+	if err != nil {
+		log.Println("error getting instance: ", err)
+		return nil, err
+	}
+	if version == max(instance.Version, models.InitialVersion) {
+		return instance, nil
+	}
+
+	revisions, err := s.db.GetInstanceRevisions(append(filter, bson.E{Key: "version", Value: version}))
+	if err != nil {
+		log.Println("error getting instance revisions: ", err)
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("%w: instance %s has no version %d", db.ErrNotFound, instanceExternalId, version)
+	}
+
+	return &revisions[0].Instance, nil
+}
+
+func (s *service) DiffInstanceVersions(tenantId string, instanceExternalId string, from int64, to int64) (*models.VersionDiff, error) {
+	fromInstance, err := s.GetInstanceVersion(tenantId, instanceExternalId, from)
+	if err != nil {
+		return nil, err
+	}
+	toInstance, err := s.GetInstanceVersion(tenantId, instanceExternalId, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VersionDiff{From: from, To: to, Changes: audit.Diff(fromInstance, toInstance)}, nil
+}
+
+func (s *service) RestoreInstanceVersion(tenantId string, instanceExternalId string, version int64, expectedVersion int64) (int64, error) {
+	instance, err := s.GetInstanceVersion(tenantId, instanceExternalId, version)
+	if err != nil {
+		log.Println("error getting instance version: ", err)
+		return 0, err
+	}
+
+	return s.UpdateInstance(tenantId, instanceExternalId, *instance, expectedVersion)
+}
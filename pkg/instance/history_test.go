@@ -0,0 +1,62 @@
+package instance
+
+import (
+	"api/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestService_GetInstanceVersions_LegacyInstance_ReportsInitialVersion(t *testing.T) {
+	mockService, mockRepository := newHistoryTestService()
+
+	filter := bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "pump1"}}
+	current := newPumpInstance(42, 0)
+	mockRepository.On("GetInstance", filter).Return(&current, nil)
+	mockRepository.On("GetInstanceRevisions", filter).Return([]models.InstanceRevision{}, nil)
+
+	actual, actualErr := mockService.GetInstanceVersions("the-binary", "pump1")
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []models.VersionSummary{{Version: models.InitialVersion, Current: true}}, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RestoreInstanceVersion_Success_ReplacesWithRevision(t *testing.T) {
+	mockService, mockRepository := newHistoryTestService()
+
+	current := newPumpInstance(42, 3)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetInstanceRevisions", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "pump1"}, {Key: "version", Value: int64(2)}}).Return([]models.InstanceRevision{
+		{Instance: newPumpInstance(int32(17), 2), ArchivedAt: time.Now()},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance models.Instance) bool {
+		return instance.BasicInformation.ExternalId == "pump1" && assert.ObjectsAreEqualValues(17, instance.Attributes[0].Value)
+	})).Return(int64(2), nil)
+
+	_, actualErr := mockService.RestoreInstanceVersion("the-binary", "pump1", 2, 3)
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RestoreInstanceVersion_RevisionFailsValidation_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository := newHistoryTestService()
+
+	current := newPumpInstance(42, 3)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetInstanceRevisions", mock.AnythingOfType("primitive.D")).Return([]models.InstanceRevision{
+		{Instance: newPumpInstance("fast", 1)},
+	}, nil)
+
+	_, actualErr := mockService.RestoreInstanceVersion("the-binary", "pump1", 1, 3)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
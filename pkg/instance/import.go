@@ -0,0 +1,477 @@
+package instance
+
+import (
+	"api/pkg/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxImportRows               = 10000
+	maxImportLineBytes          = 1 << 20
+	relationshipColumnPrefix    = "relationship:"
+	relationshipTargetSeparator = ";"
+)
+
+type importRow struct {
+	row          int
+	instance     models.Instance
+	dependencies []int
+	err          error
+}
+
+type importColumn struct {
+	kind           string
+	id             string
+	relationshipId primitive.ObjectID
+}
+
+func (s *service) ImportInstances(tenantId string, importOptions models.ImportOptions, data io.Reader) (*models.ImportReport, error) {
+	var rows []importRow
+	var err error
+	switch importOptions.Format {
+	case models.ImportFormatCSV:
+		rows, err = s.parseCSVImport(tenantId, importOptions.Parent, data)
+	case models.ImportFormatNDJSON:
+		rows, err = parseNDJSONImport(importOptions.Parent, data)
+	default:
+		err = importValidationError("format", models.ValidationCodeNotAllowed, "format must be csv or ndjson")
+	}
+	if err != nil {
+		log.Println("error parsing import: ", err)
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, importValidationError("file", models.ValidationCodeRequired, "the file contains no rows")
+	}
+
+	if err := s.checkImportExternalIds(tenantId, rows); err != nil {
+		log.Println("error checking imported external ids: ", err)
+		return nil, err
+	}
+	order := importOrder(rows)
+
+	if importOptions.DryRun {
+		if err := s.validateImport(tenantId, rows, order); err != nil {
+			log.Println("error running import dry run: ", err)
+			return nil, err
+		}
+	} else {
+		s.runImport(tenantId, rows, order)
+	}
+
+	report := &models.ImportReport{
+		DryRun: importOptions.DryRun,
+		Total:  len(rows),
+		Rows:   make([]models.ImportRowResult, 0, len(rows)),
+	}
+	for _, row := range rows {
+		result := models.ImportRowResult{
+			Row:        row.row,
+			ExternalId: strings.ToLower(row.instance.BasicInformation.ExternalId),
+			Status:     models.ImportStatusCreated,
+		}
+		if importOptions.DryRun {
+			result.Status = models.ImportStatusValid
+		}
+
+		var validationErr *models.ValidationError
+		switch {
+		case row.err == nil:
+			report.Succeeded++
+		case errors.As(row.err, &validationErr):
+			result.Status = models.ImportStatusFailed
+			result.Message = "validation failed"
+			result.Errors = validationErr.Errors
+			report.Failed++
+		default:
+			result.Status = models.ImportStatusFailed
+			result.Message = row.err.Error()
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	return report, nil
+}
+
+func (s *service) runImport(tenantId string, rows []importRow, order []int) {
+	for _, i := range order {
+		if checkImportDependencies(rows, i); rows[i].err != nil {
+			continue
+		}
+
+		if err := s.AddInstance(tenantId, rows[i].instance); err != nil {
+			rows[i].err = err
+		}
+	}
+}
+
+// validateImport runs the checks of runImport with reads only. Rows that pass are kept in memory,
+// together with the inverse relationships they would add, so later rows can target them and see
+// the unique values they claim.
+func (s *service) validateImport(tenantId string, rows []importRow, order []int) error {
+	var relationshipTemplates []models.Relationship
+	targetInstances := make(map[string]*models.Instance)
+	claimedValues := make(map[string]int)
+	for _, i := range order {
+		if checkImportDependencies(rows, i); rows[i].err != nil {
+			continue
+		}
+
+		instance := rows[i].instance
+		parentTemplate, err := s.prepareInstance(tenantId, &instance)
+		if err == nil {
+			err = claimUniqueValues(claimedValues, rows[i].row, instance, *parentTemplate)
+		}
+		if err != nil {
+			rows[i].err = err
+			continue
+		}
+
+		if len(instance.Relationships) > 0 && relationshipTemplates == nil {
+			if relationshipTemplates, err = s.commonService.GetRelationships(); err != nil {
+				log.Println("error fetching relationships: ", err)
+				return err
+			}
+		}
+		inverseRelationshipsToAdd, err := s.checkRelationships(instance, nil, relationshipTemplates, targetInstances)
+		if err != nil {
+			rows[i].err = err
+			continue
+		}
+
+		for _, change := range inverseRelationshipsToAdd {
+			addRelationshipTarget(change.target, change.inverseRelationshipId, instance.BasicInformation.ExternalId)
+		}
+		targetInstances[instance.BasicInformation.ExternalId] = &instance
+	}
+
+	return nil
+}
+
+func checkImportDependencies(rows []importRow, i int) {
+	for _, dependency := range rows[i].dependencies {
+		if rows[dependency].err != nil {
+			rows[i].err = importValidationError("relationships", models.ValidationCodeInvalid, fmt.Sprintf("relationship target %s from row %d was not imported", rows[dependency].instance.BasicInformation.ExternalId, rows[dependency].row))
+			return
+		}
+	}
+}
+
+func claimUniqueValues(claimedValues map[string]int, row int, instance models.Instance, template models.Template) error {
+	validationErr := &models.ValidationError{}
+	claims := make([]string, 0)
+	claim := func(field string, index int, id string, name string, value interface{}) {
+		key := fmt.Sprintf("%s/%s/%s", field, id, models.ValueString(value))
+		if claimedRow, ok := claimedValues[key]; ok {
+			validationErr.Add(fmt.Sprintf("%s[%d].value", field, index), id, models.ValidationCodeNotUnique, fmt.Sprintf("%s must be unique but %v is already used by row %d", name, value, claimedRow))
+			return
+		}
+		claims = append(claims, key)
+	}
+
+	for i, attribute := range instance.Attributes {
+		templateAttributeIndex := slices.IndexFunc(template.Attributes, func(ta models.TemplateAttribute) bool {
+			return ta.ID == attribute.ID
+		})
+		if templateAttributeIndex == -1 || !template.Attributes[templateAttributeIndex].Constraints.IsUnique() || models.ValueString(attribute.Value) == "" {
+			continue
+		}
+		claim("attributes", i, attribute.ID, "attribute "+template.Attributes[templateAttributeIndex].Name, attribute.Value)
+	}
+	for i, metric := range instance.Metrics {
+		templateMetricIndex := slices.IndexFunc(template.Metrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == metric.ID
+		})
+		if templateMetricIndex == -1 || !template.Metrics[templateMetricIndex].Constraints.IsUnique() || metric.MetricBehaviour != "Manual" || models.ValueString(metric.Value) == "" {
+			continue
+		}
+		claim("metrics", i, metric.ID, "metric "+template.Metrics[templateMetricIndex].Name, metric.Value)
+	}
+
+	if err := validationErr.ErrOrNil(); err != nil {
+		return err
+	}
+	for _, key := range claims {
+		claimedValues[key] = row
+	}
+	return nil
+}
+
+func (s *service) checkImportExternalIds(tenantId string, rows []importRow) error {
+	rowsByExternalId := make(map[string]int)
+	externalIds := make([]string, 0, len(rows))
+	for i := range rows {
+		externalId := strings.ToLower(rows[i].instance.BasicInformation.ExternalId)
+		if rows[i].err != nil || externalId == "" {
+			continue
+		}
+		if j, ok := rowsByExternalId[externalId]; ok {
+			rows[i].err = importValidationError("basicInformation.externalId", models.ValidationCodeNotUnique, fmt.Sprintf("externalId %s is already used by row %d", externalId, rows[j].row))
+			continue
+		}
+		rowsByExternalId[externalId] = i
+		externalIds = append(externalIds, externalId)
+	}
+
+	filter := bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "basicInformation.externalId", Value: bson.D{{Key: "$in", Value: externalIds}}},
+	}
+	existingInstances, err := s.db.GetAllInstances(filter, options.Find().SetProjection(bson.D{{Key: "basicInformation.externalId", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	for _, existingInstance := range existingInstances {
+		if i, ok := rowsByExternalId[existingInstance.BasicInformation.ExternalId]; ok {
+			rows[i].err = importValidationError("basicInformation.externalId", models.ValidationCodeNotUnique, fmt.Sprintf("externalId %s already exists", existingInstance.BasicInformation.ExternalId))
+		}
+	}
+
+	return nil
+}
+
+func importOrder(rows []importRow) []int {
+	rowsByExternalId := make(map[string]int)
+	for i, row := range rows {
+		if row.err == nil {
+			rowsByExternalId[strings.ToLower(row.instance.BasicInformation.ExternalId)] = i
+		}
+	}
+	for i := range rows {
+		if rows[i].err != nil {
+			continue
+		}
+		for _, relationship := range rows[i].instance.Relationships {
+			for _, target := range relationship.TargetExternalIds() {
+				if j, ok := rowsByExternalId[strings.ToLower(target)]; ok && j != i && !slices.Contains(rows[i].dependencies, j) {
+					rows[i].dependencies = append(rows[i].dependencies, j)
+				}
+			}
+		}
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	states := make([]int, len(rows))
+	order := make([]int, 0, len(rows))
+
+	var visit func(i int)
+	visit = func(i int) {
+		if states[i] != 0 {
+			return
+		}
+		states[i] = visiting
+		for _, j := range rows[i].dependencies {
+			if states[j] == visiting {
+				rows[i].err = importValidationError("relationships", models.ValidationCodeInvalid, fmt.Sprintf("relationships form a cycle with row %d", rows[j].row))
+				continue
+			}
+			visit(j)
+		}
+		states[i] = visited
+		if rows[i].err == nil {
+			order = append(order, i)
+		}
+	}
+
+	for i := range rows {
+		if rows[i].err == nil {
+			visit(i)
+		}
+	}
+	return order
+}
+
+func (s *service) parseCSVImport(tenantId string, parent string, data io.Reader) ([]importRow, error) {
+	if parent == "" {
+		return nil, importValidationError("parent", models.ValidationCodeRequired, "a parent template is required for csv imports")
+	}
+	parentTemplate, err := s.templateService.GetTemplate(tenantId, strings.ToLower(parent))
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(data)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, importValidationError("file", models.ValidationCodeInvalid, err.Error())
+	}
+
+	columns, err := s.importColumns(header, *parentTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]importRow, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if len(rows) == maxImportRows {
+			return nil, importValidationError("file", models.ValidationCodeLength, fmt.Sprintf("at most %d rows can be imported at once", maxImportRows))
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, importRow{row: parseErr.StartLine, err: importValidationError("row", models.ValidationCodeInvalid, parseErr.Err.Error())})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, importRow{row: line, instance: csvInstance(record, columns, parentTemplate.BasicInformation.ExternalID)})
+	}
+
+	return rows, nil
+}
+
+func (s *service) importColumns(header []string, parentTemplate models.Template) ([]importColumn, error) {
+	validationErr := &models.ValidationError{}
+	columns := make([]importColumn, 0, len(header))
+	var relationshipTemplates []models.Relationship
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		field := fmt.Sprintf("columns[%d]", i)
+
+		if strings.EqualFold(name, "externalId") || strings.EqualFold(name, "name") || strings.EqualFold(name, "parent") {
+			columns = append(columns, importColumn{kind: strings.ToLower(name)})
+			continue
+		}
+
+		if relationshipName, ok := strings.CutPrefix(name, relationshipColumnPrefix); ok {
+			if relationshipTemplates == nil {
+				fetchedRelationships, err := s.commonService.GetRelationships()
+				if err != nil {
+					log.Println("error fetching relationships: ", err)
+					return nil, err
+				}
+				relationshipTemplates = fetchedRelationships
+			}
+			relationshipIndex := slices.IndexFunc(relationshipTemplates, func(r models.Relationship) bool {
+				return r.Name == relationshipName || r.ID.Hex() == relationshipName
+			})
+			if relationshipIndex == -1 {
+				validationErr.Add(field, name, models.ValidationCodeUnknownField, fmt.Sprintf("relationship %s does not exist", relationshipName))
+				continue
+			}
+			columns = append(columns, importColumn{kind: "relationship", relationshipId: relationshipTemplates[relationshipIndex].ID})
+			continue
+		}
+
+		if attributeIndex := slices.IndexFunc(parentTemplate.Attributes, func(a models.TemplateAttribute) bool {
+			return a.ID == name || strings.EqualFold(a.Name, name)
+		}); attributeIndex != -1 {
+			columns = append(columns, importColumn{kind: "attribute", id: parentTemplate.Attributes[attributeIndex].ID})
+			continue
+		}
+
+		if metricIndex := slices.IndexFunc(parentTemplate.Metrics, func(m models.TemplateMetric) bool {
+			return m.ID == name || strings.EqualFold(m.Name, name)
+		}); metricIndex != -1 {
+			columns = append(columns, importColumn{kind: "metric", id: parentTemplate.Metrics[metricIndex].ID})
+			continue
+		}
+
+		validationErr.Add(field, name, models.ValidationCodeUnknownField, fmt.Sprintf("column %s is not an attribute, metric or relationship of template %s", name, parentTemplate.BasicInformation.ExternalID))
+	}
+
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func csvInstance(record []string, columns []importColumn, parent string) models.Instance {
+	instance := models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Parent: parent},
+		Attributes:       make([]models.InstanceAttribute, 0),
+		Metrics:          make([]models.InstanceMetric, 0),
+		Relationships:    make([]models.InstanceRelationship, 0),
+	}
+	for i, column := range columns {
+		value := strings.TrimSpace(record[i])
+		if value == "" {
+			continue
+		}
+
+		switch column.kind {
+		case "externalid":
+			instance.BasicInformation.ExternalId = value
+		case "name":
+			instance.BasicInformation.Name = value
+		case "parent":
+			instance.BasicInformation.Parent = strings.ToLower(value)
+		case "attribute":
+			instance.Attributes = append(instance.Attributes, models.InstanceAttribute{ID: column.id, Value: value})
+		case "metric":
+			instance.Metrics = append(instance.Metrics, models.InstanceMetric{ID: column.id, MetricBehaviour: "Manual", Value: value})
+		case "relationship":
+			targets := make([]string, 0)
+			for _, target := range strings.Split(value, relationshipTargetSeparator) {
+				if target = strings.TrimSpace(target); target != "" {
+					targets = append(targets, target)
+				}
+			}
+			instance.Relationships = append(instance.Relationships, models.InstanceRelationship{Target: targets, RelationshipTemplateId: column.relationshipId})
+		}
+	}
+	return instance
+}
+
+func parseNDJSONImport(parent string, data io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+
+	rows := make([]importRow, 0)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, importValidationError("file", models.ValidationCodeLength, fmt.Sprintf("at most %d rows can be imported at once", maxImportRows))
+		}
+
+		row := importRow{row: line}
+		if err := json.Unmarshal([]byte(text), &row.instance); err != nil {
+			row.err = importValidationError("row", models.ValidationCodeInvalid, err.Error())
+		}
+		if row.instance.BasicInformation.Parent == "" {
+			row.instance.BasicInformation.Parent = parent
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, importValidationError("file", models.ValidationCodeInvalid, err.Error())
+	}
+
+	return rows, nil
+}
+
+func importValidationError(field, code, message string) error {
+	validationErr := &models.ValidationError{}
+	validationErr.Add(field, "", code, message)
+	return validationErr
+}
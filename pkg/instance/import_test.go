@@ -0,0 +1,151 @@
+package instance
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestService_ImportInstances_NDJSON_AddsRowsInDependencyOrder(t *testing.T) {
+	containsId := primitive.NewObjectID()
+	mockService, mockRepository := newCalculationTestService(containsId)
+
+	floor1 := newFloorInstance("floor1", 120)
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{}, nil).Once()
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{floor1}, nil)
+	mockRepository.On("GetAllInstances", filterKey(1, "relationships.target"), mock.Anything).Return([]models.Instance{}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&floor1, nil)
+	added := make([]string, 0)
+	mockRepository.On("AddOne", "instances", mock.AnythingOfType("models.Instance")).Run(func(args mock.Arguments) {
+		added = append(added, args.Get(1).(models.Instance).BasicInformation.ExternalId)
+	}).Return(nil)
+
+	data := `{"basicInformation":{"name":"Building 1","externalId":"building1","parent":"p.com.building"},"relationships":[{"target":["Floor1"],"relationshipTemplateId":"` + containsId.Hex() + `"}]}
+
+{"basicInformation":{"name":"Floor 1","externalId":"Floor1"},"metrics":[{"id":"area","metricBehaviour":"Manual","value":120}]}
+`
+	actualReport, actualErr := mockService.ImportInstances("the-binary", models.ImportOptions{Format: models.ImportFormatNDJSON, Parent: "p.com.floor"}, strings.NewReader(data))
+	assert.Nil(t, actualErr)
+	assert.Equal(t, &models.ImportReport{
+		Total:     2,
+		Succeeded: 2,
+		Rows: []models.ImportRowResult{
+			{Row: 1, ExternalId: "building1", Status: models.ImportStatusCreated},
+			{Row: 3, ExternalId: "floor1", Status: models.ImportStatusCreated},
+		},
+	}, actualReport)
+	assert.Equal(t, []string{"floor1", "building1"}, added)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportInstances_CSVDryRun_MapsColumnsAndReportsValidRows(t *testing.T) {
+	mockService, mockRepository := newCalculationTestService(primitive.NewObjectID())
+
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{}, nil)
+
+	data := "ExternalId,NAME,Area\nfloor1,Floor 1,120\nfloor2,Floor 2,80\n"
+	actualReport, actualErr := mockService.ImportInstances("the-binary", models.ImportOptions{Format: models.ImportFormatCSV, Parent: "P.com.floor", DryRun: true}, strings.NewReader(data))
+	assert.Nil(t, actualErr)
+	assert.Equal(t, &models.ImportReport{
+		DryRun:    true,
+		Total:     2,
+		Succeeded: 2,
+		Rows: []models.ImportRowResult{
+			{Row: 2, ExternalId: "floor1", Status: models.ImportStatusValid},
+			{Row: 3, ExternalId: "floor2", Status: models.ImportStatusValid},
+		},
+	}, actualReport)
+
+	mockRepository.AssertNotCalled(t, "WithTransaction", mock.Anything)
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportInstances_NDJSONDryRun_ValidatesRowsTargetingEarlierRowsWithoutWriting(t *testing.T) {
+	containsId := primitive.NewObjectID()
+	mockService, mockRepository := newCalculationTestService(containsId)
+
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{}, nil)
+
+	data := `{"basicInformation":{"name":"Building 1","externalId":"building1","parent":"p.com.building"},"relationships":[{"target":["floor1"],"relationshipTemplateId":"` + containsId.Hex() + `"}]}
+{"basicInformation":{"name":"Floor 1","externalId":"floor1"},"metrics":[{"id":"area","metricBehaviour":"Manual","value":120}]}
+{"basicInformation":{"name":"Building 2","externalId":"building2","parent":"p.com.building"},"relationships":[{"target":["floor9"],"relationshipTemplateId":"` + containsId.Hex() + `"}]}
+`
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(nil, db.ErrNotFound)
+
+	actualReport, actualErr := mockService.ImportInstances("the-binary", models.ImportOptions{Format: models.ImportFormatNDJSON, Parent: "p.com.floor", DryRun: true}, strings.NewReader(data))
+	assert.Nil(t, actualErr)
+	assert.Equal(t, 2, actualReport.Succeeded)
+	assert.Equal(t, models.ImportStatusValid, actualReport.Rows[0].Status)
+	assert.Equal(t, models.ImportStatusValid, actualReport.Rows[1].Status)
+	assert.Equal(t, models.ImportStatusFailed, actualReport.Rows[2].Status)
+
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportInstances_ConflictingRows_AreReportedAsFailed(t *testing.T) {
+	containsId := primitive.NewObjectID()
+	mockService, mockRepository := newCalculationTestService(containsId)
+
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{newFloorInstance("floor2", 80)}, nil)
+	mockRepository.On("GetAllInstances", filterKey(1, "relationships.target"), mock.Anything).Return([]models.Instance{}, nil)
+	mockRepository.On("AddOne", "instances", mock.AnythingOfType("models.Instance")).Return(nil).Once()
+
+	data := "externalId,name,relationship:contains\nfloor1,Floor 1,\nFLOOR1,Floor 1 again,\nfloor2,Floor 2,\nfloor3,Floor 3,floor4\nfloor4,Floor 4,floor3\nfloor5,Floor 5\n"
+	actualReport, actualErr := mockService.ImportInstances("the-binary", models.ImportOptions{Format: models.ImportFormatCSV, Parent: "p.com.floor"}, strings.NewReader(data))
+	assert.Nil(t, actualErr)
+	assert.Equal(t, 6, actualReport.Total)
+	assert.Equal(t, 1, actualReport.Succeeded)
+	assert.Equal(t, 5, actualReport.Failed)
+	assert.Equal(t, models.ImportStatusCreated, actualReport.Rows[0].Status)
+	assert.Equal(t, "not_unique", actualReport.Rows[1].Errors[0].Code)
+	assert.Equal(t, "externalId floor1 is already used by row 2", actualReport.Rows[1].Errors[0].Message)
+	assert.Equal(t, "externalId floor2 already exists", actualReport.Rows[2].Errors[0].Message)
+	assert.Equal(t, "relationship target floor4 from row 6 was not imported", actualReport.Rows[3].Errors[0].Message)
+	assert.Equal(t, "relationships form a cycle with row 5", actualReport.Rows[4].Errors[0].Message)
+	assert.Equal(t, "row", actualReport.Rows[5].Errors[0].Field)
+	assert.Equal(t, 7, actualReport.Rows[5].Row)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportInstances_InvalidNDJSONLine_FailsRow(t *testing.T) {
+	mockService, mockRepository := newCalculationTestService(primitive.NewObjectID())
+
+	mockRepository.On("GetAllInstances", filterKey(1, "basicInformation.externalId"), mock.Anything).Return([]models.Instance{}, nil)
+	mockRepository.On("GetAllInstances", filterKey(1, "relationships.target"), mock.Anything).Return([]models.Instance{}, nil)
+	mockRepository.On("AddOne", "instances", mock.AnythingOfType("models.Instance")).Return(nil).Once()
+
+	data := "{\"basicInformation\":{\"name\":\"Floor 1\",\"externalId\":\"floor1\"}}\n{\"basicInformation\":\n"
+	actualReport, actualErr := mockService.ImportInstances("the-binary", models.ImportOptions{Format: models.ImportFormatNDJSON, Parent: "p.com.floor"}, strings.NewReader(data))
+	assert.Nil(t, actualErr)
+	assert.Equal(t, 1, actualReport.Succeeded)
+	assert.Equal(t, models.ImportStatusFailed, actualReport.Rows[1].Status)
+	assert.Equal(t, 2, actualReport.Rows[1].Row)
+	assert.Equal(t, "invalid", actualReport.Rows[1].Errors[0].Code)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportInstances_UnknownColumn_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository := newCalculationTestService(primitive.NewObjectID())
+
+	data := "externalId,name,height\nfloor1,Floor 1,3\n"
+	actualReport, actualErr := mockService.ImportInstances("the-binary", models.ImportOptions{Format: models.ImportFormatCSV, Parent: "p.com.floor"}, strings.NewReader(data))
+	assert.Nil(t, actualReport)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []string{"columns[2]"}, fieldsOf(validationErr))
+	assert.Equal(t, []string{"unknown_field"}, codesOf(validationErr))
+
+	mockRepository.AssertExpectations(t)
+}
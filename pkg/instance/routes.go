@@ -1,11 +1,32 @@
 package instance
 
-import "github.com/gin-gonic/gin"
-
-func RegisterRoutes(r *gin.Engine, instanceController Controller) {
-	r.GET("/api/v1/tenants/:tenantId/instances/form/:parentExternalId", instanceController.GetCreateInstanceForm)
-	r.POST("/api/v1/tenants/:tenantId/instances", instanceController.AddInstance)
-	r.GET("/api/v1/tenants/:tenantId/instances", instanceController.GetInstanceList)
-	r.GET("/api/v1/tenants/:tenantId/instances/:instanceId", instanceController.GetInstanceById)
-	r.GET("/api/v1/tenants/:tenantId/parents/:parentTemplate/relationships/:relationshipTemplateId/instances", instanceController.GetApplicableRelationshipInstances)
+import (
+	"api/pkg/common"
+	"api/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, instanceController Controller, authorizer common.Authorizer) {
+	reads := r.Group("", authorizer.Require(models.PermissionInstancesRead))
+	reads.GET("/api/v1/tenants/:tenantId/instances/form/:parentExternalId", instanceController.GetCreateInstanceForm)
+	reads.GET("/api/v1/tenants/:tenantId/instances", instanceController.GetInstanceList)
+	reads.GET("/api/v1/tenants/:tenantId/instances/export", instanceController.ExportInstances)
+	reads.GET("/api/v1/tenants/:tenantId/instances/:instanceId", instanceController.GetInstanceById)
+	reads.GET("/api/v1/tenants/:tenantId/instances/:instanceId/graph", instanceController.GetInstanceGraph)
+	reads.GET("/api/v1/tenants/:tenantId/instances/:instanceId/versions", instanceController.GetInstanceVersions)
+	reads.GET("/api/v1/tenants/:tenantId/instances/:instanceId/versions/diff", instanceController.DiffInstanceVersions)
+	reads.GET("/api/v1/tenants/:tenantId/instances/:instanceId/versions/:version", instanceController.GetInstanceVersion)
+	reads.GET("/api/v1/tenants/:tenantId/instances/:instanceId/metrics/:metricId/series", instanceController.GetMetricSeries)
+	reads.GET("/api/v1/tenants/:tenantId/metrics/:metricId/series", instanceController.GetAggregateMetricSeries)
+	reads.GET("/api/v1/tenants/:tenantId/parents/:parentTemplate/relationships/:relationshipTemplateId/instances", instanceController.GetApplicableRelationshipInstances)
+
+	writes := r.Group("", authorizer.Require(models.PermissionInstancesWrite))
+	writes.POST("/api/v1/tenants/:tenantId/instances", instanceController.AddInstance)
+	writes.POST("/api/v1/tenants/:tenantId/instances/import", instanceController.ImportInstances)
+	writes.PUT("/api/v1/tenants/:tenantId/instances/:instanceId", instanceController.UpdateInstanceById)
+	writes.DELETE("/api/v1/tenants/:tenantId/instances/:instanceId", instanceController.DeleteInstanceById)
+	writes.POST("/api/v1/tenants/:tenantId/instances/:instanceId/versions/:version/restore", instanceController.RestoreInstanceVersion)
+	writes.POST("/api/v1/tenants/:tenantId/instances/:instanceId/metrics/:metricId/points", instanceController.AddMetricPoints)
+	writes.POST("/api/v1/tenants/:tenantId/metrics/points", instanceController.AddMetricPointBatch)
 }
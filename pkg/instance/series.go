@@ -0,0 +1,357 @@
+package instance
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const maxMetricPointBatchSize = 10000
+
+func (s *service) AddMetricPoints(tenantId string, instanceExternalId string, metricId string, points []models.MetricPointInput) error {
+	instanceExternalId = strings.ToLower(instanceExternalId)
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instanceExternalId}}
+	instance, err := s.db.GetInstance(filter)
+	if err != nil {
+		log.Println("error getting instance: ", err)
+		return err
+	}
+
+	for i := range points {
+		points[i].InstanceID = instanceExternalId
+		points[i].MetricID = metricId
+	}
+
+	return s.ingestMetricPoints(tenantId, points, map[string]*models.Instance{instanceExternalId: instance})
+}
+
+func (s *service) AddMetricPointBatch(tenantId string, points []models.MetricPointInput) error {
+	return s.ingestMetricPoints(tenantId, points, make(map[string]*models.Instance))
+}
+
+func (s *service) ingestMetricPoints(tenantId string, inputs []models.MetricPointInput, instances map[string]*models.Instance) error {
+	validationErr := &models.ValidationError{}
+	if len(inputs) == 0 {
+		validationErr.Add("points", "", models.ValidationCodeRequired, "at least one point is required")
+		return validationErr
+	}
+	if len(inputs) > maxMetricPointBatchSize {
+		validationErr.Add("points", "", models.ValidationCodeLength, fmt.Sprintf("at most %d points can be ingested at once", maxMetricPointBatchSize))
+		return validationErr
+	}
+
+	templates := make(map[string]*models.Template)
+	converter := s.newUnitConverter()
+	points := make([]models.MetricPoint, 0, len(inputs))
+	now := time.Now().UTC()
+	for i, input := range inputs {
+		field := fmt.Sprintf("points[%d]", i)
+		instanceExternalId := strings.ToLower(input.InstanceID)
+
+		instance, ok := instances[instanceExternalId]
+		if !ok {
+			filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instanceExternalId}}
+			fetchedInstance, err := s.db.GetInstance(filter)
+			if errors.Is(err, db.ErrNotFound) {
+				validationErr.Add(field+".instanceId", input.InstanceID, models.ValidationCodeUnknownField, "instance does not exist")
+				continue
+			}
+			if err != nil {
+				log.Println("error getting instance: ", err)
+				return err
+			}
+			instances[instanceExternalId] = fetchedInstance
+			instance = fetchedInstance
+		}
+
+		instanceTemplate, ok := templates[instance.BasicInformation.Parent]
+		if !ok {
+			fetchedTemplate, err := s.templateService.GetTemplate(tenantId, instance.BasicInformation.Parent)
+			if err != nil {
+				log.Println("error getting template: ", err)
+				return err
+			}
+			templates[instance.BasicInformation.Parent] = fetchedTemplate
+			instanceTemplate = fetchedTemplate
+		}
+
+		templateMetricIndex := slices.IndexFunc(instanceTemplate.Metrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == input.MetricID
+		})
+		if templateMetricIndex == -1 {
+			validationErr.Add(field+".metricId", input.MetricID, models.ValidationCodeUnknownField, "metric is not defined on the template")
+			continue
+		}
+		templateMetric := instanceTemplate.Metrics[templateMetricIndex]
+
+		if !slices.ContainsFunc(instance.Metrics, func(m models.InstanceMetric) bool {
+			return m.ID == input.MetricID && m.MetricBehaviour == "Sourced"
+		}) {
+			validationErr.Add(field+".metricId", input.MetricID, models.ValidationCodeNotAllowed, fmt.Sprintf("metric %s is not sourced on instance %s", templateMetric.Name, instanceExternalId))
+			continue
+		}
+
+		if models.ValueString(input.Value) == "" {
+			validationErr.Add(field+".value", input.MetricID, models.ValidationCodeRequired, "value is required")
+			continue
+		}
+
+		convertedValue, err := converter.convertValue(input.Value, input.Unit, templateMetric.Unit)
+		if isUnitError(err) {
+			validationErr.Add(field+".unit", input.MetricID, models.ValidationCodeUnitMismatch, err.Error())
+			continue
+		}
+		if errors.Is(err, models.ErrInvalidValue) {
+			validationErr.Add(field+".value", input.MetricID, models.ValidationCodeTypeMismatch, err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		value, err := models.ParseMetricValue(templateMetric, models.ValueString(convertedValue))
+		if err != nil {
+			validationErr.Add(field+".value", input.MetricID, models.ValueErrorCode(err), err.Error())
+			continue
+		}
+
+		timestamp := now
+		if input.Timestamp != nil {
+			timestamp = input.Timestamp.UTC()
+		}
+
+		points = append(points, models.MetricPoint{
+			Timestamp: timestamp,
+			Meta: models.MetricPointMeta{
+				TenantID:   tenantId,
+				InstanceID: instanceExternalId,
+				MetricID:   input.MetricID,
+				Unit:       templateMetric.Unit,
+			},
+			Value: value,
+		})
+	}
+
+	if err := validationErr.ErrOrNil(); err != nil {
+		log.Println("error validating metric points: ", err)
+		return err
+	}
+
+	if err := s.db.AddMetricPoints(points); err != nil {
+		log.Println("error adding metric points: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) applyLatestMetricPoints(tenantId string, instance *models.Instance) error {
+	if !slices.ContainsFunc(instance.Metrics, func(m models.InstanceMetric) bool {
+		return m.MetricBehaviour == "Sourced"
+	}) {
+		return nil
+	}
+
+	filter := bson.D{{Key: "meta.tenantId", Value: tenantId}, {Key: "meta.instanceId", Value: instance.BasicInformation.ExternalId}}
+	points, err := s.db.GetLatestMetricPoints(filter)
+	if err != nil {
+		log.Println("error getting latest metric points: ", err)
+		return err
+	}
+
+	for _, point := range points {
+		metricIndex := slices.IndexFunc(instance.Metrics, func(m models.InstanceMetric) bool {
+			return m.ID == point.Meta.MetricID && m.MetricBehaviour == "Sourced"
+		})
+		if metricIndex == -1 {
+			continue
+		}
+		timestamp := point.Timestamp
+		instance.Metrics[metricIndex].Value = point.Value
+		instance.Metrics[metricIndex].Timestamp = &timestamp
+	}
+
+	return nil
+}
+
+func (s *service) GetMetricSeries(tenantId string, instanceExternalId string, metricId string, query models.SeriesQuery) (*models.MetricSeries, error) {
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: strings.ToLower(instanceExternalId)}}
+	instance, err := s.db.GetInstance(filter)
+	if err != nil {
+		log.Println("error getting instance: ", err)
+		return nil, err
+	}
+
+	series, err := s.metricSeries(tenantId, metricId, []models.InstanceBasicInformation{instance.BasicInformation}, query)
+	if err != nil {
+		log.Println("error getting metric series: ", err)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (s *service) GetAggregateMetricSeries(tenantId string, metricId string, scope models.SeriesScope, query models.SeriesQuery) (*models.MetricSeries, error) {
+	members, err := s.seriesMembers(tenantId, metricId, scope)
+	if err != nil {
+		log.Println("error getting series instances: ", err)
+		return nil, err
+	}
+
+	series, err := s.metricSeries(tenantId, metricId, members, query)
+	if err != nil {
+		log.Println("error getting metric series: ", err)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (s *service) seriesMembers(tenantId string, metricId string, scope models.SeriesScope) ([]models.InstanceBasicInformation, error) {
+	members := make([]models.InstanceBasicInformation, 0)
+
+	if scope.Template != "" {
+		templateId := strings.ToLower(scope.Template)
+		scopeTemplate, err := s.templateService.GetTemplate(tenantId, templateId)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(scopeTemplate.Metrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == metricId
+		}) {
+			validationErr := &models.ValidationError{}
+			validationErr.Add("metricId", metricId, models.ValidationCodeUnknownField, fmt.Sprintf("metric is not defined on template %s", templateId))
+			return nil, validationErr
+		}
+
+		descendants, err := s.templateService.GetDescendantTemplates(tenantId, templateId)
+		if err != nil {
+			return nil, err
+		}
+		templateIds := []string{templateId}
+		for _, descendant := range descendants {
+			templateIds = append(templateIds, descendant.BasicInformation.ExternalID)
+		}
+
+		filter := bson.D{
+			{Key: "tenantId", Value: tenantId},
+			{Key: "basicInformation.parent", Value: bson.D{{Key: "$in", Value: templateIds}}},
+		}
+		instances, err := s.db.GetAllInstances(filter, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			members = append(members, instance.BasicInformation)
+		}
+		return members, nil
+	}
+
+	graph, err := s.GetInstanceGraph(tenantId, scope.Instance, scope.Depth, scope.Relationships)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range graph.Nodes {
+		if node.Depth == 0 {
+			continue
+		}
+		members = append(members, models.InstanceBasicInformation{
+			ExternalId:   node.ExternalId,
+			Name:         node.Name,
+			Parent:       node.Parent,
+			RootTemplate: node.RootTemplate,
+		})
+	}
+	return members, nil
+}
+
+func (s *service) metricSeries(tenantId string, metricId string, members []models.InstanceBasicInformation, query models.SeriesQuery) (*models.MetricSeries, error) {
+	series := &models.MetricSeries{
+		MetricID:    metricId,
+		Aggregation: query.Aggregation,
+		Interval:    query.Interval.String(),
+		From:        query.From,
+		To:          query.To,
+		Instances:   make([]string, 0),
+		Buckets:     make([]models.SeriesBucket, 0),
+	}
+	if len(members) == 0 {
+		return series, nil
+	}
+
+	templates := make(map[string]*models.Template)
+	units := make([]string, 0)
+	for _, member := range members {
+		memberTemplate, ok := templates[member.Parent]
+		if !ok {
+			fetchedTemplate, err := s.templateService.GetTemplate(tenantId, member.Parent)
+			if err != nil {
+				return nil, err
+			}
+			templates[member.Parent] = fetchedTemplate
+			memberTemplate = fetchedTemplate
+		}
+
+		templateMetricIndex := slices.IndexFunc(memberTemplate.Metrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == metricId
+		})
+		if templateMetricIndex == -1 {
+			continue
+		}
+
+		series.Instances = append(series.Instances, member.ExternalId)
+		if unit := memberTemplate.Metrics[templateMetricIndex].Unit; !slices.Contains(units, unit) {
+			units = append(units, unit)
+		}
+	}
+
+	validationErr := &models.ValidationError{}
+	if len(series.Instances) == 0 {
+		validationErr.Add("metricId", metricId, models.ValidationCodeUnknownField, "metric is not defined on any of the selected instances")
+		return nil, validationErr
+	}
+
+	series.Unit = query.Unit
+	if series.Unit == "" {
+		series.Unit = units[0]
+	}
+	converter := s.newUnitConverter()
+	for _, unit := range units {
+		conversion, err := converter.conversion(unit, series.Unit)
+		if isUnitError(err) {
+			validationErr.Add("unit", metricId, models.ValidationCodeUnitMismatch, err.Error())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !conversion.IsIdentity() {
+			query.Conversions = append(query.Conversions, conversion)
+		}
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{Key: "meta.tenantId", Value: tenantId},
+		{Key: "meta.instanceId", Value: bson.D{{Key: "$in", Value: series.Instances}}},
+		{Key: "meta.metricId", Value: metricId},
+	}
+	buckets, err := s.db.GetMetricSeries(filter, query)
+	if err != nil {
+		return nil, err
+	}
+	if buckets != nil {
+		series.Buckets = buckets
+	}
+
+	return series, nil
+}
@@ -0,0 +1,273 @@
+package instance
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestService_AddMetricPoints_Success_StoresParsedPoints(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	mockRepository.On("GetInstance", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "sensor1"}}).Return(newSensorInstance("sensor1"), nil)
+	mockRepository.On("AddMetricPoints", []models.MetricPoint{
+		{
+			Timestamp: timestamp.UTC(),
+			Meta:      models.MetricPointMeta{TenantID: "the-binary", InstanceID: "sensor1", MetricID: "temperature", Unit: "celsius"},
+			Value:     21.5,
+		},
+		{
+			Timestamp: timestamp.Add(time.Minute).UTC(),
+			Meta:      models.MetricPointMeta{TenantID: "the-binary", InstanceID: "sensor1", MetricID: "temperature", Unit: "celsius"},
+			Value:     float64(22),
+		},
+	}).Return(nil)
+
+	later := timestamp.Add(time.Minute)
+	actualErr := mockService.AddMetricPoints("the-binary", "Sensor1", "temperature", []models.MetricPointInput{
+		{Timestamp: &timestamp, Value: 21.5, Unit: "celsius"},
+		{Timestamp: &later, Value: "22"},
+	})
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddMetricPoints_CompatibleUnit_NormalisesToMetricUnit(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(newSensorInstance("sensor1"), nil)
+	mockRepository.On("AddMetricPoints", mock.MatchedBy(func(points []models.MetricPoint) bool {
+		return len(points) == 2 && points[0].Value == 1500 && points[1].Value == 42 && points[0].Meta.Unit == "kwh"
+	})).Return(nil)
+
+	actualErr := mockService.AddMetricPoints("the-binary", "sensor1", "energy", []models.MetricPointInput{
+		{Value: 1.5, Unit: "MWh"},
+		{Value: 42, Unit: "kwh"},
+	})
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddMetricPoints_InstanceNotFound_ReturnsNotFound(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(nil, db.ErrNotFound)
+
+	actualErr := mockService.AddMetricPoints("the-binary", "missing", "temperature", []models.MetricPointInput{{Value: 21.5}})
+	assert.ErrorIs(t, actualErr, db.ErrNotFound)
+
+	mockRepository.AssertNotCalled(t, "AddMetricPoints", mock.Anything)
+}
+
+func TestService_AddMetricPointBatch_InvalidPoints_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	mockRepository.On("GetInstance", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "sensor1"}}).Return(newSensorInstance("sensor1"), nil).Once()
+	mockRepository.On("GetInstance", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "sensor2"}}).Return(nil, db.ErrNotFound).Once()
+
+	actualErr := mockService.AddMetricPointBatch("the-binary", []models.MetricPointInput{
+		{InstanceID: "sensor1", MetricID: "temperature", Value: 20.5},
+		{InstanceID: "sensor1", MetricID: "temperature", Value: "warm"},
+		{InstanceID: "sensor1", MetricID: "temperature", Value: 70, Unit: "kwh"},
+		{InstanceID: "sensor1", MetricID: "occupancy", Value: 4},
+		{InstanceID: "sensor1", MetricID: "humidity", Value: 40},
+		{InstanceID: "sensor2", MetricID: "temperature", Value: 19},
+		{InstanceID: "sensor1", MetricID: "temperature"},
+	})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []string{
+		"points[1].value", "points[2].unit", "points[3].metricId", "points[4].metricId", "points[5].instanceId", "points[6].value",
+	}, fieldsOf(validationErr))
+	assert.Equal(t, []string{
+		models.ValidationCodeTypeMismatch, models.ValidationCodeUnitMismatch, models.ValidationCodeNotAllowed,
+		models.ValidationCodeUnknownField, models.ValidationCodeUnknownField, models.ValidationCodeRequired,
+	}, codesOf(validationErr))
+
+	mockRepository.AssertExpectations(t)
+	mockRepository.AssertNotCalled(t, "AddMetricPoints", mock.Anything)
+}
+
+func TestService_AddMetricPointBatch_NoPoints_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	actualErr := mockService.AddMetricPointBatch("the-binary", nil)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []string{models.ValidationCodeRequired}, codesOf(validationErr))
+
+	mockRepository.AssertNotCalled(t, "GetInstance", mock.Anything)
+}
+
+func TestService_GetInstance_SourcedMetrics_ReturnsLatestValues(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(newSensorInstance("sensor1"), nil)
+	mockRepository.On("GetLatestMetricPoints", bson.D{{Key: "meta.tenantId", Value: "the-binary"}, {Key: "meta.instanceId", Value: "sensor1"}}).Return([]models.MetricPoint{
+		{Timestamp: timestamp, Meta: models.MetricPointMeta{InstanceID: "sensor1", MetricID: "temperature", Unit: "celsius"}, Value: 23.5},
+		{Timestamp: timestamp, Meta: models.MetricPointMeta{InstanceID: "sensor1", MetricID: "occupancy"}, Value: int32(7)},
+	}, nil)
+
+	actualInstance, actualErr := mockService.GetInstance("the-binary", "sensor1")
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []models.InstanceMetric{
+		{ID: "temperature", MetricBehaviour: "Sourced", Value: 23.5, Timestamp: &timestamp},
+		{ID: "occupancy", MetricBehaviour: "Manual", Value: 3},
+		{ID: "energy", MetricBehaviour: "Sourced"},
+	}, actualInstance.Metrics)
+}
+
+func TestService_GetMetricSeries_Success_ReturnsBuckets(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	query := models.SeriesQuery{From: from, To: from.Add(time.Hour), Interval: 30 * time.Minute, Aggregation: "avg"}
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(newSensorInstance("sensor1"), nil)
+	mockRepository.On("GetMetricSeries", bson.D{
+		{Key: "meta.tenantId", Value: "the-binary"},
+		{Key: "meta.instanceId", Value: bson.D{{Key: "$in", Value: []string{"sensor1"}}}},
+		{Key: "meta.metricId", Value: "temperature"},
+	}, query).Return([]models.SeriesBucket{
+		{Timestamp: from, Value: 21.25, Count: 30},
+		{Timestamp: from.Add(30 * time.Minute), Value: 22.0, Count: 30},
+	}, nil)
+
+	actualSeries, actualErr := mockService.GetMetricSeries("the-binary", "sensor1", "temperature", query)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, &models.MetricSeries{
+		MetricID:    "temperature",
+		Unit:        "celsius",
+		Aggregation: "avg",
+		Interval:    "30m0s",
+		From:        from,
+		To:          from.Add(time.Hour),
+		Instances:   []string{"sensor1"},
+		Buckets: []models.SeriesBucket{
+			{Timestamp: from, Value: 21.25, Count: 30},
+			{Timestamp: from.Add(30 * time.Minute), Value: 22.0, Count: 30},
+		},
+	}, actualSeries)
+}
+
+func TestService_GetMetricSeries_UnknownMetric_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(newSensorInstance("sensor1"), nil)
+
+	_, actualErr := mockService.GetMetricSeries("the-binary", "sensor1", "humidity", models.SeriesQuery{Aggregation: "avg", Interval: time.Minute})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []string{models.ValidationCodeUnknownField}, codesOf(validationErr))
+
+	mockRepository.AssertNotCalled(t, "GetMetricSeries", mock.Anything, mock.Anything)
+}
+
+func TestService_GetAggregateMetricSeries_Template_AggregatesDescendantInstances(t *testing.T) {
+	mockService, mockRepository, mockTemplateService, _ := newSeriesTestService()
+
+	query := models.SeriesQuery{Aggregation: "sum", Interval: time.Hour}
+	mockTemplateService.On("GetDescendantTemplates", "the-binary", "p.com.sensor").Return([]models.Template{
+		{BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.sensor.outdoor", Parent: "p.com.sensor"}},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.sensor.outdoor").Return(&models.Template{
+		Metrics: []models.TemplateMetric{{ID: "temperature", Unit: "celsius", IsSourced: true}},
+	}, nil)
+	mockRepository.On("GetAllInstances", bson.D{
+		{Key: "tenantId", Value: "the-binary"},
+		{Key: "basicInformation.parent", Value: bson.D{{Key: "$in", Value: []string{"p.com.sensor", "p.com.sensor.outdoor"}}}},
+	}, (*options.FindOptions)(nil)).Return([]models.Instance{
+		*newSensorInstance("sensor1"),
+		{BasicInformation: models.InstanceBasicInformation{ExternalId: "sensor2", Parent: "p.com.sensor.outdoor"}},
+	}, nil)
+	mockRepository.On("GetMetricSeries", bson.D{
+		{Key: "meta.tenantId", Value: "the-binary"},
+		{Key: "meta.instanceId", Value: bson.D{{Key: "$in", Value: []string{"sensor1", "sensor2"}}}},
+		{Key: "meta.metricId", Value: "temperature"},
+	}, query).Return(nil, nil)
+
+	actualSeries, actualErr := mockService.GetAggregateMetricSeries("the-binary", "temperature", models.SeriesScope{Template: "P.com.Sensor"}, query)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []string{"sensor1", "sensor2"}, actualSeries.Instances)
+	assert.Equal(t, "celsius", actualSeries.Unit)
+	assert.Equal(t, []models.SeriesBucket{}, actualSeries.Buckets)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_GetAggregateMetricSeries_RelationshipWithMixedUnits_ConvertsToFirstUnit(t *testing.T) {
+	mockService, mockRepository, mockTemplateService, mockCommonService := newSeriesTestService()
+
+	monitorsId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{{ID: monitorsId, Name: "monitors"}}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.sensor.imperial").Return(&models.Template{
+		Metrics: []models.TemplateMetric{{ID: "temperature", Unit: "fahrenheit", IsSourced: true}},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.room").Return(&models.Template{}, nil)
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "room1", Parent: "p.com.room"},
+		Relationships: []models.InstanceRelationship{
+			{Target: primitive.A{"sensor1", "sensor2"}, RelationshipTemplateId: monitorsId},
+		},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.Anything).Return([]models.Instance{
+		*newSensorInstance("sensor1"),
+		{BasicInformation: models.InstanceBasicInformation{ExternalId: "sensor2", Parent: "p.com.sensor.imperial"}},
+	}, nil)
+	mockRepository.On("GetMetricSeries", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(query models.SeriesQuery) bool {
+		return len(query.Conversions) == 1 && query.Conversions[0].Unit == "fahrenheit" &&
+			assert.InDelta(t, 100, query.Conversions[0].Apply(212), 1e-9)
+	})).Return([]models.SeriesBucket{}, nil)
+
+	actualSeries, actualErr := mockService.GetAggregateMetricSeries("the-binary", "temperature", models.SeriesScope{Instance: "room1", Relationships: []string{"monitors"}, Depth: 1}, models.SeriesQuery{})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, "celsius", actualSeries.Unit)
+	assert.Equal(t, []string{"sensor1", "sensor2"}, actualSeries.Instances)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_GetMetricSeries_IncompatibleUnit_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository, _, _ := newSeriesTestService()
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(newSensorInstance("sensor1"), nil)
+
+	_, actualErr := mockService.GetMetricSeries("the-binary", "sensor1", "temperature", models.SeriesQuery{Unit: "kwh"})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []string{"unit"}, fieldsOf(validationErr))
+	assert.Equal(t, []string{models.ValidationCodeUnitMismatch}, codesOf(validationErr))
+
+	mockRepository.AssertNotCalled(t, "GetMetricSeries", mock.Anything, mock.Anything)
+}
+
+func fieldsOf(validationErr *models.ValidationError) []string {
+	fields := make([]string, 0, len(validationErr.Errors))
+	for _, fieldErr := range validationErr.Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	return fields
+}
+
+func codesOf(validationErr *models.ValidationError) []string {
+	codes := make([]string, 0, len(validationErr.Errors))
+	for _, fieldErr := range validationErr.Errors {
+		codes = append(codes, fieldErr.Code)
+	}
+	return codes
+}
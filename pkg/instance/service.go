@@ -1,6 +1,7 @@
 package instance
 
 import (
+	"api/pkg/audit"
 	"api/pkg/common"
 	"api/pkg/db"
 	"api/pkg/models"
@@ -8,24 +9,54 @@ import (
 	"cmp"
 	"errors"
 	"fmt"
+	"io"
 	"log"
-	"regexp"
 	"slices"
-	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Service interface {
 	AddInstance(tenantId string, instance models.Instance) error
+	UpdateInstance(tenantId string, instanceExternalId string, instance models.Instance, expectedVersion int64) (int64, error)
+	DeleteInstance(tenantId string, instanceExternalId string, restrict bool) ([]string, error)
 	GetCreateInstanceForm(tenantId string, parentTemplateExternalId string) (*models.InstanceFormMetaData, error)
-	GetInstances(tenantId string) ([]models.Instance, error)
+	GetInstances(tenantId string, query models.ListQuery) (*models.InstancePage, error)
 	GetInstance(tenantId string, instanceExternalId string) (*models.Instance, error)
+	GetInstanceGraph(tenantId string, instanceExternalId string, depth int, relationshipNames []string) (*models.InstanceGraph, error)
 	GetApplicableRelationshipInstances(tenantId, relationshipTemplateId, parentTemplate, instanceExternalIdToExclude string) ([]models.Instance, error)
+	AddMetricPoints(tenantId string, instanceExternalId string, metricId string, points []models.MetricPointInput) error
+	AddMetricPointBatch(tenantId string, points []models.MetricPointInput) error
+	GetMetricSeries(tenantId string, instanceExternalId string, metricId string, query models.SeriesQuery) (*models.MetricSeries, error)
+	GetAggregateMetricSeries(tenantId string, metricId string, scope models.SeriesScope, query models.SeriesQuery) (*models.MetricSeries, error)
+	ImportInstances(tenantId string, importOptions models.ImportOptions, data io.Reader) (*models.ImportReport, error)
+	ExportInstances(tenantId string, exportOptions models.ExportOptions, w io.Writer) error
+	GetInstanceVersions(tenantId string, instanceExternalId string) ([]models.VersionSummary, error)
+	GetInstanceVersion(tenantId string, instanceExternalId string, version int64) (*models.Instance, error)
+	DiffInstanceVersions(tenantId string, instanceExternalId string, from int64, to int64) (*models.VersionDiff, error)
+	RestoreInstanceVersion(tenantId string, instanceExternalId string, version int64, expectedVersion int64) (int64, error)
+	WithAuditContext(auditContext models.AuditContext) Service
+}
+
+var ErrInstanceReferenced = errors.New("instance is referenced by other instances")
+
+type CardinalityError struct {
+	Relationship string   `json:"relationship"`
+	Cardinality  string   `json:"cardinality"`
+	Reason       string   `json:"reason"`
+	ExternalIds  []string `json:"externalIds"`
+}
+
+func (e *CardinalityError) Error() string {
+	return fmt.Sprintf("relationship %s (%s) %s: %s", e.Relationship, e.Cardinality, e.Reason, strings.Join(e.ExternalIds, ", "))
+}
+
+type inverseRelationshipChange struct {
+	target                *models.Instance
+	inverseRelationshipId primitive.ObjectID
 }
 
 type service struct {
@@ -109,11 +140,10 @@ func (s *service) GetApplicableRelationshipInstances(tenantId, relationshipTempl
 	return instances, nil
 }
 
-func (s *service) GetInstances(tenantId string) ([]models.Instance, error) {
-	filter := bson.D{{Key: "tenantId", Value: tenantId}}
-	opts := options.Find().SetSort(bson.D{{Key: "basicInformation.externalId", Value: 1}})
+func (s *service) GetInstances(tenantId string, query models.ListQuery) (*models.InstancePage, error) {
+	query.TenantID = tenantId
 
-	instances, err := s.db.GetAllInstances(filter, opts)
+	instances, err := s.db.ListInstances(query)
 	if err != nil {
 		log.Println("error getting all instances: ", err)
 		return nil, err
@@ -123,15 +153,113 @@ func (s *service) GetInstances(tenantId string) ([]models.Instance, error) {
 }
 
 func (s *service) GetInstance(tenantId string, instanceExternalId string) (*models.Instance, error) {
+	instance, err := s.getStoredInstance(tenantId, instanceExternalId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyLatestMetricPoints(tenantId, instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// getStoredInstance returns the instance as persisted, without the latest sourced metric points,
+// so write paths never save a sensor reading back into the document.
+func (s *service) getStoredInstance(tenantId string, instanceExternalId string) (*models.Instance, error) {
 	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instanceExternalId}}
 
-	template, err := s.db.GetInstance(filter)
+	instance, err := s.db.GetInstance(filter)
 	if err != nil {
-		log.Println("error getting template: ", err)
+		log.Println("error getting instance: ", err)
 		return nil, err
 	}
 
-	return template, nil
+	return instance, nil
+}
+
+func (s *service) GetInstanceGraph(tenantId string, instanceExternalId string, depth int, relationshipNames []string) (*models.InstanceGraph, error) {
+	root, err := s.GetInstance(tenantId, strings.ToLower(instanceExternalId))
+	if err != nil {
+		log.Println("error getting instance: ", err)
+		return nil, err
+	}
+
+	relationshipTemplates, err := s.commonService.GetRelationships()
+	if err != nil {
+		log.Println("error fetching relationships: ", err)
+		return nil, err
+	}
+	relationshipNamesById := make(map[primitive.ObjectID]string, len(relationshipTemplates))
+	for _, relationshipTemplate := range relationshipTemplates {
+		relationshipNamesById[relationshipTemplate.ID] = relationshipTemplate.Name
+	}
+
+	graph := &models.InstanceGraph{
+		Nodes: make([]models.InstanceGraphNode, 0),
+		Edges: make([]models.InstanceGraphEdge, 0),
+	}
+	// visited holds every queued external id; it is true once the instance was found and added as a node.
+	visited := map[string]bool{root.BasicInformation.ExternalId: false}
+	frontier := []models.Instance{*root}
+
+	for level := 0; len(frontier) > 0; level++ {
+		nextExternalIds := make([]string, 0)
+		for _, instance := range frontier {
+			visited[instance.BasicInformation.ExternalId] = true
+			graph.Nodes = append(graph.Nodes, models.InstanceGraphNode{
+				ExternalId:   instance.BasicInformation.ExternalId,
+				Name:         instance.BasicInformation.Name,
+				Parent:       instance.BasicInformation.Parent,
+				RootTemplate: instance.BasicInformation.RootTemplate,
+				Depth:        level,
+			})
+			if level == depth {
+				continue
+			}
+
+			for _, relationship := range instance.Relationships {
+				relationshipName := relationshipNamesById[relationship.RelationshipTemplateId]
+				if len(relationshipNames) > 0 && !slices.Contains(relationshipNames, relationshipName) {
+					continue
+				}
+
+				for _, targetExternalId := range relationship.TargetExternalIds() {
+					graph.Edges = append(graph.Edges, models.InstanceGraphEdge{
+						Source:                 instance.BasicInformation.ExternalId,
+						Target:                 targetExternalId,
+						Relationship:           relationshipName,
+						RelationshipTemplateId: relationship.RelationshipTemplateId,
+					})
+					if _, ok := visited[targetExternalId]; !ok {
+						visited[targetExternalId] = false
+						nextExternalIds = append(nextExternalIds, targetExternalId)
+					}
+				}
+			}
+		}
+
+		if len(nextExternalIds) == 0 {
+			break
+		}
+
+		filter := bson.D{
+			{Key: "tenantId", Value: tenantId},
+			{Key: "basicInformation.externalId", Value: bson.D{{Key: "$in", Value: nextExternalIds}}},
+		}
+		frontier, err = s.db.GetAllInstances(filter, nil)
+		if err != nil {
+			log.Println("error fetching related instances: ", err)
+			return nil, err
+		}
+	}
+
+	graph.Edges = slices.DeleteFunc(graph.Edges, func(edge models.InstanceGraphEdge) bool {
+		return !visited[edge.Target]
+	})
+
+	return graph, nil
 }
 
 func (s *service) GetCreateInstanceForm(tenantId string, parentTemplateExternalId string) (*models.InstanceFormMetaData, error) {
@@ -215,19 +343,16 @@ func (s *service) GetCreateInstanceForm(tenantId string, parentTemplateExternalI
 	ret.Attributes.Fields = make([]models.InstanceMetaDataFields, 0)
 	for _, attr := range parentAttributes {
 		if attr.ID != "a25aefe5-b5aa-44b9-9ddf-1f911d1af502" && attr.ID != "c2134cea-ddd2-43f7-a775-e4d12742ef79" && attr.ID != "39a04903-435e-4f91-9c68-4772292dca4a" && attr.ID != "2bf69f85-50b0-4c31-a329-9bf4121a9045" {
-			attributeTypeIndex, _ := slices.BinarySearchFunc(attributeTypes, models.Dropdown{
-				Value: attr.DataType,
-			}, func(dropdown models.Dropdown, dropdown2 models.Dropdown) int {
-				return cmp.Compare(dropdown.Value, dropdown2.Value)
-			})
 			ret.Attributes.Fields = append(ret.Attributes.Fields, models.InstanceMetaDataFields{
-				ID:         attr.ID,
-				Label:      attr.Name,
-				TypeLabel:  attributeTypes[attributeTypeIndex].Label,
-				Type:       attr.DataType,
-				InfoText:   "",
-				IsRequired: attr.IsRequired,
-				IsHidden:   attr.IsHidden,
+				ID:          attr.ID,
+				Label:       attr.Name,
+				TypeLabel:   attributeTypeLabel(attributeTypes, attr.DataType),
+				Type:        attr.DataType,
+				InfoText:    "",
+				IsRequired:  attr.IsRequired,
+				IsHidden:    attr.IsHidden,
+				Options:     attr.EnumValues,
+				Constraints: attr.Constraints,
 			})
 		}
 	}
@@ -252,33 +377,70 @@ func (s *service) GetCreateInstanceForm(tenantId string, parentTemplateExternalI
 			DropdownValues: dropdownValues,
 			ManualValue:    manualValue,
 			Unit:           metric.Unit,
+			Constraints:    metric.Constraints,
 		})
 	}
 
 	return &ret, nil
 }
 
-func (s *service) AddInstance(tenantId string, instance models.Instance) error {
-	if instance.BasicInformation.Name == "" {
-		return fmt.Errorf("%s is required but not provided", "Name")
+func attributeTypeLabel(attributeTypes []models.Dropdown, dataType string) string {
+	attributeTypeIndex := slices.IndexFunc(attributeTypes, func(dropdown models.Dropdown) bool {
+		return dropdown.Value == dataType
+	})
+	if attributeTypeIndex != -1 {
+		return attributeTypes[attributeTypeIndex].Label
 	}
+	if label, ok := models.DataTypeLabels[dataType]; ok {
+		return label
+	}
+	return dataType
+}
 
-	if instance.BasicInformation.ExternalId == "" {
-		return fmt.Errorf("%s is required but not provided", "External Id")
+func (s *service) AddInstance(tenantId string, instance models.Instance) error {
+	parentTemplate, err := s.prepareInstance(tenantId, &instance)
+	if err != nil {
+		return err
 	}
 
+	return s.db.WithTransaction(func(tx db.Repository) error {
+		txService := s.withRepository(tx)
+		if err := txService.validateRelationships(instance, nil); err != nil {
+			log.Println("error validating relationships: ", err)
+			return err
+		}
+
+		assignRelationshipIds(instance.Relationships)
+
+		calculator := txService.newCalculator(tenantId)
+		calculator.Calculate(&instance, parentTemplate)
+
+		instance.Version = models.InitialVersion
+		if err := tx.AddOne("instances", instance); err != nil {
+			log.Println("error adding instance: ", err)
+			return err
+		}
+		return calculator.Propagate([]string{instance.BasicInformation.ExternalId})
+	})
+}
+
+// prepareInstance normalises a new instance against its parent template and validates
+// everything except its relationships, without writing anything.
+func (s *service) prepareInstance(tenantId string, instance *models.Instance) (*models.Template, error) {
+	validationErr := validateBasicInformation(instance.BasicInformation, true)
 	if instance.BasicInformation.Parent == "" {
-		return fmt.Errorf("%s is required but not provided", "Parent")
+		return nil, validationErr.ErrOrNil()
 	}
 
 	instance.BasicInformation.IsCustom = true
 	instance.TenantID = tenantId
 	instance.BasicInformation.ExternalId = strings.ToLower(instance.BasicInformation.ExternalId)
+	instance.LowercaseRelationshipTargets()
 
 	parentTemplate, err := s.templateService.GetTemplate(tenantId, instance.BasicInformation.Parent)
 	if err != nil {
 		log.Println("error getting template: ", err)
-		return err
+		return nil, err
 	}
 
 	if parentTemplate.BasicInformation.RootTemplate == "" {
@@ -287,36 +449,252 @@ func (s *service) AddInstance(tenantId string, instance models.Instance) error {
 		instance.BasicInformation.RootTemplate = parentTemplate.BasicInformation.RootTemplate
 	}
 
-	if err := validateAttributes(instance.Attributes, parentTemplate.Attributes); err != nil {
-		log.Println("error validating attribute: ", err)
-		return err
+	instance.Attributes = applyAttributeDefaults(instance.Attributes, parentTemplate.Attributes)
+	instance.Metrics = applyMetricDefaults(instance.Metrics, parentTemplate.Metrics)
+	validationErr.Merge(validateAttributes(instance.Attributes, parentTemplate.Attributes))
+	if err := s.normaliseMetricUnits(instance.Metrics, parentTemplate.Metrics); err != nil {
+		var unitErr *models.ValidationError
+		if !errors.As(err, &unitErr) {
+			return nil, err
+		}
+		validationErr.Merge(unitErr)
+	}
+	validationErr.Merge(validateMetrics(instance.Metrics, parentTemplate.Metrics))
+	if err := s.validateUniqueness(*instance, *parentTemplate); err != nil {
+		var uniquenessErr *models.ValidationError
+		if !errors.As(err, &uniquenessErr) {
+			return nil, err
+		}
+		validationErr.Merge(uniquenessErr)
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		log.Println("error validating instance: ", err)
+		return nil, err
 	}
 
-	if err := validateMetrics(instance.Metrics, parentTemplate.Metrics); err != nil {
-		log.Println("error validating metric: ", err)
-		return err
+	return parentTemplate, nil
+}
+
+func (s *service) withRepository(repository db.Repository) *service {
+	return &service{
+		db:              repository,
+		templateService: s.templateService,
+		commonService:   s.commonService,
 	}
+}
 
-	if err := s.validateRelationships(instance); err != nil {
-		log.Println("error validating relationships: ", err)
-		return err
+func (s *service) WithAuditContext(auditContext models.AuditContext) Service {
+	return s.withRepository(audit.NewRepository(s.db, auditContext))
+}
+
+func (s *service) UpdateInstance(tenantId string, instanceExternalId string, instance models.Instance, expectedVersion int64) (int64, error) {
+	validationErr := validateBasicInformation(instance.BasicInformation, false)
+
+	existingInstance, err := s.getStoredInstance(tenantId, strings.ToLower(instanceExternalId))
+	if err != nil {
+		log.Println("error getting instance: ", err)
+		return 0, err
+	}
+	if expectedVersion != 0 && expectedVersion != max(existingInstance.Version, models.InitialVersion) {
+		log.Printf("instance %s is not at version %d\n", existingInstance.BasicInformation.ExternalId, expectedVersion)
+		return 0, db.ErrPreconditionFailed
 	}
 
-	for i, instanceRelationship := range instance.Relationships {
-		newRelationshipId, _ := uuid.NewUUID()
-		if instanceRelationship.ID == "" {
-			instance.Relationships[i].ID = newRelationshipId.String()
+	instance.TenantID = tenantId
+	instance.BasicInformation.ExternalId = existingInstance.BasicInformation.ExternalId
+	instance.BasicInformation.Parent = existingInstance.BasicInformation.Parent
+	instance.BasicInformation.RootTemplate = existingInstance.BasicInformation.RootTemplate
+	instance.BasicInformation.IsCustom = existingInstance.BasicInformation.IsCustom
+	instance.LowercaseRelationshipTargets()
+
+	parentTemplate, err := s.templateService.GetTemplate(tenantId, instance.BasicInformation.Parent)
+	if err != nil {
+		log.Println("error getting template: ", err)
+		return 0, err
+	}
+
+	instance.Attributes = applyAttributeDefaults(instance.Attributes, parentTemplate.Attributes)
+	instance.Metrics = applyMetricDefaults(instance.Metrics, parentTemplate.Metrics)
+	validationErr.Merge(validateAttributes(instance.Attributes, parentTemplate.Attributes))
+	if err := s.normaliseMetricUnits(instance.Metrics, parentTemplate.Metrics); err != nil {
+		var unitErr *models.ValidationError
+		if !errors.As(err, &unitErr) {
+			return 0, err
 		}
+		validationErr.Merge(unitErr)
+	}
+	validationErr.Merge(validateMetrics(instance.Metrics, parentTemplate.Metrics))
+	if err := s.validateUniqueness(instance, *parentTemplate); err != nil {
+		var uniquenessErr *models.ValidationError
+		if !errors.As(err, &uniquenessErr) {
+			return 0, err
+		}
+		validationErr.Merge(uniquenessErr)
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		log.Println("error validating instance: ", err)
+		return 0, err
 	}
 
-	if err := s.db.AddOne("instances", instance); err != nil {
-		log.Println("error adding instance: ", err)
-		return err
+	var version int64
+	err = s.db.WithTransaction(func(tx db.Repository) error {
+		txService := s.withRepository(tx)
+		if err := txService.validateRelationships(instance, existingInstance.Relationships); err != nil {
+			log.Println("error validating relationships: ", err)
+			return err
+		}
+
+		assignRelationshipIds(instance.Relationships)
+
+		calculator := txService.newCalculator(tenantId)
+		calculator.Calculate(&instance, parentTemplate)
+
+		filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instance.BasicInformation.ExternalId}}
+		replacedVersion, err := tx.ReplaceInstance(db.VersionFilter(filter, expectedVersion), instance)
+		if err != nil {
+			log.Println("error updating instance: ", err)
+			return err
+		}
+		version = replacedVersion
+		return calculator.Propagate([]string{instance.BasicInformation.ExternalId})
+	})
+	if err != nil {
+		return 0, err
 	}
-	return nil
+
+	return version, nil
+}
+
+func (s *service) DeleteInstance(tenantId string, instanceExternalId string, restrict bool) ([]string, error) {
+	instanceExternalId = strings.ToLower(instanceExternalId)
+	instance, err := s.getStoredInstance(tenantId, instanceExternalId)
+	if err != nil {
+		log.Println("error getting instance: ", err)
+		return nil, err
+	}
+
+	referencingFilter := bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "basicInformation.externalId", Value: bson.D{{Key: "$ne", Value: instanceExternalId}}},
+		{Key: "relationships.target", Value: instanceExternalId},
+	}
+
+	var referencingExternalIds []string
+	err = s.db.WithTransaction(func(tx db.Repository) error {
+		txService := s.withRepository(tx)
+		referencingInstances, err := tx.GetAllInstances(referencingFilter, nil)
+		if err != nil {
+			log.Println("error fetching referencing instances: ", err)
+			return err
+		}
+
+		if restrict {
+			referencingExternalIds, err = txService.restrictingReferences(instance, referencingInstances)
+			if err != nil {
+				return err
+			}
+			if len(referencingExternalIds) > 0 {
+				return ErrInstanceReferenced
+			}
+		}
+
+		calculator := txService.newCalculator(tenantId)
+		strippedExternalIds := make([]string, 0)
+		for i := range referencingInstances {
+			referencingInstance := &referencingInstances[i]
+			if !referencingInstance.RemoveRelationshipTargets([]string{instanceExternalId}) {
+				continue
+			}
+			strippedExternalIds = append(strippedExternalIds, referencingInstance.BasicInformation.ExternalId)
+
+			referencingTemplate, err := calculator.Template(referencingInstance.BasicInformation.Parent)
+			if err != nil {
+				return err
+			}
+			calculator.Calculate(referencingInstance, referencingTemplate)
+
+			filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: referencingInstance.BasicInformation.ExternalId}}
+			if _, err := tx.ReplaceInstance(filter, referencingInstance); err != nil {
+				log.Println("error updating instance: ", err)
+				return err
+			}
+		}
+
+		filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instanceExternalId}}
+		if err := tx.DeleteInstance(filter); err != nil {
+			log.Println("error deleting instance: ", err)
+			return err
+		}
+		return calculator.Propagate(strippedExternalIds)
+	})
+	if errors.Is(err, ErrInstanceReferenced) {
+		return referencingExternalIds, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// restrictingReferences returns the referencing instances that link to the instance on their own
+// account. A reference is ignored when it is only the inverse of one of the instance's relationships,
+// since deleting the instance removes that link together with the relationship it mirrors.
+func (s *service) restrictingReferences(instance *models.Instance, referencingInstances []models.Instance) ([]string, error) {
+	inverseOf := make(map[primitive.ObjectID]primitive.ObjectID)
+	if len(instance.Relationships) > 0 {
+		relationshipTemplates, err := s.commonService.GetRelationships()
+		if err != nil {
+			log.Println("error fetching relationships: ", err)
+			return nil, err
+		}
+		for _, relationshipTemplate := range relationshipTemplates {
+			if !relationshipTemplate.Inverse.IsZero() {
+				inverseOf[relationshipTemplate.ID] = relationshipTemplate.Inverse
+			}
+		}
+	}
+
+	referencingExternalIds := make([]string, 0)
+	for _, referencingInstance := range referencingInstances {
+		referencingExternalId := referencingInstance.BasicInformation.ExternalId
+		for _, referencingRelationship := range referencingInstance.Relationships {
+			if !slices.Contains(referencingRelationship.TargetExternalIds(), instance.BasicInformation.ExternalId) {
+				continue
+			}
+			isInverse := slices.ContainsFunc(instance.Relationships, func(ir models.InstanceRelationship) bool {
+				inverseRelationshipId, ok := inverseOf[ir.RelationshipTemplateId]
+				return ok && inverseRelationshipId == referencingRelationship.RelationshipTemplateId &&
+					slices.Contains(ir.TargetExternalIds(), referencingExternalId)
+			})
+			if !isInverse {
+				referencingExternalIds = append(referencingExternalIds, referencingExternalId)
+				break
+			}
+		}
+	}
+	return referencingExternalIds, nil
+}
+
+func validateBasicInformation(basicInformation models.InstanceBasicInformation, isCreate bool) *models.ValidationError {
+	validationErr := &models.ValidationError{}
+	if basicInformation.Name == "" {
+		validationErr.Add("basicInformation.name", "", models.ValidationCodeRequired, "Name is required but not provided")
+	}
+	if !isCreate {
+		return validationErr
+	}
+	if basicInformation.ExternalId == "" {
+		validationErr.Add("basicInformation.externalId", "", models.ValidationCodeRequired, "External Id is required but not provided")
+	}
+	if basicInformation.Parent == "" {
+		validationErr.Add("basicInformation.parent", "", models.ValidationCodeRequired, "Parent is required but not provided")
+	}
+	return validationErr
 }
 
 func validateAttributes(instanceAttributes []models.InstanceAttribute, templateAttributes []models.TemplateAttribute) error {
+	validationErr := &models.ValidationError{}
 	for _, attribute := range templateAttributes {
 		if attribute.ID == "a25aefe5-b5aa-44b9-9ddf-1f911d1af502" || attribute.ID == "c2134cea-ddd2-43f7-a775-e4d12742ef79" || attribute.ID == "2bf69f85-50b0-4c31-a329-9bf4121a9045" || attribute.ID == "39a04903-435e-4f91-9c68-4772292dca4a" {
 			continue
@@ -325,135 +703,223 @@ func validateAttributes(instanceAttributes []models.InstanceAttribute, templateA
 			if exists := slices.ContainsFunc(instanceAttributes, func(ia models.InstanceAttribute) bool {
 				return ia.ID == attribute.ID
 			}); !exists {
-				return fmt.Errorf("attribute %s is required but not provided", attribute.Name)
+				validationErr.Add("attributes", attribute.ID, models.ValidationCodeRequired, fmt.Sprintf("attribute %s is required but not provided", attribute.Name))
 			}
 		}
 	}
 
 	for i, attribute := range instanceAttributes {
-		if isValidAttribute := slices.ContainsFunc(templateAttributes, func(ta models.TemplateAttribute) bool {
-			attributeId := attribute.ID
-			attributeValue := attribute.Value.(string)
-			if ta.ID == attribute.ID {
-				if ta.IsRequired && len(attributeValue) == 0 {
-					log.Printf("attribute %s marked as required is empty", attributeId)
-					return false
-				}
-				if attributeValue != "" {
-					switch ta.DataType {
-					case "integer":
-						integerValue, err := strconv.Atoi(attributeValue)
-						if err != nil {
-							log.Printf("attribute %s is not an integer value", attributeId)
-							return false
-						}
-						instanceAttributes[i].Value = integerValue
-					case "float":
-						floatValue, err := strconv.ParseFloat(attributeValue, 64)
-						if err != nil {
-							log.Printf("attribute %s is not a float value", attributeId)
-							return false
-						}
-						instanceAttributes[i].Value = floatValue
-					case "bool":
-						booleanValue, err := strconv.ParseBool(strings.ToLower(attributeValue))
-						if err != nil {
-							log.Printf("attribute %s is not a boolean value", attributeId)
-							return false
-						}
-						instanceAttributes[i].Value = booleanValue
-					case "string":
-						match, _ := regexp.MatchString("^[a-zA-Z0-9\\s]*$", attributeValue)
-						if !match {
-							log.Printf("attribute %s is not a valid string", attributeId)
-						}
-						instanceAttributes[i].Value = attributeValue
-					}
-				}
+		templateAttributeIndex := slices.IndexFunc(templateAttributes, func(ta models.TemplateAttribute) bool {
+			return ta.ID == attribute.ID
+		})
+		if templateAttributeIndex == -1 {
+			validationErr.Add(fmt.Sprintf("attributes[%d].id", i), attribute.ID, models.ValidationCodeUnknownField, "attribute is not defined on the template")
+			continue
+		}
 
-				return true
+		templateAttribute := templateAttributes[templateAttributeIndex]
+		field := fmt.Sprintf("attributes[%d].value", i)
+		attributeValue := models.ValueString(attribute.Value)
+		if attributeValue == "" {
+			if templateAttribute.IsRequired {
+				validationErr.Add(field, attribute.ID, models.ValidationCodeRequired, fmt.Sprintf("attribute %s is required but empty", templateAttribute.Name))
 			}
+			continue
+		}
 
-			return false
-		}); !isValidAttribute {
-			return errors.New("error validating attributes")
+		value, err := models.ParseAttributeValue(templateAttribute, attributeValue)
+		if err != nil {
+			validationErr.Add(field, attribute.ID, models.ValueErrorCode(err), err.Error())
+			continue
 		}
-		continue
+		instanceAttributes[i].Value = value
 	}
-	return nil
+	return validationErr.ErrOrNil()
+}
+
+func applyAttributeDefaults(instanceAttributes []models.InstanceAttribute, templateAttributes []models.TemplateAttribute) []models.InstanceAttribute {
+	for _, templateAttribute := range templateAttributes {
+		if !templateAttribute.Constraints.HasDefault() {
+			continue
+		}
+		attributeIndex := slices.IndexFunc(instanceAttributes, func(ia models.InstanceAttribute) bool {
+			return ia.ID == templateAttribute.ID
+		})
+		if attributeIndex == -1 {
+			instanceAttributes = append(instanceAttributes, models.InstanceAttribute{
+				ID:    templateAttribute.ID,
+				Value: templateAttribute.Constraints.Default,
+			})
+			continue
+		}
+		if models.ValueString(instanceAttributes[attributeIndex].Value) == "" {
+			instanceAttributes[attributeIndex].Value = templateAttribute.Constraints.Default
+		}
+	}
+	return instanceAttributes
+}
+
+func applyMetricDefaults(instanceMetrics []models.InstanceMetric, templateMetrics []models.TemplateMetric) []models.InstanceMetric {
+	for i, metric := range instanceMetrics {
+		if metric.MetricBehaviour != "Manual" || models.ValueString(metric.Value) != "" {
+			continue
+		}
+		templateMetricIndex := slices.IndexFunc(templateMetrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == metric.ID
+		})
+		if templateMetricIndex != -1 && templateMetrics[templateMetricIndex].Constraints.HasDefault() {
+			instanceMetrics[i].Value = templateMetrics[templateMetricIndex].Constraints.Default
+		}
+	}
+	return instanceMetrics
 }
 
 func validateMetrics(instanceMetrics []models.InstanceMetric, templateMetrics []models.TemplateMetric) error {
+	validationErr := &models.ValidationError{}
 	for i, metric := range instanceMetrics {
-		if metric.MetricBehaviour == "Manual" {
-			if isValidMetricValue := slices.ContainsFunc(templateMetrics, func(tm models.TemplateMetric) bool {
-				metricId := metric.ID
-				metricValue := metric.Value.(string)
-				if tm.ID == metric.ID {
-					if metricValue != "" {
-						switch tm.MetricType {
-						case "integer":
-							integerValue, err := strconv.Atoi(metricValue)
-							if err != nil {
-								log.Printf("metric %s is not an integer value", metricId)
-								return false
-							}
-							instanceMetrics[i].Value = integerValue
-						case "float":
-							floatValue, err := strconv.ParseFloat(metricValue, 64)
-							if err != nil {
-								log.Printf("metric %s is not a float value", metricId)
-								return false
-							}
-							instanceMetrics[i].Value = floatValue
-						case "bool":
-							booleanValue, err := strconv.ParseBool(strings.ToLower(metricValue))
-							if err != nil {
-								log.Printf("metric %s is not a boolean value", metricId)
-								return false
-							}
-							instanceMetrics[i].Value = booleanValue
-						case "string":
-							match, _ := regexp.MatchString("^[a-zA-Z0-9\\s]*$", metricValue)
-							if !match {
-								log.Printf("metric %s is not a valid string", metricId)
-							}
-							instanceMetrics[i].Value = metricValue
-						}
-					}
+		if metric.MetricBehaviour != "Manual" {
+			continue
+		}
 
-					return true
-				}
+		templateMetricIndex := slices.IndexFunc(templateMetrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == metric.ID
+		})
+		if templateMetricIndex == -1 {
+			validationErr.Add(fmt.Sprintf("metrics[%d].id", i), metric.ID, models.ValidationCodeUnknownField, "metric is not defined on the template")
+			continue
+		}
 
-				return false
-			}); !isValidMetricValue {
-				return errors.New("error validating metrics")
-			}
+		metricValue := models.ValueString(metric.Value)
+		if metricValue == "" {
+			continue
+		}
+
+		value, err := models.ParseMetricValue(templateMetrics[templateMetricIndex], metricValue)
+		if err != nil {
+			validationErr.Add(fmt.Sprintf("metrics[%d].value", i), metric.ID, models.ValueErrorCode(err), err.Error())
+			continue
 		}
-		continue
+		instanceMetrics[i].Value = value
 	}
-	return nil
+	return validationErr.ErrOrNil()
+}
+
+func (s *service) validateUniqueness(instance models.Instance, template models.Template) error {
+	validationErr := &models.ValidationError{}
+	for i, attribute := range instance.Attributes {
+		templateAttributeIndex := slices.IndexFunc(template.Attributes, func(ta models.TemplateAttribute) bool {
+			return ta.ID == attribute.ID
+		})
+		if templateAttributeIndex == -1 || !template.Attributes[templateAttributeIndex].Constraints.IsUnique() || models.ValueString(attribute.Value) == "" {
+			continue
+		}
+		taken, err := s.isValueTaken(instance, "attributes", attribute.ID, attribute.Value)
+		if err != nil {
+			return err
+		}
+		if taken {
+			validationErr.Add(fmt.Sprintf("attributes[%d].value", i), attribute.ID, models.ValidationCodeNotUnique, fmt.Sprintf("attribute %s must be unique but %v is already used", template.Attributes[templateAttributeIndex].Name, attribute.Value))
+		}
+	}
+
+	for i, metric := range instance.Metrics {
+		templateMetricIndex := slices.IndexFunc(template.Metrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == metric.ID
+		})
+		if templateMetricIndex == -1 || !template.Metrics[templateMetricIndex].Constraints.IsUnique() || metric.MetricBehaviour != "Manual" || models.ValueString(metric.Value) == "" {
+			continue
+		}
+		taken, err := s.isValueTaken(instance, "metrics", metric.ID, metric.Value)
+		if err != nil {
+			return err
+		}
+		if taken {
+			validationErr.Add(fmt.Sprintf("metrics[%d].value", i), metric.ID, models.ValidationCodeNotUnique, fmt.Sprintf("metric %s must be unique but %v is already used", template.Metrics[templateMetricIndex].Name, metric.Value))
+		}
+	}
+
+	return validationErr.ErrOrNil()
+}
+
+func (s *service) isValueTaken(instance models.Instance, field string, id string, value interface{}) (bool, error) {
+	filter := bson.D{
+		{Key: "tenantId", Value: instance.TenantID},
+		{Key: "basicInformation.externalId", Value: bson.D{{Key: "$ne", Value: instance.BasicInformation.ExternalId}}},
+		{Key: field, Value: bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "id", Value: id}, {Key: "value", Value: value}}}}},
+	}
+	instances, err := s.db.GetAllInstances(filter, nil)
+	if err != nil {
+		log.Println("error checking unique value: ", err)
+		return false, err
+	}
+	return len(instances) > 0, nil
 }
 
-func (s *service) validateRelationships(instance models.Instance) error {
+func (s *service) validateRelationships(instance models.Instance, previousRelationships []models.InstanceRelationship) error {
+	if len(instance.Relationships) == 0 && len(previousRelationships) == 0 {
+		return nil
+	}
+
 	relationshipTemplates, err := s.commonService.GetRelationships()
 	if err != nil {
 		log.Println("error fetching relationships: ", err)
 		return err
 	}
 
-	for _, instanceRelationship := range instance.Relationships {
+	inverseRelationshipsToAdd, err := s.checkRelationships(instance, previousRelationships, relationshipTemplates, make(map[string]*models.Instance))
+	if err != nil {
+		return err
+	}
+
+	for _, change := range inverseRelationshipsToAdd {
+		if err := s.addInverseRelationship(change.target, change.inverseRelationshipId, instance.BasicInformation.ExternalId); err != nil {
+			log.Println("error adding inverse relationship: ", err)
+			return err
+		}
+	}
+
+	for _, previousRelationship := range previousRelationships {
+		directRelationshipIndex := slices.IndexFunc(relationshipTemplates, func(r models.Relationship) bool {
+			return r.ID == previousRelationship.RelationshipTemplateId
+		})
+		if directRelationshipIndex == -1 || relationshipTemplates[directRelationshipIndex].Inverse.IsZero() {
+			continue
+		}
+		inverseRelationshipId := relationshipTemplates[directRelationshipIndex].Inverse
+
+		currentTargetExternalIds := relationshipTargets(instance.Relationships, previousRelationship.RelationshipTemplateId)
+		for _, targetExternalId := range previousRelationship.TargetExternalIds() {
+			if slices.Contains(currentTargetExternalIds, targetExternalId) {
+				continue
+			}
+
+			if err := s.removeInverseRelationship(instance.TenantID, targetExternalId, inverseRelationshipId, instance.BasicInformation.ExternalId); err != nil {
+				log.Println("error removing inverse relationship: ", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRelationships validates the relationships of an instance with reads only and returns the
+// inverse relationships its targets still need. Targets are looked up in targetInstances first.
+func (s *service) checkRelationships(instance models.Instance, previousRelationships []models.InstanceRelationship, relationshipTemplates []models.Relationship, targetInstances map[string]*models.Instance) ([]inverseRelationshipChange, error) {
+	var err error
+	inverseRelationshipsToAdd := make([]inverseRelationshipChange, 0)
+	for i, instanceRelationship := range instance.Relationships {
 		directRelationshipIndex := slices.IndexFunc(relationshipTemplates, func(r models.Relationship) bool {
 			return r.ID == instanceRelationship.RelationshipTemplateId
 		})
 		if directRelationshipIndex == -1 {
 			log.Printf("relationship not found: %s\n", instanceRelationship.RelationshipTemplateId)
-			return errors.New("error validating relationships")
+			return nil, relationshipValidationError(i, instanceRelationship.RelationshipTemplateId.Hex(), models.ValidationCodeUnknownField, "relationship is not defined")
 		}
 		directRelationship := relationshipTemplates[directRelationshipIndex]
 		if directRelationship.Source != instance.BasicInformation.RootTemplate {
 			log.Printf("relationship %s source not correct\n", instanceRelationship.ID)
-			return errors.New("error validating relationships")
+			return nil, relationshipValidationError(i, instanceRelationship.RelationshipTemplateId.Hex(), models.ValidationCodeTypeMismatch, fmt.Sprintf("relationship %s cannot start from %s", directRelationship.Name, instance.BasicInformation.RootTemplate))
 		}
 
 		var inverseRelationship models.Relationship
@@ -464,83 +930,198 @@ func (s *service) validateRelationships(instance models.Instance) error {
 			})
 			if inverseRelationshipIndex == -1 {
 				log.Printf("inverse relationship not found: %s\n", inverseRelationshipId)
-				return errors.New("error validating relationships")
+				return nil, relationshipValidationError(i, inverseRelationshipId.Hex(), models.ValidationCodeUnknownField, "inverse relationship is not defined")
 			}
 			inverseRelationship = relationshipTemplates[inverseRelationshipIndex]
 		}
 
-		targetExternalIdsToFind := make([]string, 0)
-		for _, id := range instanceRelationship.Target.([]interface{}) {
-			targetExternalIdsToFind = append(targetExternalIdsToFind, id.(string))
+		targetExternalIdsToFind := instanceRelationship.TargetExternalIds()
+		sourceSide, targetSide := cardinalitySides(directRelationship.Cardinality)
+		if targetSide == "one" && len(targetExternalIdsToFind) > 1 {
+			return nil, &CardinalityError{
+				Relationship: directRelationship.Name,
+				Cardinality:  directRelationship.Cardinality,
+				Reason:       "allows only one target",
+				ExternalIds:  targetExternalIdsToFind,
+			}
 		}
-		if strings.HasSuffix(directRelationship.Cardinality, "many") {
-			for _, targetExternalIdToFind := range targetExternalIdsToFind {
-				targetInstance, err := s.GetInstance(instance.TenantID, targetExternalIdToFind)
+		previousTargetExternalIds := relationshipTargets(previousRelationships, directRelationship.ID)
+
+		for _, targetExternalIdToFind := range targetExternalIdsToFind {
+			targetInstance, ok := targetInstances[targetExternalIdToFind]
+			if !ok {
+				targetInstance, err = s.getStoredInstance(instance.TenantID, targetExternalIdToFind)
 				if err != nil {
 					log.Printf("error fetching target instance %s\n: %s", targetExternalIdToFind, err)
-					return errors.New("error validating relationships")
-				}
-
-				if !slices.Contains(directRelationship.Target, targetInstance.BasicInformation.RootTemplate) {
-					log.Printf("relationship %s target not correct\n", instanceRelationship.ID)
-					return errors.New("error validating relationships")
-				}
-
-				if !inverseRelationship.ID.IsZero() {
-					newInverseRelationshipId, _ := uuid.NewUUID()
-					targetInstance.Relationships = append(targetInstance.Relationships, models.InstanceRelationship{
-						ID:                     newInverseRelationshipId.String(),
-						Target:                 instance.BasicInformation.ExternalId,
-						RelationshipTemplateId: inverseRelationship.ID,
-					})
-
-					filter := bson.D{{Key: "tenantId", Value: instance.TenantID}, {Key: "basicInformation.externalId", Value: targetInstance.BasicInformation.ExternalId}}
-					if err := s.db.ReplaceInstance(filter, targetInstance); err != nil {
-						log.Println("error updating instance: ", err)
-						return err
+					if errors.Is(err, db.ErrNotFound) {
+						return nil, relationshipValidationError(i, targetExternalIdToFind, models.ValidationCodeUnknownField, fmt.Sprintf("target instance %s does not exist", targetExternalIdToFind))
 					}
+					return nil, err
 				}
+				targetInstances[targetExternalIdToFind] = targetInstance
 			}
-		} else {
-			targetInstance, err := s.GetInstance(instance.TenantID, targetExternalIdsToFind[0])
-			if err != nil {
-				log.Printf("error fetching target instance %s\n: %s", targetExternalIdsToFind[0], err)
-				return errors.New("error validating relationships")
-			}
+
 			if !slices.Contains(directRelationship.Target, targetInstance.BasicInformation.RootTemplate) {
 				log.Printf("relationship %s target not correct\n", instanceRelationship.ID)
-				return errors.New("error validating relationships")
+				return nil, relationshipValidationError(i, targetExternalIdToFind, models.ValidationCodeTypeMismatch, fmt.Sprintf("relationship %s cannot target %s", directRelationship.Name, targetInstance.BasicInformation.RootTemplate))
 			}
 
-			if !inverseRelationship.ID.IsZero() {
-				newInverseRelationshipId, _ := uuid.NewUUID()
-				if targetInstance.Relationships == nil {
-					targetInstance.Relationships = append(targetInstance.Relationships, models.InstanceRelationship{
-						ID:                     newInverseRelationshipId.String(),
-						Target:                 []string{instance.BasicInformation.ExternalId},
-						RelationshipTemplateId: inverseRelationship.ID,
-					})
-				} else {
-					existingRelationshipIndex := slices.IndexFunc(targetInstance.Relationships, func(ir models.InstanceRelationship) bool {
-						return ir.RelationshipTemplateId == inverseRelationshipId
-					})
-					existingRelationship := targetInstance.Relationships[existingRelationshipIndex]
-					existingExternalIds := make([]string, 0)
-					for _, id := range existingRelationship.Target.(primitive.A) {
-						existingExternalIds = append(existingExternalIds, id.(string))
-					}
-					existingExternalIds = append(existingExternalIds, instance.BasicInformation.ExternalId)
-					targetInstance.Relationships[existingRelationshipIndex].Target = existingExternalIds
+			if sourceSide == "one" {
+				if err := s.validateSingleSource(instance, directRelationship, targetExternalIdToFind); err != nil {
+					return nil, err
 				}
+			}
 
-				filter := bson.D{{Key: "tenantId", Value: instance.TenantID}, {Key: "basicInformation.externalId", Value: targetInstance.BasicInformation.ExternalId}}
-				if err := s.db.ReplaceInstance(filter, targetInstance); err != nil {
-					log.Println("error updating instance: ", err)
-					return err
+			if inverseRelationship.ID.IsZero() {
+				continue
+			}
+
+			if _, inverseTargetSide := cardinalitySides(inverseRelationship.Cardinality); inverseTargetSide == "one" {
+				existingSources := slices.DeleteFunc(relationshipTargets(targetInstance.Relationships, inverseRelationship.ID), func(id string) bool {
+					return id == instance.BasicInformation.ExternalId
+				})
+				if len(existingSources) > 0 {
+					return nil, &CardinalityError{
+						Relationship: inverseRelationship.Name,
+						Cardinality:  inverseRelationship.Cardinality,
+						Reason:       fmt.Sprintf("already links %s to", targetExternalIdToFind),
+						ExternalIds:  existingSources,
+					}
 				}
 			}
+
+			if slices.Contains(previousTargetExternalIds, targetExternalIdToFind) {
+				continue
+			}
+			inverseRelationshipsToAdd = append(inverseRelationshipsToAdd, inverseRelationshipChange{
+				target:                targetInstance,
+				inverseRelationshipId: inverseRelationship.ID,
+			})
+		}
+	}
+
+	return inverseRelationshipsToAdd, nil
+}
+
+func relationshipValidationError(index int, id, code, message string) error {
+	validationErr := &models.ValidationError{}
+	validationErr.Add(fmt.Sprintf("relationships[%d]", index), id, code, message)
+	return validationErr
+}
+
+func (s *service) validateSingleSource(instance models.Instance, directRelationship models.Relationship, targetExternalId string) error {
+	filter := bson.D{
+		{Key: "tenantId", Value: instance.TenantID},
+		{Key: "basicInformation.externalId", Value: bson.D{{Key: "$ne", Value: instance.BasicInformation.ExternalId}}},
+		{Key: "relationships", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "relationshipTemplateId", Value: directRelationship.ID},
+			{Key: "target", Value: targetExternalId},
+		}}}},
+	}
+	linkedInstances, err := s.db.GetAllInstances(filter, nil)
+	if err != nil {
+		log.Println("error fetching linked instances: ", err)
+		return err
+	}
+
+	if len(linkedInstances) == 0 {
+		return nil
+	}
+
+	linkedExternalIds := make([]string, 0, len(linkedInstances))
+	for _, linkedInstance := range linkedInstances {
+		linkedExternalIds = append(linkedExternalIds, linkedInstance.BasicInformation.ExternalId)
+	}
+	return &CardinalityError{
+		Relationship: directRelationship.Name,
+		Cardinality:  directRelationship.Cardinality,
+		Reason:       fmt.Sprintf("already links %s from", targetExternalId),
+		ExternalIds:  linkedExternalIds,
+	}
+}
+
+func cardinalitySides(cardinality string) (string, string) {
+	sourceSide, targetSide, found := strings.Cut(strings.ToLower(cardinality), "-to-")
+	if !found {
+		return "many", "many"
+	}
+	return sourceSide, targetSide
+}
+
+func (s *service) addInverseRelationship(targetInstance *models.Instance, inverseRelationshipId primitive.ObjectID, sourceExternalId string) error {
+	if !addRelationshipTarget(targetInstance, inverseRelationshipId, sourceExternalId) {
+		return nil
+	}
+
+	filter := bson.D{{Key: "tenantId", Value: targetInstance.TenantID}, {Key: "basicInformation.externalId", Value: targetInstance.BasicInformation.ExternalId}}
+	if _, err := s.db.ReplaceInstance(filter, targetInstance); err != nil {
+		log.Println("error updating instance: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func addRelationshipTarget(instance *models.Instance, relationshipTemplateId primitive.ObjectID, targetExternalId string) bool {
+	existingRelationshipIndex := slices.IndexFunc(instance.Relationships, func(ir models.InstanceRelationship) bool {
+		return ir.RelationshipTemplateId == relationshipTemplateId
+	})
+	if existingRelationshipIndex == -1 {
+		newRelationshipId, _ := uuid.NewUUID()
+		instance.Relationships = append(instance.Relationships, models.InstanceRelationship{
+			ID:                     newRelationshipId.String(),
+			Target:                 []string{targetExternalId},
+			RelationshipTemplateId: relationshipTemplateId,
+		})
+		return true
+	}
+
+	existingExternalIds := instance.Relationships[existingRelationshipIndex].TargetExternalIds()
+	if slices.Contains(existingExternalIds, targetExternalId) {
+		return false
+	}
+	instance.Relationships[existingRelationshipIndex].Target = append(existingExternalIds, targetExternalId)
+	return true
+}
+
+func (s *service) removeInverseRelationship(tenantId, targetExternalId string, inverseRelationshipId primitive.ObjectID, sourceExternalId string) error {
+	targetInstance, err := s.getStoredInstance(tenantId, targetExternalId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
 		}
+		log.Printf("error fetching target instance %s\n: %s", targetExternalId, err)
+		return err
+	}
+
+	if !targetInstance.RemoveRelationshipTarget(inverseRelationshipId, sourceExternalId) {
+		return nil
+	}
+
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: targetInstance.BasicInformation.ExternalId}}
+	if _, err := s.db.ReplaceInstance(filter, targetInstance); err != nil {
+		log.Println("error updating instance: ", err)
+		return err
 	}
 
 	return nil
 }
+
+func relationshipTargets(relationships []models.InstanceRelationship, relationshipTemplateId primitive.ObjectID) []string {
+	targets := make([]string, 0)
+	for _, relationship := range relationships {
+		if relationship.RelationshipTemplateId == relationshipTemplateId {
+			targets = append(targets, relationship.TargetExternalIds()...)
+		}
+	}
+	return targets
+}
+
+func assignRelationshipIds(relationships []models.InstanceRelationship) {
+	for i, instanceRelationship := range relationships {
+		if instanceRelationship.ID == "" {
+			newRelationshipId, _ := uuid.NewUUID()
+			relationships[i].ID = newRelationshipId.String()
+		}
+	}
+}
@@ -1,12 +1,17 @@
 package instance
 
-// import (
-// 	"api/pkg/db"
-// 	"api/pkg/template"
-// 	"testing"
+import (
+	"api/pkg/common"
+	"api/pkg/db"
+	"api/pkg/models"
+	"api/pkg/template"
+	"slices"
+	"testing"
 
-// 	"github.com/stretchr/testify/assert"
-// )
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // func TestNewService(t *testing.T) {
 // 	mockRepository := &db.MockedDbRepository{}
@@ -20,6 +25,994 @@ package instance
 // 	assert.Equal(t, mockService, newService)
 // }
 
+func TestService_UpdateInstance_Success_ReplacesInstance(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			Parent:       "testtemplate1",
+			ExternalId:   "testinstance1",
+			Name:         "Test Instance 1",
+			IsCustom:     true,
+			RootTemplate: "p.com.asset",
+		},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID:   "testtemplate1",
+			RootTemplate: "p.com.asset",
+		},
+		Attributes: []models.TemplateAttribute{{
+			ID:       "412ba829-eca5-4513-97e7-f30c34f03a70",
+			Name:     "attribute1",
+			DataType: "integer",
+		}},
+	}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance models.Instance) bool {
+		return instance.BasicInformation.Parent == "testtemplate1" && instance.Attributes[0].Value == 42
+	})).Return(int64(2), nil)
+
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	actualVersion, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			Name:   "Test Instance 1 Renamed",
+			Parent: "someothertemplate",
+		},
+		Attributes: []models.InstanceAttribute{{
+			ID:    "412ba829-eca5-4513-97e7-f30c34f03a70",
+			Value: "42",
+		}},
+	}, 0)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, int64(2), actualVersion)
+
+	mockRepository.AssertExpectations(t)
+	mockTemplateService.AssertExpectations(t)
+}
+
+func TestService_UpdateInstance_FailsGettingInstance_ReturnsError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(nil, db.ErrNotFound)
+
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			Name: "Test Instance 1",
+		},
+	}, 0)
+	assert.Equal(t, db.ErrNotFound, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateInstance_StaleVersion_ReturnsPreconditionFailed(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		TenantID:         "the-binary",
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance1", Parent: "testtemplate1"},
+	}, nil)
+
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Test Instance 1"},
+	}, 2)
+	assert.ErrorIs(t, actualErr, db.ErrPreconditionFailed)
+
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateInstance_FailsValidatingAttributes_ReturnsError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			Parent:     "testtemplate1",
+			ExternalId: "testinstance1",
+		},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		Attributes: []models.TemplateAttribute{{
+			ID:       "412ba829-eca5-4513-97e7-f30c34f03a70",
+			Name:     "attribute1",
+			DataType: "integer",
+		}},
+	}, nil)
+
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			Name: "Test Instance 1",
+		},
+		Attributes: []models.InstanceAttribute{{
+			ID:    "412ba829-eca5-4513-97e7-f30c34f03a70",
+			Value: "not a number",
+		}},
+	}, 0)
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []models.FieldError{{
+		Field:   "attributes[0].value",
+		ID:      "412ba829-eca5-4513-97e7-f30c34f03a70",
+		Code:    models.ValidationCodeTypeMismatch,
+		Message: `invalid value: "not a number" is not an integer value`,
+	}}, validationErr.Errors)
+
+	mockRepository.AssertExpectations(t)
+	mockTemplateService.AssertExpectations(t)
+}
+
+func TestService_AddInstance_InvalidFields_ReturnsAllValidationErrors(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		Attributes: []models.TemplateAttribute{
+			{ID: "attribute1", Name: "attribute1", DataType: "integer"},
+			{ID: "attribute2", Name: "attribute2", DataType: "string", Constraints: &models.Constraints{Pattern: "^[a-z]+$"}},
+			{ID: "attribute3", Name: "attribute3", DataType: "string", IsRequired: true},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "metric1", Name: "metric1", MetricType: "float"},
+		},
+	}, nil)
+
+	actualErr := mockService.AddInstance("the-binary", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: "testinstance1",
+			Parent:     "testtemplate1",
+		},
+		Attributes: []models.InstanceAttribute{
+			{ID: "attribute1", Value: "abc"},
+			{ID: "attribute2", Value: "not-allowed!"},
+			{ID: "attribute4", Value: "1"},
+		},
+		Metrics: []models.InstanceMetric{
+			{ID: "metric1", MetricBehaviour: "Manual", Value: "1.5"},
+			{ID: "metric2", MetricBehaviour: "Manual", Value: "2"},
+		},
+	})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	codes := make([]string, 0)
+	fields := make([]string, 0)
+	for _, fieldErr := range validationErr.Errors {
+		codes = append(codes, fieldErr.Code)
+		fields = append(fields, fieldErr.Field)
+	}
+	assert.Equal(t, []string{
+		models.ValidationCodeRequired,
+		models.ValidationCodeRequired,
+		models.ValidationCodeTypeMismatch,
+		models.ValidationCodePattern,
+		models.ValidationCodeUnknownField,
+		models.ValidationCodeUnknownField,
+	}, codes)
+	assert.Equal(t, []string{
+		"basicInformation.name",
+		"attributes",
+		"attributes[0].value",
+		"attributes[1].value",
+		"attributes[2].id",
+		"metrics[1].id",
+	}, fields)
+
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+	mockTemplateService.AssertExpectations(t)
+}
+
+func TestService_AddInstance_MissingParent_ReturnsValidationErrorWithoutFetchingTemplate(t *testing.T) {
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              &db.MockedDbRepository{},
+		templateService: mockTemplateService,
+	}
+
+	actualErr := mockService.AddInstance("the-binary", models.Instance{})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Len(t, validationErr.Errors, 3)
+	mockTemplateService.AssertNotCalled(t, "GetTemplate", mock.Anything, mock.Anything)
+}
+
+func TestService_DeleteInstance_Success_StripsInverseRelationshipsAndDeletes(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	inverseRelationshipId := primitive.NewObjectID()
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: "floor1",
+		},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: "building1",
+			Parent:     "p.com.space",
+		},
+		Relationships: []models.InstanceRelationship{
+			{ID: "1", Target: primitive.A{"floor1", "floor2"}, RelationshipTemplateId: inverseRelationshipId},
+		},
+	}}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance *models.Instance) bool {
+		return assert.ObjectsAreEqual([]string{"floor2"}, instance.Relationships[0].Target)
+	})).Return(int64(2), nil)
+	mockRepository.On("DeleteInstance", mock.AnythingOfType("primitive.D")).Return(nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.space").Return(&models.Template{}, nil)
+
+	referencedBy, actualErr := mockService.DeleteInstance("the-binary", "floor1", false)
+	assert.Nil(t, actualErr)
+	assert.Nil(t, referencedBy)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DeleteInstance_RestrictedWhileReferenced_ReturnsReferencedError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: "floor1",
+		},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{{
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: "building1",
+		},
+		Relationships: []models.InstanceRelationship{
+			{ID: "1", Target: primitive.A{"floor1"}, RelationshipTemplateId: primitive.NewObjectID()},
+		},
+	}}, nil)
+
+	referencedBy, actualErr := mockService.DeleteInstance("the-binary", "floor1", true)
+	assert.Equal(t, ErrInstanceReferenced, actualErr)
+	assert.Equal(t, []string{"building1"}, referencedBy)
+
+	mockRepository.AssertNotCalled(t, "DeleteInstance", mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DeleteInstance_RestrictedWithOnlyInverseLinks_DeletesInstance(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsRelationshipId := primitive.NewObjectID()
+	containedInRelationshipId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsRelationshipId, Name: "contains", Inverse: containedInRelationshipId},
+		{ID: containedInRelationshipId, Name: "containedIn", Inverse: containsRelationshipId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: "building1",
+		},
+		Relationships: []models.InstanceRelationship{
+			{ID: "1", Target: primitive.A{"floor1"}, RelationshipTemplateId: containsRelationshipId},
+		},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: "floor1",
+			Parent:     "p.com.space",
+		},
+		Relationships: []models.InstanceRelationship{
+			{ID: "2", Target: primitive.A{"building1"}, RelationshipTemplateId: containedInRelationshipId},
+		},
+	}}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance *models.Instance) bool {
+		return len(instance.Relationships) == 0
+	})).Return(int64(2), nil)
+	mockRepository.On("DeleteInstance", mock.AnythingOfType("primitive.D")).Return(nil)
+
+	referencedBy, actualErr := mockService.DeleteInstance("the-binary", "building1", true)
+	assert.Nil(t, actualErr)
+	assert.Nil(t, referencedBy)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DeleteInstance_FailsGettingInstance_ReturnsError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(nil, db.ErrNotFound)
+
+	_, actualErr := mockService.DeleteInstance("the-binary", "floor1", false)
+	assert.Equal(t, db.ErrNotFound, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_GetInstanceGraph_Success_WalksRelationshipsBreadthFirst(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockCommonService := &common.MockService{}
+	mockService := &service{
+		db:            mockRepository,
+		commonService: mockCommonService,
+	}
+
+	containsId := primitive.NewObjectID()
+	containedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Inverse: containedInId},
+		{ID: containedInId, Name: "is contained in", Inverse: containsId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "site1", Name: "Site 1"},
+		Relationships: []models.InstanceRelationship{
+			{ID: "1", Target: primitive.A{"building1"}, RelationshipTemplateId: containsId},
+		},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "building1", Name: "Building 1"},
+			Relationships: []models.InstanceRelationship{
+				{ID: "2", Target: primitive.A{"site1"}, RelationshipTemplateId: containedInId},
+				{ID: "3", Target: primitive.A{"floor1"}, RelationshipTemplateId: containsId},
+			},
+		},
+	}, nil).Once()
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", Name: "Floor 1"},
+			Relationships: []models.InstanceRelationship{
+				{ID: "4", Target: primitive.A{"building1"}, RelationshipTemplateId: containedInId},
+			},
+		},
+	}, nil).Once()
+
+	actual, actualErr := mockService.GetInstanceGraph("the-binary", "site1", 2, []string{"contains"})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []models.InstanceGraphNode{
+		{ExternalId: "site1", Name: "Site 1", Depth: 0},
+		{ExternalId: "building1", Name: "Building 1", Depth: 1},
+		{ExternalId: "floor1", Name: "Floor 1", Depth: 2},
+	}, actual.Nodes)
+	assert.Equal(t, []models.InstanceGraphEdge{
+		{Source: "site1", Target: "building1", Relationship: "contains", RelationshipTemplateId: containsId},
+		{Source: "building1", Target: "floor1", Relationship: "contains", RelationshipTemplateId: containsId},
+	}, actual.Edges)
+
+	mockRepository.AssertExpectations(t)
+	mockCommonService.AssertExpectations(t)
+}
+
+func TestService_GetInstanceGraph_Cycle_VisitsEachInstanceOnce(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockCommonService := &common.MockService{}
+	mockService := &service{
+		db:            mockRepository,
+		commonService: mockCommonService,
+	}
+
+	feedsId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: feedsId, Name: "feeds"},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "pump1"},
+		Relationships: []models.InstanceRelationship{
+			{ID: "1", Target: primitive.A{"pump2"}, RelationshipTemplateId: feedsId},
+		},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "pump2"},
+			Relationships: []models.InstanceRelationship{
+				{ID: "2", Target: primitive.A{"pump1"}, RelationshipTemplateId: feedsId},
+			},
+		},
+	}, nil).Once()
+
+	actual, actualErr := mockService.GetInstanceGraph("the-binary", "pump1", 10, nil)
+	assert.Nil(t, actualErr)
+	assert.Len(t, actual.Nodes, 2)
+	assert.Len(t, actual.Edges, 2)
+
+	mockRepository.AssertExpectations(t)
+	mockCommonService.AssertExpectations(t)
+}
+
+func TestService_GetCreateInstanceForm_RicherDataTypes_ReturnsWidgetMetadata(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		Attributes: []models.TemplateAttribute{
+			{ID: "attribute1", Name: "Status", DataType: "enum", EnumValues: []string{"active", "retired"}},
+			{ID: "attribute2", Name: "Location", DataType: "geopoint"},
+			{ID: "attribute3", Name: "Floors", DataType: "integer"},
+		},
+	}, nil)
+	mockRepository.On("GetTypeDropdownValues", "attribute_types").Return([]models.Dropdown{
+		{Label: "Whole Number", Value: "integer"},
+	}, nil)
+
+	actual, actualErr := mockService.GetCreateInstanceForm("the-binary", "testtemplate1")
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []models.InstanceMetaDataFields{
+		{ID: "attribute1", Label: "Status", Type: "enum", TypeLabel: "Enum", Options: []string{"active", "retired"}},
+		{ID: "attribute2", Label: "Location", Type: "geopoint", TypeLabel: "Geo Point"},
+		{ID: "attribute3", Label: "Floors", Type: "integer", TypeLabel: "Whole Number"},
+	}, actual.Attributes.Fields)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateInstance_JsonObjectValue_StoresParsedValue(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Parent: "testtemplate1", ExternalId: "testinstance1"},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		Attributes: []models.TemplateAttribute{{ID: "attribute1", Name: "Specs", DataType: "json"}},
+	}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance models.Instance) bool {
+		return assert.ObjectsAreEqual(map[string]interface{}{"floors": float64(3)}, instance.Attributes[0].Value)
+	})).Return(int64(2), nil)
+
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Test Instance 1"},
+		Attributes:       []models.InstanceAttribute{{ID: "attribute1", Value: map[string]interface{}{"floors": 3}}},
+	}, 0)
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateInstance_ConstraintViolations_ReturnsValidationErrors(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	minFloors, maxFloors := 1.0, 100.0
+	maxLength := 5
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Parent: "testtemplate1", ExternalId: "testinstance1"},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		Attributes: []models.TemplateAttribute{
+			{ID: "floors", Name: "Floors", DataType: "integer", Constraints: &models.Constraints{Min: &minFloors, Max: &maxFloors}},
+			{ID: "code", Name: "Code", DataType: "string", Constraints: &models.Constraints{MaxLength: &maxLength}},
+			{ID: "zone", Name: "Zone", DataType: "string", Constraints: &models.Constraints{AllowedValues: []string{"north", "south"}}},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "area", Name: "Area", MetricType: "float", Constraints: &models.Constraints{Min: &minFloors}},
+		},
+	}, nil)
+
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Test Instance 1"},
+		Attributes: []models.InstanceAttribute{
+			{ID: "floors", Value: "120"},
+			{ID: "code", Value: "ABCDEFG"},
+			{ID: "zone", Value: "east"},
+		},
+		Metrics: []models.InstanceMetric{
+			{ID: "area", MetricBehaviour: "Manual", Value: "0.5"},
+		},
+	}, 0)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	codes := make([]string, 0)
+	for _, fieldErr := range validationErr.Errors {
+		codes = append(codes, fieldErr.Code)
+	}
+	assert.Equal(t, []string{
+		models.ValidationCodeOutOfRange,
+		models.ValidationCodeLength,
+		models.ValidationCodeNotAllowed,
+		models.ValidationCodeOutOfRange,
+	}, codes)
+
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+}
+
+func TestService_UpdateInstance_MissingAttributeWithDefault_StoresDefault(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Parent: "testtemplate1", ExternalId: "testinstance1"},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		Attributes: []models.TemplateAttribute{
+			{ID: "floors", Name: "Floors", DataType: "integer", IsRequired: true, Constraints: &models.Constraints{Default: "3"}},
+		},
+	}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance models.Instance) bool {
+		return assert.ObjectsAreEqual([]models.InstanceAttribute{{ID: "floors", Value: 3}}, instance.Attributes)
+	})).Return(int64(2), nil)
+
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Test Instance 1"},
+	}, 0)
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_UniqueValueAlreadyUsed_ReturnsNotUniqueError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+	}
+
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		Attributes: []models.TemplateAttribute{
+			{ID: "serial", Name: "Serial", DataType: "string", Constraints: &models.Constraints{Unique: true}},
+		},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.MatchedBy(func(filter primitive.D) bool {
+		return filter[1].Key == "basicInformation.externalId" && filter[2].Key == "attributes"
+	}), mock.Anything).Return([]models.Instance{
+		{BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance2"}},
+	}, nil)
+
+	actualErr := mockService.AddInstance("the-binary", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Test Instance 1", ExternalId: "testinstance1", Parent: "testtemplate1"},
+		Attributes:       []models.InstanceAttribute{{ID: "serial", Value: "SN1"}},
+	})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, "attributes[0].value", validationErr.Errors[0].Field)
+	assert.Equal(t, models.ValidationCodeNotUnique, validationErr.Errors[0].Code)
+
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func newTestService() (*service, *db.MockedDbRepository, *template.MockService, *common.MockService) {
+	mockRepository := &db.MockedDbRepository{}
+	mockTemplateService := &template.MockService{}
+	mockCommonService := &common.MockService{}
+	mockService := &service{
+		db:              mockRepository,
+		templateService: mockTemplateService,
+		commonService:   mockCommonService,
+	}
+
+	return mockService, mockRepository, mockTemplateService, mockCommonService
+}
+
+func mockUnitDropdown(mockCommonService *common.MockService) {
+	mockCommonService.On("GetUnitDropdown").Return([]models.Dropdown{
+		{Label: "Celsius", Value: "celsius", Symbol: "°C", Dimension: "temperature", Factor: 1, Offset: 273.15},
+		{Label: "Fahrenheit", Value: "fahrenheit", Symbol: "°F", Dimension: "temperature", Factor: 5.0 / 9, Offset: 459.67 * 5 / 9},
+		{Label: "Kilowatt hour", Value: "kwh", Symbol: "kWh", Dimension: "energy", Factor: 3.6e6},
+		{Label: "Megawatt hour", Value: "mwh", Symbol: "MWh", Dimension: "energy", Factor: 3.6e9},
+	}, nil)
+}
+
+func filterKey(index int, key string) interface{} {
+	return mock.MatchedBy(func(filter primitive.D) bool {
+		return len(filter) > index && filter[index].Key == key
+	})
+}
+
+func newRelationshipTestService() (*service, *db.MockedDbRepository, *template.MockService, *common.MockService) {
+	mockService, mockRepository, mockTemplateService, mockCommonService := newTestService()
+
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.space").Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "p.com.space",
+		},
+	}, nil)
+
+	return mockService, mockRepository, mockTemplateService, mockCommonService
+}
+
+func newSpaceInstance(relationships ...models.InstanceRelationship) models.Instance {
+	return models.Instance{
+		BasicInformation: models.InstanceBasicInformation{
+			Name:       "Building 1",
+			ExternalId: "building1",
+			Parent:     "p.com.space",
+		},
+		Relationships: relationships,
+	}
+}
+
+func newUnitTestService() (*service, *db.MockedDbRepository) {
+	mockService, mockRepository, mockTemplateService, mockCommonService := newTestService()
+
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Parent: "testtemplate1", ExternalId: "testinstance1"},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{
+		Metrics: []models.TemplateMetric{{ID: "consumption", Name: "Consumption", MetricType: "integer", Unit: "kwh", IsManual: true}},
+	}, nil)
+	mockUnitDropdown(mockCommonService)
+
+	return mockService, mockRepository
+}
+
+func newSeriesTestService() (*service, *db.MockedDbRepository, *template.MockService, *common.MockService) {
+	mockService, mockRepository, mockTemplateService, mockCommonService := newTestService()
+
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.sensor").Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.sensor"},
+		Metrics: []models.TemplateMetric{
+			{ID: "temperature", Name: "temperature", MetricType: "float", Unit: "celsius", IsSourced: true},
+			{ID: "occupancy", Name: "occupancy", MetricType: "integer", IsSourced: true, IsManual: true},
+			{ID: "energy", Name: "energy", MetricType: "integer", Unit: "kwh", IsSourced: true},
+		},
+	}, nil)
+	mockUnitDropdown(mockCommonService)
+
+	return mockService, mockRepository, mockTemplateService, mockCommonService
+}
+
+func newSensorInstance(externalId string) *models.Instance {
+	return &models.Instance{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId: externalId,
+			Parent:     "p.com.sensor",
+		},
+		Metrics: []models.InstanceMetric{
+			{ID: "temperature", MetricBehaviour: "Sourced"},
+			{ID: "occupancy", MetricBehaviour: "Manual", Value: 3},
+			{ID: "energy", MetricBehaviour: "Sourced"},
+		},
+	}
+}
+
+func newHistoryTestService() (*service, *db.MockedDbRepository) {
+	mockService, mockRepository, mockTemplateService, _ := newTestService()
+
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.pump").Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.pump", RootTemplate: "p.com.asset"},
+		Attributes:       []models.TemplateAttribute{{ID: "flow", Name: "flow", DataType: "integer"}},
+	}, nil)
+
+	return mockService, mockRepository
+}
+
+func newPumpInstance(flow interface{}, version int64) models.Instance {
+	return models.Instance{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			Name:         "Pump 1",
+			ExternalId:   "pump1",
+			Parent:       "p.com.pump",
+			RootTemplate: "p.com.asset",
+		},
+		Attributes: []models.InstanceAttribute{{ID: "flow", Value: flow}},
+		Version:    version,
+	}
+}
+
+func newCalculationTestService(containsId primitive.ObjectID) (*service, *db.MockedDbRepository) {
+	mockService, mockRepository, mockTemplateService, mockCommonService := newTestService()
+
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.building").Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.building"},
+		Metrics: []models.TemplateMetric{
+			{ID: "floor-count", Name: "floors", MetricType: "integer", IsCalculated: true, Formula: `count("contains")`},
+			{ID: "total-area", Name: "total area", MetricType: "float", IsCalculated: true, Formula: `sum("contains", "area")`},
+			{ID: "average-area", Name: "average area", MetricType: "float", IsCalculated: true, Formula: "[total area] / floors"},
+		},
+	}, nil)
+	mockTemplateService.On("GetTemplate", "the-binary", "p.com.floor").Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.floor"},
+		Metrics: []models.TemplateMetric{
+			{ID: "area", Name: "area", MetricType: "float", IsManual: true},
+		},
+	}, nil)
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.building", Target: []string{"p.com.floor"}, Cardinality: "many-to-many"},
+	}, nil)
+
+	return mockService, mockRepository
+}
+
+func newFloorInstance(externalId string, area float64) models.Instance {
+	return models.Instance{
+		TenantID: "the-binary",
+		BasicInformation: models.InstanceBasicInformation{
+			ExternalId:   externalId,
+			Parent:       "p.com.floor",
+			RootTemplate: "p.com.floor",
+		},
+		Metrics: []models.InstanceMetric{{ID: "area", MetricBehaviour: "Manual", Value: area}},
+	}
+}
+
+func TestService_AddInstance_ToOneRelationshipWithManyTargets_ReturnsCardinalityError(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	locatedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: locatedInId, Name: "is located in", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-one"},
+	}, nil)
+
+	actualErr := mockService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"site1", "site2"},
+		RelationshipTemplateId: locatedInId,
+	}))
+
+	var cardinalityErr *CardinalityError
+	assert.ErrorAs(t, actualErr, &cardinalityErr)
+	assert.Equal(t, "is located in", cardinalityErr.Relationship)
+	assert.Equal(t, []string{"site1", "site2"}, cardinalityErr.ExternalIds)
+
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_OneToManyTargetAlreadyLinked_ReturnsCardinalityError(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "one-to-many"},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{BasicInformation: models.InstanceBasicInformation{ExternalId: "building2"}},
+	}, nil)
+
+	actualErr := mockService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"floor1"},
+		RelationshipTemplateId: containsId,
+	}))
+
+	var cardinalityErr *CardinalityError
+	assert.ErrorAs(t, actualErr, &cardinalityErr)
+	assert.Equal(t, "contains", cardinalityErr.Relationship)
+	assert.Equal(t, []string{"building2"}, cardinalityErr.ExternalIds)
+
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_InverseOneSideAlreadyLinked_ReturnsCardinalityError(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsId := primitive.NewObjectID()
+	containedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-many", Inverse: containedInId},
+		{ID: containedInId, Name: "is contained in", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-one", Inverse: containsId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+		Relationships: []models.InstanceRelationship{
+			{ID: "1", Target: primitive.A{"building2"}, RelationshipTemplateId: containedInId},
+		},
+	}, nil)
+
+	actualErr := mockService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"floor1"},
+		RelationshipTemplateId: containsId,
+	}))
+
+	var cardinalityErr *CardinalityError
+	assert.ErrorAs(t, actualErr, &cardinalityErr)
+	assert.Equal(t, "is contained in", cardinalityErr.Relationship)
+	assert.Equal(t, []string{"building2"}, cardinalityErr.ExternalIds)
+
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_OneToMany_AddsInverseRelationshipAndCreates(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsId := primitive.NewObjectID()
+	containedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "one-to-many", Inverse: containedInId},
+		{ID: containedInId, Name: "is contained in", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-one", Inverse: containsId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance *models.Instance) bool {
+		return instance.BasicInformation.ExternalId == "floor1" &&
+			assert.ObjectsAreEqual([]string{"building1"}, instance.Relationships[0].Target) &&
+			instance.Relationships[0].RelationshipTemplateId == containedInId
+	})).Return(int64(2), nil)
+	mockRepository.On("AddOne", "instances", mock.AnythingOfType("models.Instance")).Return(nil)
+
+	actualErr := mockService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"floor1"},
+		RelationshipTemplateId: containsId,
+	}))
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_MixedCaseTarget_LooksUpAndStoresLowercaseTarget(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsId := primitive.NewObjectID()
+	containedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "one-to-many", Inverse: containedInId},
+		{ID: containedInId, Name: "is contained in", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-one", Inverse: containsId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.MatchedBy(func(filter primitive.D) bool {
+		return slices.Contains(filter, primitive.E{Key: "basicInformation.externalId", Value: "floor1"})
+	})).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*models.Instance")).Return(int64(2), nil)
+	mockRepository.On("AddOne", "instances", mock.MatchedBy(func(instance models.Instance) bool {
+		return assert.ObjectsAreEqual([]string{"floor1"}, instance.Relationships[0].Target)
+	})).Return(nil)
+
+	actualErr := mockService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"Floor1"},
+		RelationshipTemplateId: containsId,
+	}))
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_TargetWithSourcedMetric_KeepsStoredValueOnInverseWrite(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsId := primitive.NewObjectID()
+	containedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "one-to-many", Inverse: containedInId},
+		{ID: containedInId, Name: "is contained in", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-one", Inverse: containsId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+		Metrics:          []models.InstanceMetric{{ID: "temperature", MetricBehaviour: "Sourced", Value: float64(18)}},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance *models.Instance) bool {
+		return instance.BasicInformation.ExternalId == "floor1" &&
+			assert.ObjectsAreEqual([]models.InstanceMetric{{ID: "temperature", MetricBehaviour: "Sourced", Value: float64(18)}}, instance.Metrics)
+	})).Return(int64(2), nil)
+	mockRepository.On("AddOne", "instances", mock.AnythingOfType("models.Instance")).Return(nil)
+
+	actualErr := mockService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"floor1"},
+		RelationshipTemplateId: containsId,
+	}))
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertNotCalled(t, "GetLatestMetricPoints", mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_WithAuditContext_RecordsInverseRelationshipAndCreate(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsId := primitive.NewObjectID()
+	containedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "one-to-many", Inverse: containedInId},
+		{ID: containedInId, Name: "is contained in", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-one", Inverse: containsId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		TenantID:         "the-binary",
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+	}, nil).Once()
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		TenantID:         "the-binary",
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*models.Instance")).Return(int64(2), nil)
+	mockRepository.On("AddOne", "instances", mock.AnythingOfType("models.Instance")).Return(nil)
+	recorded := make([]models.AuditEvent, 0)
+	mockRepository.On("AddAuditEvents", mock.AnythingOfType("[]models.AuditEvent")).Run(func(args mock.Arguments) {
+		recorded = append(recorded, args.Get(0).([]models.AuditEvent)...)
+	}).Return(nil)
+
+	auditedService := mockService.WithAuditContext(models.AuditContext{Actor: "alice", RequestID: "request-1"})
+	actualErr := auditedService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"floor1"},
+		RelationshipTemplateId: containsId,
+	}))
+	assert.Nil(t, actualErr)
+
+	assert.Len(t, recorded, 2)
+	assert.Equal(t, "floor1", recorded[0].EntityExternalId)
+	assert.Equal(t, models.AuditOperationUpdate, recorded[0].Operation)
+	assert.Equal(t, "relationships[0].relationshipTemplateId", recorded[0].Changes[1].Path)
+	assert.Equal(t, "building1", recorded[1].EntityExternalId)
+	assert.Equal(t, models.AuditOperationCreate, recorded[1].Operation)
+	for _, event := range recorded {
+		assert.Equal(t, "the-binary", event.TenantID)
+		assert.Equal(t, "alice", event.Actor)
+		assert.Equal(t, "request-1", event.RequestID)
+		assert.Equal(t, models.AuditEntityInstance, event.EntityKind)
+	}
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddInstance_DuplicateExternalIdAfterInverseWrite_ReturnsError(t *testing.T) {
+	mockService, mockRepository, _, mockCommonService := newRelationshipTestService()
+
+	containsId := primitive.NewObjectID()
+	containedInId := primitive.NewObjectID()
+	mockCommonService.On("GetRelationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-many", Inverse: containedInId},
+		{ID: containedInId, Name: "is contained in", Source: "p.com.space", Target: []string{"p.com.space"}, Cardinality: "many-to-many", Inverse: containsId},
+	}, nil)
+	mockRepository.On("GetInstance", mock.AnythingOfType("primitive.D")).Return(&models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "floor1", RootTemplate: "p.com.space"},
+	}, nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*models.Instance")).Return(int64(2), nil)
+	mockRepository.On("AddOne", "instances", mock.AnythingOfType("models.Instance")).Return(db.ErrDuplicateExternalId)
+
+	actualErr := mockService.AddInstance("the-binary", newSpaceInstance(models.InstanceRelationship{
+		Target:                 []interface{}{"floor1"},
+		RelationshipTemplateId: containsId,
+	}))
+	assert.ErrorIs(t, actualErr, db.ErrDuplicateExternalId)
+
+	mockRepository.AssertExpectations(t)
+}
+
 // func TestService_AddInstance_Success_CreatesInstance(t *testing.T) {
 // 	//mockRepository := &db.MockedDbRepository{}
 // 	//mockService := &service{
@@ -0,0 +1,92 @@
+package instance
+
+import (
+	"api/pkg/common"
+	"api/pkg/models"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+)
+
+type unitConverter struct {
+	commonService common.Service
+	units         models.UnitCatalogue
+	loaded        bool
+}
+
+func (s *service) newUnitConverter() *unitConverter {
+	return &unitConverter{commonService: s.commonService}
+}
+
+func (c *unitConverter) conversion(from string, to string) (models.UnitConversion, error) {
+	if from == "" || from == to {
+		return models.UnitConversion{Unit: from, Factor: 1}, nil
+	}
+
+	if !c.loaded {
+		units, err := c.commonService.GetUnitDropdown()
+		if err != nil {
+			log.Println("error fetching units: ", err)
+			return models.UnitConversion{}, err
+		}
+		c.units = units
+		c.loaded = true
+	}
+
+	return c.units.Conversion(from, to)
+}
+
+func (c *unitConverter) convertValue(value interface{}, from string, to string) (interface{}, error) {
+	conversion, err := c.conversion(from, to)
+	if err != nil || conversion.IsIdentity() {
+		return value, err
+	}
+
+	number, err := strconv.ParseFloat(models.ValueString(value), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q must be numeric to convert from %s to %s", models.ErrInvalidValue, models.ValueString(value), from, to)
+	}
+	return strconv.FormatFloat(conversion.Apply(number), 'f', -1, 64), nil
+}
+
+func isUnitError(err error) bool {
+	return errors.Is(err, models.ErrUnknownUnit) || errors.Is(err, models.ErrIncompatibleUnit)
+}
+
+func (s *service) normaliseMetricUnits(instanceMetrics []models.InstanceMetric, templateMetrics []models.TemplateMetric) error {
+	validationErr := &models.ValidationError{}
+	converter := s.newUnitConverter()
+	for i, metric := range instanceMetrics {
+		if metric.Unit == "" || metric.MetricBehaviour != "Manual" {
+			continue
+		}
+		templateMetricIndex := slices.IndexFunc(templateMetrics, func(tm models.TemplateMetric) bool {
+			return tm.ID == metric.ID
+		})
+		if templateMetricIndex == -1 {
+			continue
+		}
+		if models.ValueString(metric.Value) == "" {
+			instanceMetrics[i].Unit = ""
+			continue
+		}
+
+		value, err := converter.convertValue(metric.Value, metric.Unit, templateMetrics[templateMetricIndex].Unit)
+		if isUnitError(err) {
+			validationErr.Add(fmt.Sprintf("metrics[%d].unit", i), metric.ID, models.ValidationCodeUnitMismatch, err.Error())
+			continue
+		}
+		if errors.Is(err, models.ErrInvalidValue) {
+			validationErr.Add(fmt.Sprintf("metrics[%d].value", i), metric.ID, models.ValidationCodeTypeMismatch, err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		instanceMetrics[i].Value = value
+		instanceMetrics[i].Unit = ""
+	}
+	return validationErr.ErrOrNil()
+}
@@ -0,0 +1,46 @@
+package instance
+
+import (
+	"api/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_UpdateInstance_ManualMetricInCompatibleUnit_StoresTemplateUnitValue(t *testing.T) {
+	mockService, mockRepository := newUnitTestService()
+
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance models.Instance) bool {
+		return assert.ObjectsAreEqual([]models.InstanceMetric{{ID: "consumption", MetricBehaviour: "Manual", Value: 2500}}, instance.Metrics)
+	})).Return(int64(2), nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Test Instance 1"},
+		Metrics:          []models.InstanceMetric{{ID: "consumption", MetricBehaviour: "Manual", Value: "2.5", Unit: "MWh"}},
+	}, 0)
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateInstance_ManualMetricInIncompatibleUnit_ReturnsValidationError(t *testing.T) {
+	mockService, mockRepository := newUnitTestService()
+
+	_, actualErr := mockService.UpdateInstance("the-binary", "testinstance1", models.Instance{
+		BasicInformation: models.InstanceBasicInformation{Name: "Test Instance 1"},
+		Metrics:          []models.InstanceMetric{{ID: "consumption", MetricBehaviour: "Manual", Value: "21", Unit: "°C"}},
+	}, 0)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []models.FieldError{{
+		Field:   "metrics[0].unit",
+		ID:      "consumption",
+		Code:    models.ValidationCodeUnitMismatch,
+		Message: `incompatible unit: cannot convert "°C" to "kwh"`,
+	}}, validationErr.Errors)
+
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+}
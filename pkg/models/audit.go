@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AuditEntityTemplate = "template"
+	AuditEntityInstance = "instance"
+
+	AuditOperationCreate = "create"
+	AuditOperationUpdate = "update"
+	AuditOperationDelete = "delete"
+)
+
+type AuditContext struct {
+	Actor     string
+	RequestID string
+}
+
+type AuditEvent struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID         string             `bson:"tenantId" json:"-"`
+	Actor            string             `bson:"actor" json:"actor"`
+	RequestID        string             `bson:"requestId" json:"requestId"`
+	EntityKind       string             `bson:"entityKind" json:"entityKind"`
+	EntityExternalId string             `bson:"entityExternalId" json:"entityExternalId"`
+	Operation        string             `bson:"operation" json:"operation"`
+	Changes          []AuditChange      `bson:"changes" json:"changes"`
+	Timestamp        time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+type AuditChange struct {
+	Path   string      `bson:"path" json:"path"`
+	Before interface{} `bson:"before" json:"before"`
+	After  interface{} `bson:"after" json:"after"`
+}
+
+type AuditQuery struct {
+	Entity string
+	Kind   string
+	From   time.Time
+	To     time.Time
+	Limit  int64
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	ErrOutOfRange        = errors.New("value is out of range")
+	ErrInvalidLength     = errors.New("value has an invalid length")
+	ErrValueNotAllowed   = errors.New("value is not allowed")
+	ErrInvalidConstraint = errors.New("invalid constraint")
+)
+
+// compiledPatterns caches every pattern compiled while templates are saved or values validated.
+var compiledPatterns sync.Map
+
+type Constraints struct {
+	Min           *float64    `bson:"min,omitempty" json:"min,omitempty"`
+	Max           *float64    `bson:"max,omitempty" json:"max,omitempty"`
+	MinLength     *int        `bson:"minLength,omitempty" json:"minLength,omitempty"`
+	MaxLength     *int        `bson:"maxLength,omitempty" json:"maxLength,omitempty"`
+	Pattern       string      `bson:"pattern,omitempty" json:"pattern,omitempty"`
+	AllowedValues []string    `bson:"allowedValues,omitempty" json:"allowedValues,omitempty"`
+	Default       interface{} `bson:"default,omitempty" json:"default,omitempty"`
+	Unique        bool        `bson:"unique,omitempty" json:"unique,omitempty"`
+}
+
+func (c *Constraints) HasDefault() bool {
+	return c != nil && c.Default != nil && c.Default != ""
+}
+
+func (c *Constraints) IsUnique() bool {
+	return c != nil && c.Unique
+}
+
+func (c *Constraints) Equal(other *Constraints) bool {
+	return reflect.DeepEqual(c, other)
+}
+
+func (c *Constraints) Check() error {
+	if c == nil {
+		return nil
+	}
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		return fmt.Errorf("%w: min %v is greater than max %v", ErrInvalidConstraint, *c.Min, *c.Max)
+	}
+	if (c.MinLength != nil && *c.MinLength < 0) || (c.MaxLength != nil && *c.MaxLength < 0) {
+		return fmt.Errorf("%w: lengths cannot be negative", ErrInvalidConstraint)
+	}
+	if c.MinLength != nil && c.MaxLength != nil && *c.MinLength > *c.MaxLength {
+		return fmt.Errorf("%w: minLength %d is greater than maxLength %d", ErrInvalidConstraint, *c.MinLength, *c.MaxLength)
+	}
+	if c.Pattern != "" {
+		if _, err := compilePattern(c.Pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := compiledPatterns.Load(pattern); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: pattern %q does not compile", ErrInvalidConstraint, pattern)
+	}
+	compiledPatterns.Store(pattern, compiled)
+	return compiled, nil
+}
+
+func (c *Constraints) Validate(value interface{}) error {
+	if c == nil || value == nil {
+		return nil
+	}
+
+	if number, ok := NumericValue(value); ok {
+		if c.Min != nil && number < *c.Min {
+			return fmt.Errorf("%w: %v is less than %v", ErrOutOfRange, value, *c.Min)
+		}
+		if c.Max != nil && number > *c.Max {
+			return fmt.Errorf("%w: %v is greater than %v", ErrOutOfRange, value, *c.Max)
+		}
+	}
+
+	if text, ok := value.(string); ok {
+		length := utf8.RuneCountInString(text)
+		if c.MinLength != nil && length < *c.MinLength {
+			return fmt.Errorf("%w: %q is shorter than %d characters", ErrInvalidLength, text, *c.MinLength)
+		}
+		if c.MaxLength != nil && length > *c.MaxLength {
+			return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidLength, text, *c.MaxLength)
+		}
+		if c.Pattern != "" {
+			pattern, err := compilePattern(c.Pattern)
+			if err != nil {
+				return err
+			}
+			if !pattern.MatchString(text) {
+				return fmt.Errorf("%w: %q does not match %s", ErrPatternMismatch, text, c.Pattern)
+			}
+		}
+	}
+
+	if len(c.AllowedValues) > 0 && !slices.Contains(c.AllowedValues, ValueString(value)) {
+		return fmt.Errorf("%w: %q is not one of %s", ErrValueNotAllowed, ValueString(value), strings.Join(c.AllowedValues, ", "))
+	}
+
+	return nil
+}
+
+func NumericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
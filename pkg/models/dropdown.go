@@ -1,9 +1,12 @@
 package models
 
 type Dropdown struct {
-	Label  string `json:"label"`
-	Value  string `json:"value"`
-	Symbol string `json:"symbol"`
+	Label     string  `json:"label"`
+	Value     string  `json:"value"`
+	Symbol    string  `json:"symbol"`
+	Dimension string  `json:"dimension,omitempty"`
+	Factor    float64 `json:"factor,omitempty"`
+	Offset    float64 `json:"offset,omitempty"`
 }
 
 type ParentTemplateDropdown struct {
@@ -0,0 +1,12 @@
+package models
+
+const (
+	ExportFormatCSV    = ImportFormatCSV
+	ExportFormatNDJSON = ImportFormatNDJSON
+)
+
+type ExportOptions struct {
+	Format       string
+	Template     string
+	RootTemplate string
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const InitialVersion int64 = 1
+
+type TemplateRevision struct {
+	Template   `bson:",inline"`
+	ArchivedAt time.Time  `bson:"archivedAt" json:"archivedAt"`
+	DeletedAt  *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+type InstanceRevision struct {
+	Instance   `bson:",inline"`
+	ArchivedAt time.Time  `bson:"archivedAt" json:"archivedAt"`
+	DeletedAt  *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+type VersionSummary struct {
+	Version    int64      `json:"version"`
+	Current    bool       `json:"current"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+}
+
+type VersionDiff struct {
+	From    int64         `json:"from"`
+	To      int64         `json:"to"`
+	Changes []AuditChange `json:"changes"`
+}
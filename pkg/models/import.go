@@ -0,0 +1,32 @@
+package models
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+
+	ImportStatusCreated = "created"
+	ImportStatusValid   = "valid"
+	ImportStatusFailed  = "failed"
+)
+
+type ImportOptions struct {
+	Format string
+	Parent string
+	DryRun bool
+}
+
+type ImportReport struct {
+	DryRun    bool              `json:"dryRun"`
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+type ImportRowResult struct {
+	Row        int          `json:"row"`
+	ExternalId string       `json:"externalId"`
+	Status     string       `json:"status"`
+	Message    string       `json:"message,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+}
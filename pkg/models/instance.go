@@ -1,6 +1,12 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Instance struct {
 	BasicInformation InstanceBasicInformation `bson:"basicInformation" json:"basicInformation"`
@@ -8,6 +14,7 @@ type Instance struct {
 	Metrics          []InstanceMetric         `bson:"metrics" json:"metrics"`
 	Relationships    []InstanceRelationship   `bson:"relationships" json:"relationships"`
 	TenantID         string                   `bson:"tenantId" json:"tenantId"`
+	Version          int64                    `bson:"version" json:"version,omitempty"`
 }
 
 type InstanceBasicInformation struct {
@@ -27,6 +34,8 @@ type InstanceMetric struct {
 	ID              string      `bson:"id" json:"id"`
 	MetricBehaviour string      `bson:"metricBehaviour" json:"metricBehaviour"`
 	Value           interface{} `bson:"value" json:"value"`
+	Unit            string      `bson:"-" json:"unit,omitempty"`
+	Timestamp       *time.Time  `bson:"-" json:"timestamp,omitempty"`
 }
 
 type InstanceRelationship struct {
@@ -35,6 +44,91 @@ type InstanceRelationship struct {
 	RelationshipTemplateId primitive.ObjectID `bson:"relationshipTemplateId" json:"relationshipTemplateId"`
 }
 
+func (r InstanceRelationship) TargetExternalIds() []string {
+	externalIds := make([]string, 0)
+	switch t := r.Target.(type) {
+	case string:
+		externalIds = append(externalIds, t)
+	case []string:
+		externalIds = append(externalIds, t...)
+	case []interface{}:
+		for _, id := range t {
+			if externalId, ok := id.(string); ok {
+				externalIds = append(externalIds, externalId)
+			}
+		}
+	case primitive.A:
+		for _, id := range t {
+			if externalId, ok := id.(string); ok {
+				externalIds = append(externalIds, externalId)
+			}
+		}
+	}
+	return externalIds
+}
+
+// LowercaseRelationshipTargets lowercases every relationship target, matching how
+// instance external ids are stored.
+func (i *Instance) LowercaseRelationshipTargets() {
+	for j, relationship := range i.Relationships {
+		if target, ok := relationship.Target.(string); ok {
+			i.Relationships[j].Target = strings.ToLower(target)
+			continue
+		}
+		if relationship.Target == nil {
+			continue
+		}
+		targets := relationship.TargetExternalIds()
+		for k, target := range targets {
+			targets[k] = strings.ToLower(target)
+		}
+		i.Relationships[j].Target = targets
+	}
+}
+
+func (i *Instance) RemoveRelationshipTarget(relationshipTemplateId primitive.ObjectID, externalId string) bool {
+	return i.removeRelationshipTargets(func(relationship InstanceRelationship) bool {
+		return relationship.RelationshipTemplateId == relationshipTemplateId
+	}, []string{externalId})
+}
+
+func (i *Instance) RemoveRelationshipTargets(externalIds []string) bool {
+	return i.removeRelationshipTargets(func(relationship InstanceRelationship) bool {
+		return true
+	}, externalIds)
+}
+
+func (i *Instance) removeRelationshipTargets(matches func(relationship InstanceRelationship) bool, externalIds []string) bool {
+	removed := false
+	relationships := make([]InstanceRelationship, 0, len(i.Relationships))
+	for _, relationship := range i.Relationships {
+		if !matches(relationship) {
+			relationships = append(relationships, relationship)
+			continue
+		}
+
+		targets := relationship.TargetExternalIds()
+		remainingTargets := slices.DeleteFunc(slices.Clone(targets), func(id string) bool {
+			return slices.Contains(externalIds, id)
+		})
+		if len(remainingTargets) == len(targets) {
+			relationships = append(relationships, relationship)
+			continue
+		}
+
+		removed = true
+		if len(remainingTargets) > 0 {
+			relationship.Target = remainingTargets
+			relationships = append(relationships, relationship)
+		}
+	}
+
+	if removed {
+		i.Relationships = relationships
+	}
+	return removed
+}
+
 type InstanceFormMetaData struct {
 	BasicInformation InstanceMetaData `json:"basicInformation"`
 	Attributes       InstanceMetaData `json:"attributes"`
@@ -46,14 +140,36 @@ type InstanceMetaData struct {
 }
 
 type InstanceMetaDataFields struct {
-	ID             string      `json:"id"`
-	Label          string      `json:"label"`
-	InfoText       string      `json:"infoText"`
-	TypeLabel      string      `json:"typeLabel"`
-	Type           string      `json:"type"`
-	IsRequired     bool        `json:"isRequired"`
-	IsHidden       bool        `json:"isHidden"`
-	DropdownValues []string    `json:"dropdownValues"`
-	ManualValue    interface{} `json:"manualValue"`
-	Unit           string      `json:"unit"`
+	ID             string       `json:"id"`
+	Label          string       `json:"label"`
+	InfoText       string       `json:"infoText"`
+	TypeLabel      string       `json:"typeLabel"`
+	Type           string       `json:"type"`
+	IsRequired     bool         `json:"isRequired"`
+	IsHidden       bool         `json:"isHidden"`
+	DropdownValues []string     `json:"dropdownValues"`
+	Options        []string     `json:"options,omitempty"`
+	Constraints    *Constraints `json:"constraints,omitempty"`
+	ManualValue    interface{}  `json:"manualValue"`
+	Unit           string       `json:"unit"`
+}
+
+type InstanceGraph struct {
+	Nodes []InstanceGraphNode `json:"nodes"`
+	Edges []InstanceGraphEdge `json:"edges"`
+}
+
+type InstanceGraphNode struct {
+	ExternalId   string `json:"externalId"`
+	Name         string `json:"name"`
+	Parent       string `json:"parent"`
+	RootTemplate string `json:"rootTemplate"`
+	Depth        int    `json:"depth"`
+}
+
+type InstanceGraphEdge struct {
+	Source                 string             `json:"source"`
+	Target                 string             `json:"target"`
+	Relationship           string             `json:"relationship"`
+	RelationshipTemplateId primitive.ObjectID `json:"relationshipTemplateId"`
 }
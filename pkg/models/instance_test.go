@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRemoveRelationshipTarget_RemovesExternalIdAndEmptyRelationships(t *testing.T) {
+	inverseRelationshipId := primitive.NewObjectID()
+	otherRelationshipId := primitive.NewObjectID()
+	instance := &Instance{
+		Relationships: []InstanceRelationship{
+			{ID: "1", Target: primitive.A{"floor1", "floor2"}, RelationshipTemplateId: inverseRelationshipId},
+			{ID: "2", Target: "floor1", RelationshipTemplateId: inverseRelationshipId},
+			{ID: "3", Target: primitive.A{"floor1"}, RelationshipTemplateId: otherRelationshipId},
+		},
+	}
+
+	removed := instance.RemoveRelationshipTarget(inverseRelationshipId, "floor1")
+
+	assert.True(t, removed)
+	assert.Equal(t, []InstanceRelationship{
+		{ID: "1", Target: []string{"floor2"}, RelationshipTemplateId: inverseRelationshipId},
+		{ID: "3", Target: primitive.A{"floor1"}, RelationshipTemplateId: otherRelationshipId},
+	}, instance.Relationships)
+}
+
+func TestRemoveRelationshipTarget_NoMatch_LeavesRelationshipsUntouched(t *testing.T) {
+	inverseRelationshipId := primitive.NewObjectID()
+	instance := &Instance{
+		Relationships: []InstanceRelationship{
+			{ID: "1", Target: primitive.A{"floor2"}, RelationshipTemplateId: inverseRelationshipId},
+		},
+	}
+
+	removed := instance.RemoveRelationshipTarget(inverseRelationshipId, "floor1")
+
+	assert.False(t, removed)
+	assert.Equal(t, primitive.A{"floor2"}, instance.Relationships[0].Target)
+}
+
+func TestInstance_RemoveRelationshipTargets_RemovesExternalIdsFromEveryRelationship(t *testing.T) {
+	instance := &Instance{
+		Relationships: []InstanceRelationship{
+			{ID: "1", Target: primitive.A{"floor1", "floor2"}, RelationshipTemplateId: primitive.NewObjectID()},
+			{ID: "2", Target: "floor2", RelationshipTemplateId: primitive.NewObjectID()},
+		},
+	}
+
+	removed := instance.RemoveRelationshipTargets([]string{"floor2"})
+
+	assert.True(t, removed)
+	assert.Len(t, instance.Relationships, 1)
+	assert.Equal(t, []string{"floor1"}, instance.Relationships[0].Target)
+}
+
+func TestInstanceRelationship_TargetExternalIds_HandlesStoredAndRequestShapes(t *testing.T) {
+	assert.Equal(t, []string{"floor1"}, InstanceRelationship{Target: "floor1"}.TargetExternalIds())
+	assert.Equal(t, []string{"floor1", "floor2"}, InstanceRelationship{Target: []interface{}{"floor1", "floor2"}}.TargetExternalIds())
+	assert.Equal(t, []string{"floor1"}, InstanceRelationship{Target: primitive.A{"floor1"}}.TargetExternalIds())
+	assert.Equal(t, []string{}, InstanceRelationship{Target: nil}.TargetExternalIds())
+}
+
+func TestInstance_LowercaseRelationshipTargets_KeepsTargetShape(t *testing.T) {
+	instance := &Instance{
+		Relationships: []InstanceRelationship{
+			{ID: "1", Target: "Floor1"},
+			{ID: "2", Target: []interface{}{"Floor2", "FLOOR3"}},
+			{ID: "3", Target: nil},
+		},
+	}
+
+	instance.LowercaseRelationshipTargets()
+
+	assert.Equal(t, "floor1", instance.Relationships[0].Target)
+	assert.Equal(t, []string{"floor2", "floor3"}, instance.Relationships[1].Target)
+	assert.Nil(t, instance.Relationships[2].Target)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+var ListSortFields = []string{"externalId", "name", "parent", "rootTemplate"}
+
+type ListQuery struct {
+	TenantID     string
+	Limit        int64
+	Page         int64
+	Cursor       *ListCursor
+	Sort         string
+	Descending   bool
+	Parent       string
+	RootTemplate string
+	NamePrefix   string
+	Attributes   []AttributeFilter
+}
+
+type AttributeFilter struct {
+	ID    string
+	Value string
+}
+
+type InstancePage struct {
+	Items         []Instance `json:"data"`
+	Total         int64      `json:"total"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+}
+
+type TemplatePage struct {
+	Items         []Template `json:"data"`
+	Total         int64      `json:"total"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+}
+
+type ListCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Value      string `json:"v"`
+	ExternalId string `json:"id"`
+}
+
+func (c ListCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeListCursor(token string) (*ListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &ListCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
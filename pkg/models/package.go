@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	TemplatePackageVersion = 1
+
+	ConflictStrategySkip      = "skip"
+	ConflictStrategyOverwrite = "overwrite"
+	ConflictStrategyRename    = "rename"
+
+	PackageStatusCreated     = "created"
+	PackageStatusSkipped     = "skipped"
+	PackageStatusOverwritten = "overwritten"
+	PackageStatusRenamed     = "renamed"
+)
+
+type TemplatePackage struct {
+	Version       int            `json:"version"`
+	Root          string         `json:"root"`
+	ExportedAt    time.Time      `json:"exportedAt"`
+	Templates     []Template     `json:"templates"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+type PackageImportOptions struct {
+	Strategy string
+	Parent   string
+	DryRun   bool
+}
+
+type PackageImportReport struct {
+	DryRun    bool                    `json:"dryRun"`
+	Templates []PackageTemplateResult `json:"templates"`
+}
+
+type PackageTemplateResult struct {
+	ExternalId string `json:"externalId"`
+	ImportedAs string `json:"importedAs"`
+	Status     string `json:"status"`
+}
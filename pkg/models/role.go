@@ -0,0 +1,53 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	RoleViewer   = "viewer"
+	RoleEditor   = "editor"
+	RoleModeller = "modeller"
+	RoleAdmin    = "admin"
+
+	PermissionTemplatesRead      = "templates:read"
+	PermissionTemplatesWrite     = "templates:write"
+	PermissionInstancesRead      = "instances:read"
+	PermissionInstancesWrite     = "instances:write"
+	PermissionRelationshipsRead  = "relationships:read"
+	PermissionRelationshipsWrite = "relationships:write"
+	PermissionRolesManage        = "roles:manage"
+	PermissionAuditRead          = "audit:read"
+)
+
+var Roles = []string{RoleViewer, RoleEditor, RoleModeller, RoleAdmin}
+
+var rolePermissions = map[string][]string{
+	RoleViewer:   {PermissionTemplatesRead, PermissionInstancesRead, PermissionRelationshipsRead},
+	RoleEditor:   {PermissionTemplatesRead, PermissionInstancesRead, PermissionRelationshipsRead, PermissionInstancesWrite},
+	RoleModeller: {PermissionTemplatesRead, PermissionInstancesRead, PermissionRelationshipsRead, PermissionInstancesWrite, PermissionTemplatesWrite, PermissionRelationshipsWrite, PermissionAuditRead},
+	RoleAdmin:    {PermissionTemplatesRead, PermissionInstancesRead, PermissionRelationshipsRead, PermissionInstancesWrite, PermissionTemplatesWrite, PermissionRelationshipsWrite, PermissionAuditRead, PermissionRolesManage},
+}
+
+type RoleAssignment struct {
+	TenantID   string    `bson:"tenantId" json:"-"`
+	Subject    string    `bson:"subject" json:"subject"`
+	Role       string    `bson:"role" json:"role"`
+	AssignedBy string    `bson:"assignedBy" json:"assignedBy"`
+	AssignedAt time.Time `bson:"assignedAt" json:"assignedAt"`
+}
+
+func RoleHasPermission(role string, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+func HighestRole(roles []string) string {
+	highest := ""
+	for _, role := range roles {
+		if slices.Index(Roles, role) > slices.Index(Roles, highest) {
+			highest = role
+		}
+	}
+	return highest
+}
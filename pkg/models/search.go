@@ -0,0 +1,10 @@
+package models
+
+type SearchHit struct {
+	Kind         string  `json:"kind"`
+	ExternalId   string  `json:"externalId"`
+	Name         string  `json:"name"`
+	Parent       string  `json:"parent"`
+	RootTemplate string  `json:"rootTemplate"`
+	Score        float64 `json:"score"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+type MetricPoint struct {
+	Timestamp time.Time       `bson:"timestamp" json:"timestamp"`
+	Meta      MetricPointMeta `bson:"meta" json:"meta"`
+	Value     interface{}     `bson:"value" json:"value"`
+}
+
+type MetricPointMeta struct {
+	TenantID   string `bson:"tenantId" json:"-"`
+	InstanceID string `bson:"instanceId" json:"instanceId"`
+	MetricID   string `bson:"metricId" json:"metricId"`
+	Unit       string `bson:"unit" json:"unit"`
+}
+
+type MetricPointInput struct {
+	InstanceID string      `json:"instanceId,omitempty"`
+	MetricID   string      `json:"metricId,omitempty"`
+	Timestamp  *time.Time  `json:"timestamp"`
+	Value      interface{} `json:"value"`
+	Unit       string      `json:"unit"`
+}
+
+type MetricPointBatch struct {
+	Points []MetricPointInput `json:"points"`
+}
+
+type SeriesQuery struct {
+	From        time.Time
+	To          time.Time
+	Interval    time.Duration
+	Aggregation string
+	Unit        string
+	Conversions []UnitConversion
+}
+
+type SeriesScope struct {
+	Template      string
+	Instance      string
+	Relationships []string
+	Depth         int
+}
+
+type SeriesBucket struct {
+	Timestamp time.Time   `bson:"_id" json:"timestamp"`
+	Value     interface{} `bson:"value" json:"value"`
+	Count     int64       `bson:"count" json:"count"`
+}
+
+type MetricSeries struct {
+	MetricID    string         `json:"metricId"`
+	Unit        string         `json:"unit"`
+	Aggregation string         `json:"aggregation"`
+	Interval    string         `json:"interval"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Instances   []string       `json:"instances"`
+	Buckets     []SeriesBucket `json:"buckets"`
+}
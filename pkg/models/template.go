@@ -5,6 +5,7 @@ type Template struct {
 	BasicInformation TemplateBasicInformation `bson:"basicInformation" json:"basicInformation"`
 	Attributes       []TemplateAttribute      `bson:"attributes" json:"attributes"`
 	Metrics          []TemplateMetric         `bson:"metrics" json:"metrics"`
+	Version          int64                    `bson:"version" json:"version,omitempty"`
 }
 
 type TemplateBasicInformation struct {
@@ -15,23 +16,65 @@ type TemplateBasicInformation struct {
 	RootTemplate string `bson:"rootTemplate" json:"rootTemplate"`
 }
 
+type TemplateDependents struct {
+	Templates []string `json:"templates"`
+	Instances []string `json:"instances"`
+}
+
 type TemplateAttribute struct {
-	ID             string `bson:"id" json:"id"`
-	Name           string `bson:"name" json:"name"`
-	DataType       string `bson:"dataType" json:"dataType"`
-	IsRequired     bool   `bson:"isRequired" json:"isRequired"`
-	IsHidden       bool   `bson:"isHidden" json:"isHidden"`
-	OwningTemplate string `bson:"owningTemplate" json:"owningTemplate"`
+	ID             string       `bson:"id" json:"id"`
+	Name           string       `bson:"name" json:"name"`
+	DataType       string       `bson:"dataType" json:"dataType"`
+	IsRequired     bool         `bson:"isRequired" json:"isRequired"`
+	IsHidden       bool         `bson:"isHidden" json:"isHidden"`
+	EnumValues     []string     `bson:"enumValues,omitempty" json:"enumValues,omitempty"`
+	Constraints    *Constraints `bson:"constraints,omitempty" json:"constraints,omitempty"`
+	OwningTemplate string       `bson:"owningTemplate" json:"owningTemplate"`
 }
 
 type TemplateMetric struct {
-	ID             string      `bson:"id" json:"id"`
-	Name           string      `bson:"name" json:"name"`
-	MetricType     string      `bson:"metricType" json:"metricType"`
-	Unit           string      `bson:"unit" json:"unit"`
-	IsManual       bool        `bson:"isManual" json:"isManual"`
-	Value          interface{} `bson:"value" json:"value"`
-	IsCalculated   bool        `bson:"isCalculated" json:"isCalculated"`
-	IsSourced      bool        `bson:"isSourced" json:"isSourced"`
-	OwningTemplate string      `bson:"owningTemplate" json:"owningTemplate"`
+	ID             string       `bson:"id" json:"id"`
+	Name           string       `bson:"name" json:"name"`
+	MetricType     string       `bson:"metricType" json:"metricType"`
+	Unit           string       `bson:"unit" json:"unit"`
+	IsManual       bool         `bson:"isManual" json:"isManual"`
+	Value          interface{}  `bson:"value" json:"value"`
+	IsCalculated   bool         `bson:"isCalculated" json:"isCalculated"`
+	Formula        string       `bson:"formula,omitempty" json:"formula,omitempty"`
+	IsSourced      bool         `bson:"isSourced" json:"isSourced"`
+	Constraints    *Constraints `bson:"constraints,omitempty" json:"constraints,omitempty"`
+	OwningTemplate string       `bson:"owningTemplate" json:"owningTemplate"`
+}
+
+type TemplateSchemaDiff struct {
+	AddedAttributes         []string `json:"addedAttributes"`
+	RemovedAttributes       []string `json:"removedAttributes"`
+	RetypedAttributes       []string `json:"retypedAttributes"`
+	NewlyRequiredAttributes []string `json:"newlyRequiredAttributes"`
+	AddedMetrics            []string `json:"addedMetrics"`
+	RemovedMetrics          []string `json:"removedMetrics"`
+	RetypedMetrics          []string `json:"retypedMetrics"`
+	ConstrainedAttributes   []string `json:"constrainedAttributes"`
+	ConstrainedMetrics      []string `json:"constrainedMetrics"`
+}
+
+func (d TemplateSchemaDiff) IsEmpty() bool {
+	return len(d.AddedAttributes) == 0 && len(d.RemovedAttributes) == 0 && len(d.RetypedAttributes) == 0 &&
+		len(d.NewlyRequiredAttributes) == 0 && len(d.AddedMetrics) == 0 && len(d.RemovedMetrics) == 0 && len(d.RetypedMetrics) == 0 &&
+		len(d.ConstrainedAttributes) == 0 && len(d.ConstrainedMetrics) == 0
+}
+
+type SchemaMigrationReport struct {
+	DryRun                bool               `json:"dryRun"`
+	Version               int64              `json:"version"`
+	Diff                  TemplateSchemaDiff `json:"diff"`
+	MigratedInstances     []string           `json:"migratedInstances"`
+	RecalculatedInstances []string           `json:"recalculatedInstances"`
+	InvalidInstances      []InvalidInstance  `json:"invalidInstances"`
+}
+
+type InvalidInstance struct {
+	ExternalId string   `json:"externalId"`
+	Template   string   `json:"template"`
+	Reasons    []string `json:"reasons"`
 }
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownUnit      = errors.New("unknown unit")
+	ErrIncompatibleUnit = errors.New("incompatible unit")
+)
+
+type UnitCatalogue []Dropdown
+
+type UnitConversion struct {
+	Unit   string
+	Factor float64
+	Offset float64
+}
+
+func (c UnitCatalogue) Find(unit string) (Dropdown, bool) {
+	for _, candidate := range c {
+		if candidate.Value == unit || candidate.Symbol == unit {
+			return candidate, true
+		}
+	}
+	return Dropdown{}, false
+}
+
+func (c UnitCatalogue) Conversion(from string, to string) (UnitConversion, error) {
+	if from == to {
+		return UnitConversion{Unit: from, Factor: 1}, nil
+	}
+
+	fromUnit, ok := c.Find(from)
+	if !ok {
+		return UnitConversion{}, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	toUnit, ok := c.Find(to)
+	if !ok {
+		return UnitConversion{}, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if fromUnit.Dimension == "" || fromUnit.Dimension != toUnit.Dimension {
+		return UnitConversion{}, fmt.Errorf("%w: cannot convert %q to %q", ErrIncompatibleUnit, from, to)
+	}
+
+	fromFactor, toFactor := unitFactor(fromUnit), unitFactor(toUnit)
+	return UnitConversion{
+		Unit:   from,
+		Factor: fromFactor / toFactor,
+		Offset: (fromUnit.Offset - toUnit.Offset) / toFactor,
+	}, nil
+}
+
+func (c UnitConversion) Apply(value float64) float64 {
+	return value*c.Factor + c.Offset
+}
+
+func (c UnitConversion) IsIdentity() bool {
+	return c.Factor == 1 && c.Offset == 0
+}
+
+func unitFactor(unit Dropdown) float64 {
+	if unit.Factor == 0 {
+		return 1
+	}
+	return unit.Factor
+}
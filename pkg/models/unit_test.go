@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testUnits = UnitCatalogue{
+	{Label: "Celsius", Value: "celsius", Symbol: "°C", Dimension: "temperature", Factor: 1, Offset: 273.15},
+	{Label: "Fahrenheit", Value: "fahrenheit", Symbol: "°F", Dimension: "temperature", Factor: 5.0 / 9, Offset: 459.67 * 5 / 9},
+	{Label: "Kilowatt hour", Value: "kwh", Symbol: "kWh", Dimension: "energy", Factor: 3.6e6},
+	{Label: "Megawatt hour", Value: "mwh", Symbol: "MWh", Dimension: "energy", Factor: 3.6e9},
+	{Label: "Count", Value: "count"},
+}
+
+func TestUnitCatalogue_Conversion_CompatibleUnits_Converts(t *testing.T) {
+	for _, test := range []struct {
+		from, to        string
+		value, expected float64
+	}{
+		{"MWh", "kwh", 1.5, 1500},
+		{"kwh", "mwh", 250, 0.25},
+		{"°F", "°C", 212, 100},
+		{"celsius", "fahrenheit", -40, -40},
+		{"count", "count", 7, 7},
+	} {
+		conversion, err := testUnits.Conversion(test.from, test.to)
+		assert.Nil(t, err)
+		assert.InDelta(t, test.expected, conversion.Apply(test.value), 1e-9, "%s -> %s", test.from, test.to)
+	}
+}
+
+func TestUnitCatalogue_Conversion_SameUnit_IsIdentity(t *testing.T) {
+	conversion, err := testUnits.Conversion("unlisted", "unlisted")
+	assert.Nil(t, err)
+	assert.True(t, conversion.IsIdentity())
+}
+
+func TestUnitCatalogue_Conversion_Invalid_ReturnsError(t *testing.T) {
+	_, err := testUnits.Conversion("kwh", "celsius")
+	assert.ErrorIs(t, err, ErrIncompatibleUnit)
+
+	_, err = testUnits.Conversion("count", "kwh")
+	assert.ErrorIs(t, err, ErrIncompatibleUnit)
+
+	_, err = testUnits.Conversion("furlong", "kwh")
+	assert.ErrorIs(t, err, ErrUnknownUnit)
+}
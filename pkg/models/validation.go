@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ValidationCodeRequired     = "required"
+	ValidationCodeTypeMismatch = "type_mismatch"
+	ValidationCodePattern      = "pattern"
+	ValidationCodeUnknownField = "unknown_field"
+	ValidationCodeOutOfRange   = "out_of_range"
+	ValidationCodeLength       = "length"
+	ValidationCodeNotAllowed   = "not_allowed"
+	ValidationCodeNotUnique    = "not_unique"
+	ValidationCodeInvalid      = "invalid"
+	ValidationCodeUnitMismatch = "unit_mismatch"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	ID      string `json:"id,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Add(field, id, code, message string) {
+	e.Errors = append(e.Errors, FieldError{
+		Field:   field,
+		ID:      id,
+		Code:    code,
+		Message: message,
+	})
+}
+
+func (e *ValidationError) Merge(other error) {
+	if validationErr, ok := other.(*ValidationError); ok && validationErr != nil {
+		e.Errors = append(e.Errors, validationErr.Errors...)
+	}
+}
+
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
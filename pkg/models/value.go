@@ -0,0 +1,322 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidValue    = errors.New("invalid value")
+	ErrPatternMismatch = errors.New("value does not match pattern")
+)
+
+const dateLayout = "2006-01-02"
+
+var DataTypeLabels = map[string]string{
+	"integer":  "Integer",
+	"float":    "Float",
+	"bool":     "Boolean",
+	"string":   "String",
+	"date":     "Date",
+	"datetime": "Date & Time",
+	"enum":     "Enum",
+	"url":      "URL",
+	"email":    "Email",
+	"json":     "JSON",
+	"geopoint": "Geo Point",
+}
+
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+func NewGeoPoint(latitude, longitude float64) GeoPoint {
+	return GeoPoint{
+		Type:        "Point",
+		Coordinates: []float64{longitude, latitude},
+	}
+}
+
+func ParseValue(dataType string, value string) (interface{}, error) {
+	switch dataType {
+	case "integer":
+		integerValue, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an integer value", ErrInvalidValue, value)
+		}
+		return integerValue, nil
+	case "float":
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a float value", ErrInvalidValue, value)
+		}
+		return floatValue, nil
+	case "bool":
+		booleanValue, err := strconv.ParseBool(strings.ToLower(value))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a boolean value", ErrInvalidValue, value)
+		}
+		return booleanValue, nil
+	case "date":
+		dateValue, err := time.Parse(dateLayout, value)
+		if err != nil {
+			dateTimeValue, dateTimeErr := time.Parse(time.RFC3339, value)
+			if dateTimeErr != nil {
+				return nil, fmt.Errorf("%w: %q is not a date value (YYYY-MM-DD)", ErrInvalidValue, value)
+			}
+			dateValue = dateTimeValue.UTC().Truncate(24 * time.Hour)
+		}
+		return dateValue, nil
+	case "datetime":
+		dateTimeValue, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an RFC 3339 date-time value", ErrInvalidValue, value)
+		}
+		return dateTimeValue.UTC(), nil
+	case "url":
+		urlValue, err := url.ParseRequestURI(value)
+		if err != nil || urlValue.Scheme == "" || urlValue.Host == "" {
+			return nil, fmt.Errorf("%w: %q is not an absolute URL", ErrInvalidValue, value)
+		}
+		return value, nil
+	case "email":
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value {
+			return nil, fmt.Errorf("%w: %q is not an email address", ErrInvalidValue, value)
+		}
+		return value, nil
+	case "json":
+		var jsonValue interface{}
+		if err := json.Unmarshal([]byte(value), &jsonValue); err != nil {
+			return nil, fmt.Errorf("%w: %q is not valid JSON", ErrInvalidValue, value)
+		}
+		return jsonValue, nil
+	case "geopoint":
+		return parseGeoPoint(value)
+	}
+
+	return value, nil
+}
+
+func ParseAttributeValue(attribute TemplateAttribute, value string) (interface{}, error) {
+	if attribute.DataType == "enum" {
+		if !slices.Contains(attribute.EnumValues, value) {
+			return nil, fmt.Errorf("%w: %q is not one of %s", ErrInvalidValue, value, strings.Join(attribute.EnumValues, ", "))
+		}
+		if err := attribute.Constraints.Validate(value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	parsedValue, err := ParseValue(attribute.DataType, value)
+	if err != nil {
+		return nil, err
+	}
+	if err := attribute.Constraints.Validate(parsedValue); err != nil {
+		return nil, err
+	}
+	return parsedValue, nil
+}
+
+func ParseMetricValue(metric TemplateMetric, value string) (interface{}, error) {
+	parsedValue, err := ParseValue(metric.MetricType, value)
+	if err != nil {
+		return nil, err
+	}
+	if err := metric.Constraints.Validate(parsedValue); err != nil {
+		return nil, err
+	}
+	return parsedValue, nil
+}
+
+func ConvertValue(dataType string, value interface{}) (interface{}, error) {
+	if value == nil || value == "" {
+		return value, nil
+	}
+	return ParseValue(dataType, ValueString(value))
+}
+
+func ConvertAttributeValue(attribute TemplateAttribute, value interface{}) (interface{}, error) {
+	if value == nil || value == "" {
+		return value, nil
+	}
+	return ParseAttributeValue(attribute, ValueString(value))
+}
+
+func ConvertMetricValue(metric TemplateMetric, value interface{}) (interface{}, error) {
+	if value == nil || value == "" {
+		return value, nil
+	}
+	return ParseMetricValue(metric, ValueString(value))
+}
+
+func ValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case primitive.DateTime:
+		return v.Time().UTC().Format(time.RFC3339)
+	case primitive.D:
+		return ValueString(DecodeValue(v))
+	case map[string]interface{}, []interface{}, primitive.M, primitive.A, GeoPoint:
+		jsonValue, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(jsonValue)
+	}
+	return fmt.Sprint(value)
+}
+
+// DecodeValue turns embedded documents read into an interface{} into maps, so json and geopoint
+// values keep their object shape instead of the driver's ordered key/value pairs.
+func DecodeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.D:
+		document := make(primitive.M, len(v))
+		for _, element := range v {
+			document[element.Key] = DecodeValue(element.Value)
+		}
+		return document
+	case primitive.M:
+		for key, element := range v {
+			v[key] = DecodeValue(element)
+		}
+		return v
+	case primitive.A:
+		for i, element := range v {
+			v[i] = DecodeValue(element)
+		}
+		return v
+	}
+	return value
+}
+
+func (a *InstanceAttribute) UnmarshalBSON(data []byte) error {
+	type instanceAttribute InstanceAttribute
+	if err := bson.Unmarshal(data, (*instanceAttribute)(a)); err != nil {
+		return err
+	}
+	a.Value = DecodeValue(a.Value)
+	return nil
+}
+
+func (m *InstanceMetric) UnmarshalBSON(data []byte) error {
+	type instanceMetric InstanceMetric
+	if err := bson.Unmarshal(data, (*instanceMetric)(m)); err != nil {
+		return err
+	}
+	m.Value = DecodeValue(m.Value)
+	return nil
+}
+
+func (m *TemplateMetric) UnmarshalBSON(data []byte) error {
+	type templateMetric TemplateMetric
+	if err := bson.Unmarshal(data, (*templateMetric)(m)); err != nil {
+		return err
+	}
+	m.Value = DecodeValue(m.Value)
+	return nil
+}
+
+func (c *Constraints) UnmarshalBSON(data []byte) error {
+	type constraints Constraints
+	if err := bson.Unmarshal(data, (*constraints)(c)); err != nil {
+		return err
+	}
+	c.Default = DecodeValue(c.Default)
+	return nil
+}
+
+func (p *MetricPoint) UnmarshalBSON(data []byte) error {
+	type metricPoint MetricPoint
+	if err := bson.Unmarshal(data, (*metricPoint)(p)); err != nil {
+		return err
+	}
+	p.Value = DecodeValue(p.Value)
+	return nil
+}
+
+func (c *AuditChange) UnmarshalBSON(data []byte) error {
+	type auditChange AuditChange
+	if err := bson.Unmarshal(data, (*auditChange)(c)); err != nil {
+		return err
+	}
+	c.Before = DecodeValue(c.Before)
+	c.After = DecodeValue(c.After)
+	return nil
+}
+
+func ValueErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrPatternMismatch):
+		return ValidationCodePattern
+	case errors.Is(err, ErrOutOfRange):
+		return ValidationCodeOutOfRange
+	case errors.Is(err, ErrInvalidLength):
+		return ValidationCodeLength
+	case errors.Is(err, ErrValueNotAllowed):
+		return ValidationCodeNotAllowed
+	case errors.Is(err, ErrInvalidConstraint):
+		return ValidationCodeInvalid
+	}
+	return ValidationCodeTypeMismatch
+}
+
+func parseGeoPoint(value string) (interface{}, error) {
+	invalidErr := fmt.Errorf("%w: %q is not a geo point (\"lat,long\" or {\"lat\":..,\"long\":..})", ErrInvalidValue, value)
+
+	var latitude, longitude float64
+	if strings.HasPrefix(strings.TrimSpace(value), "{") {
+		var point struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+			Lat         *float64  `json:"lat"`
+			Long        *float64  `json:"long"`
+		}
+		if err := json.Unmarshal([]byte(value), &point); err != nil {
+			return nil, invalidErr
+		}
+		switch {
+		case point.Lat != nil && point.Long != nil:
+			latitude, longitude = *point.Lat, *point.Long
+		case point.Type == "Point" && len(point.Coordinates) == 2:
+			longitude, latitude = point.Coordinates[0], point.Coordinates[1]
+		default:
+			return nil, invalidErr
+		}
+	} else {
+		parts := strings.Split(value, ",")
+		if len(parts) != 2 {
+			return nil, invalidErr
+		}
+		var err error
+		if latitude, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+			return nil, invalidErr
+		}
+		if longitude, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+			return nil, invalidErr
+		}
+	}
+
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return nil, fmt.Errorf("%w: latitude must be within [-90, 90] and longitude within [-180, 180]", ErrInvalidValue)
+	}
+	return NewGeoPoint(latitude, longitude), nil
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseValue_NewDataTypes_ReturnsTypedValues(t *testing.T) {
+	tests := []struct {
+		dataType string
+		value    string
+		expected interface{}
+	}{
+		{"date", "2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"date", "2024-03-01T10:30:00Z", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"datetime", "2024-03-01T10:30:00+02:00", time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{"url", "https://buildifyy.com/docs", "https://buildifyy.com/docs"},
+		{"email", "ops@buildifyy.com", "ops@buildifyy.com"},
+		{"json", `{"floors":3}`, map[string]interface{}{"floors": float64(3)}},
+		{"geopoint", "52.52, 13.405", NewGeoPoint(52.52, 13.405)},
+		{"geopoint", `{"lat":52.52,"long":13.405}`, NewGeoPoint(52.52, 13.405)},
+		{"geopoint", `{"type":"Point","coordinates":[13.405,52.52]}`, NewGeoPoint(52.52, 13.405)},
+	}
+
+	for _, test := range tests {
+		actual, err := ParseValue(test.dataType, test.value)
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, actual, test.value)
+	}
+}
+
+func TestParseValue_InvalidNewDataTypes_ReturnsInvalidValueError(t *testing.T) {
+	tests := []struct {
+		dataType string
+		value    string
+	}{
+		{"date", "01/03/2024"},
+		{"datetime", "2024-03-01 10:30"},
+		{"url", "buildifyy.com"},
+		{"email", "Ops <ops@buildifyy.com>"},
+		{"json", "{floors:3}"},
+		{"geopoint", "52.52"},
+		{"geopoint", "91,13"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseValue(test.dataType, test.value)
+		assert.ErrorIs(t, err, ErrInvalidValue, test.value)
+		assert.Equal(t, ValidationCodeTypeMismatch, ValueErrorCode(err))
+	}
+}
+
+func TestParseAttributeValue_Enum_ChecksAllowedValues(t *testing.T) {
+	attribute := TemplateAttribute{DataType: "enum", EnumValues: []string{"active", "retired"}}
+
+	actual, err := ParseAttributeValue(attribute, "retired")
+	assert.NoError(t, err)
+	assert.Equal(t, "retired", actual)
+
+	_, err = ParseAttributeValue(attribute, "broken")
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestConvertAttributeValue_StoredValues_RoundTrip(t *testing.T) {
+	actual, err := ConvertAttributeValue(TemplateAttribute{DataType: "geopoint"}, map[string]interface{}{
+		"type":        "Point",
+		"coordinates": []interface{}{13.405, 52.52},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, NewGeoPoint(52.52, 13.405), actual)
+
+	actual, err = ConvertAttributeValue(TemplateAttribute{DataType: "date"}, time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), actual)
+}
+
+func TestDecodeValue_EmbeddedDocuments_ReturnsMaps(t *testing.T) {
+	data, _ := bson.Marshal(Instance{
+		Attributes: []InstanceAttribute{{ID: "1", Value: map[string]interface{}{"pump": primitive.A{map[string]interface{}{"rpm": 1200}}}}},
+		Metrics:    []InstanceMetric{{ID: "2", Value: NewGeoPoint(52.5, 13.4)}},
+	})
+
+	var instance Instance
+	assert.Nil(t, bson.Unmarshal(data, &instance))
+	assert.Equal(t, primitive.M{"pump": primitive.A{primitive.M{"rpm": int32(1200)}}}, instance.Attributes[0].Value)
+	assert.Equal(t, primitive.M{"type": "Point", "coordinates": primitive.A{13.4, 52.5}}, instance.Metrics[0].Value)
+}
+
+func TestParseAttributeValue_ConstraintViolation_ReturnsOnlyError(t *testing.T) {
+	attribute := TemplateAttribute{DataType: "string", Constraints: &Constraints{Pattern: "^P-[0-9]+$"}}
+
+	value, err := ParseAttributeValue(attribute, "X-1")
+	assert.Nil(t, value)
+	assert.ErrorIs(t, err, ErrPatternMismatch)
+
+	value, err = ParseAttributeValue(attribute, "P-12")
+	assert.Nil(t, err)
+	assert.Equal(t, "P-12", value)
+}
+
+func TestConstraints_Validate_InvalidPattern_ReturnsInvalidConstraintError(t *testing.T) {
+	constraints := &Constraints{Pattern: "("}
+
+	assert.ErrorIs(t, constraints.Check(), ErrInvalidConstraint)
+	assert.ErrorIs(t, constraints.Validate("anything"), ErrInvalidConstraint)
+}
@@ -0,0 +1,87 @@
+package rbac
+
+import (
+	"api/pkg/auth"
+	"api/pkg/common"
+	"api/pkg/models"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const roleKey = "role"
+
+type authorizer struct {
+	roleService Service
+}
+
+func NewAuthorizer(roleService Service) common.Authorizer {
+	return &authorizer{
+		roleService: roleService,
+	}
+}
+
+func (a *authorizer) Require(permission string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		principal, ok := auth.CurrentPrincipal(context)
+		if !ok {
+			common.RespondWithError(context, http.StatusUnauthorized, common.ErrorCodeUnauthorized, auth.ErrMissingCredentials.Error(), nil)
+			context.Abort()
+			return
+		}
+
+		role, err := a.role(context, principal)
+		if err != nil {
+			log.Println("error resolving role: ", err)
+			common.RespondWithServiceError(context, err)
+			context.Abort()
+			return
+		}
+
+		if !models.RoleHasPermission(role, permission) {
+			log.Printf("principal %s with role %q lacks permission %s\n", principal.Subject, role, permission)
+			common.RespondWithError(context, http.StatusForbidden, common.ErrorCodeForbidden, fmt.Sprintf("missing permission %s", permission), nil)
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
+
+func (a *authorizer) role(context *gin.Context, principal *auth.Principal) (string, error) {
+	if role := context.GetString(roleKey); role != "" {
+		return role, nil
+	}
+
+	var role string
+	var err error
+	switch tenantId := context.Param("tenantId"); {
+	case principal.HasTenant(auth.AllTenants):
+		role = models.RoleAdmin
+	case tenantId != "":
+		role, err = a.roleService.GetRole(tenantId, principal.Subject)
+	default:
+		role, err = a.roleService.GetHighestRole(principal.Subject, principal.Tenants)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	context.Set(roleKey, role)
+	return role, nil
+}
+
+type allowAllAuthorizer struct{}
+
+func NewAllowAllAuthorizer() common.Authorizer {
+	return &allowAllAuthorizer{}
+}
+
+func (a *allowAllAuthorizer) Require(string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Next()
+	}
+}
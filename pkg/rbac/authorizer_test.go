@@ -0,0 +1,62 @@
+package rbac
+
+import (
+	"api/pkg/auth"
+	"api/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthorizerTestEngine(roleService Service) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(auth.Middleware(auth.NewAPIKeyAuthenticator([]auth.APIKey{
+		{Name: "jane", Key: "jane-key", Tenants: []string{"the-binary", "other"}},
+		{Name: "operator", Key: "operator-key", Tenants: []string{auth.AllTenants}},
+	})))
+
+	authorizer := NewAuthorizer(roleService)
+	ok := func(context *gin.Context) {
+		context.Status(http.StatusOK)
+	}
+	r.GET("/api/v1/tenants/:tenantId/instances", authorizer.Require(models.PermissionInstancesRead), ok)
+	r.POST("/api/v1/tenants/:tenantId/instances", authorizer.Require(models.PermissionInstancesWrite), ok)
+	r.POST("/api/v1/tenants/:tenantId/templates", authorizer.Require(models.PermissionTemplatesWrite), ok)
+	r.GET("/api/v1/units", authorizer.Require(models.PermissionTemplatesRead), ok)
+	return r
+}
+
+func TestAuthorizer_Require_ChecksRolePermissions(t *testing.T) {
+	mockService := &MockService{}
+	mockService.On("GetRole", "the-binary", "jane").Return(models.RoleEditor, nil)
+	mockService.On("GetRole", "other", "jane").Return("", nil)
+	mockService.On("GetHighestRole", "jane", []string{"the-binary", "other"}).Return(models.RoleEditor, nil)
+	r := newAuthorizerTestEngine(mockService)
+
+	for _, testCase := range []struct {
+		method         string
+		path           string
+		apiKey         string
+		expectedStatus int
+	}{
+		{method: http.MethodGet, path: "/api/v1/tenants/the-binary/instances", apiKey: "jane-key", expectedStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/api/v1/tenants/the-binary/instances", apiKey: "jane-key", expectedStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/api/v1/tenants/the-binary/templates", apiKey: "jane-key", expectedStatus: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/v1/tenants/other/instances", apiKey: "jane-key", expectedStatus: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/v1/units", apiKey: "jane-key", expectedStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/api/v1/tenants/any/templates", apiKey: "operator-key", expectedStatus: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		request, _ := http.NewRequest(testCase.method, testCase.path, nil)
+		request.Header.Set(auth.APIKeyHeader, testCase.apiKey)
+		r.ServeHTTP(w, request)
+
+		assert.Equal(t, testCase.expectedStatus, w.Code, testCase.method+" "+testCase.path)
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,80 @@
+package rbac
+
+import (
+	"api/pkg/auth"
+	"api/pkg/common"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Controller interface {
+	GetRoleAssignments(context *gin.Context)
+	AssignRole(context *gin.Context)
+	RemoveRole(context *gin.Context)
+}
+
+type controller struct {
+	roleService Service
+}
+
+type roleAssignmentRequest struct {
+	Role string `json:"role"`
+}
+
+func NewController(roleService Service) Controller {
+	return &controller{
+		roleService: roleService,
+	}
+}
+
+func (c *controller) GetRoleAssignments(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+
+	res, err := c.roleService.GetRoleAssignments(tenantId)
+	if err != nil {
+		log.Println("error getting role assignments: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) AssignRole(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	subject := context.Param("subject")
+	var request roleAssignmentRequest
+
+	if err := context.ShouldBindJSON(&request); err != nil {
+		log.Println("error parsing request body: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	assignedBy := ""
+	if principal, ok := auth.CurrentPrincipal(context); ok {
+		assignedBy = principal.Subject
+	}
+	if err := c.roleService.AssignRole(tenantId, subject, request.Role, assignedBy); err != nil {
+		log.Println("error assigning role: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Status(http.StatusOK)
+}
+
+func (c *controller) RemoveRole(context *gin.Context) {
+	tenantId := context.Param("tenantId")
+	subject := context.Param("subject")
+
+	if err := c.roleService.RemoveRole(tenantId, subject); err != nil {
+		log.Println("error removing role: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Status(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package rbac
+
+import (
+	"api/pkg/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) GetRole(tenantId string, subject string) (string, error) {
+	args := m.Called(tenantId, subject)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockService) GetHighestRole(subject string, tenantIds []string) (string, error) {
+	args := m.Called(subject, tenantIds)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockService) GetRoleAssignments(tenantId string) ([]models.RoleAssignment, error) {
+	args := m.Called(tenantId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RoleAssignment), args.Error(1)
+}
+
+func (m *MockService) AssignRole(tenantId string, subject string, role string, assignedBy string) error {
+	args := m.Called(tenantId, subject, role, assignedBy)
+	return args.Error(0)
+}
+
+func (m *MockService) RemoveRole(tenantId string, subject string) error {
+	args := m.Called(tenantId, subject)
+	return args.Error(0)
+}
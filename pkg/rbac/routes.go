@@ -0,0 +1,15 @@
+package rbac
+
+import (
+	"api/pkg/common"
+	"api/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, roleController Controller, authorizer common.Authorizer) {
+	admin := r.Group("", authorizer.Require(models.PermissionRolesManage))
+	admin.GET("/api/v1/tenants/:tenantId/roles", roleController.GetRoleAssignments)
+	admin.PUT("/api/v1/tenants/:tenantId/roles/:subject", roleController.AssignRole)
+	admin.DELETE("/api/v1/tenants/:tenantId/roles/:subject", roleController.RemoveRole)
+}
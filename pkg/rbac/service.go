@@ -0,0 +1,150 @@
+package rbac
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Service interface {
+	GetRole(tenantId string, subject string) (string, error)
+	GetHighestRole(subject string, tenantIds []string) (string, error)
+	GetRoleAssignments(tenantId string) ([]models.RoleAssignment, error)
+	AssignRole(tenantId string, subject string, role string, assignedBy string) error
+	RemoveRole(tenantId string, subject string) error
+}
+
+type service struct {
+	db db.Repository
+}
+
+func NewService(dbRepository db.Repository) Service {
+	return &service{
+		db: dbRepository,
+	}
+}
+
+func (s *service) GetRole(tenantId string, subject string) (string, error) {
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "subject", Value: subject}}
+	assignments, err := s.db.GetRoleAssignments(filter)
+	if err != nil {
+		log.Println("error getting role assignments: ", err)
+		return "", err
+	}
+	if len(assignments) == 0 {
+		return "", nil
+	}
+
+	return assignments[0].Role, nil
+}
+
+func (s *service) GetHighestRole(subject string, tenantIds []string) (string, error) {
+	filter := bson.D{{Key: "subject", Value: subject}, {Key: "tenantId", Value: bson.D{{Key: "$in", Value: tenantIds}}}}
+	assignments, err := s.db.GetRoleAssignments(filter)
+	if err != nil {
+		log.Println("error getting role assignments: ", err)
+		return "", err
+	}
+
+	roles := make([]string, 0, len(assignments))
+	for _, assignment := range assignments {
+		roles = append(roles, assignment.Role)
+	}
+	return models.HighestRole(roles), nil
+}
+
+func (s *service) GetRoleAssignments(tenantId string) ([]models.RoleAssignment, error) {
+	assignments, err := s.db.GetRoleAssignments(bson.D{{Key: "tenantId", Value: tenantId}})
+	if err != nil {
+		log.Println("error getting role assignments: ", err)
+		return nil, err
+	}
+	if assignments == nil {
+		assignments = make([]models.RoleAssignment, 0)
+	}
+
+	return assignments, nil
+}
+
+func (s *service) AssignRole(tenantId string, subject string, role string, assignedBy string) error {
+	validationErr := &models.ValidationError{}
+	if subject == "" {
+		validationErr.Add("subject", "", models.ValidationCodeRequired, "subject is required but not provided")
+	}
+	if !slices.Contains(models.Roles, role) {
+		validationErr.Add("role", role, models.ValidationCodeNotAllowed, fmt.Sprintf("role must be one of %v", models.Roles))
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	currentRole, err := s.GetRole(tenantId, subject)
+	if err != nil {
+		return err
+	}
+	if currentRole == models.RoleAdmin && role != models.RoleAdmin {
+		if err := s.ensureOtherAdmin(tenantId, subject); err != nil {
+			return err
+		}
+	}
+
+	assignment := models.RoleAssignment{
+		TenantID:   tenantId,
+		Subject:    subject,
+		Role:       role,
+		AssignedBy: assignedBy,
+		AssignedAt: time.Now().UTC(),
+	}
+	if err := s.db.UpsertRoleAssignment(assignment); err != nil {
+		log.Println("error assigning role: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) RemoveRole(tenantId string, subject string) error {
+	currentRole, err := s.GetRole(tenantId, subject)
+	if err != nil {
+		return err
+	}
+	if currentRole == "" {
+		return db.ErrNotFound
+	}
+	if currentRole == models.RoleAdmin {
+		if err := s.ensureOtherAdmin(tenantId, subject); err != nil {
+			return err
+		}
+	}
+
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "subject", Value: subject}}
+	if err := s.db.DeleteRoleAssignment(filter); err != nil {
+		log.Println("error removing role: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ensureOtherAdmin(tenantId string, subject string) error {
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "role", Value: models.RoleAdmin}}
+	admins, err := s.db.GetRoleAssignments(filter)
+	if err != nil {
+		log.Println("error getting role assignments: ", err)
+		return err
+	}
+
+	if !slices.ContainsFunc(admins, func(a models.RoleAssignment) bool {
+		return a.Subject != subject
+	}) {
+		validationErr := &models.ValidationError{}
+		validationErr.Add("subject", subject, models.ValidationCodeNotAllowed, "a tenant needs at least one other admin")
+		return validationErr
+	}
+	return nil
+}
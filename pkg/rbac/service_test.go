@@ -0,0 +1,94 @@
+package rbac
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNewService(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+	newService := NewService(mockRepository)
+
+	assert.Equal(t, mockService, newService)
+}
+
+func TestService_GetHighestRole_MultipleTenants_ReturnsHighestRole(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	mockRepository.On("GetRoleAssignments", bson.D{{Key: "subject", Value: "jane"}, {Key: "tenantId", Value: bson.D{{Key: "$in", Value: []string{"a", "b"}}}}}).Return([]models.RoleAssignment{
+		{TenantID: "a", Subject: "jane", Role: models.RoleModeller},
+		{TenantID: "b", Subject: "jane", Role: models.RoleViewer},
+	}, nil)
+
+	actual, actualErr := mockService.GetHighestRole("jane", []string{"a", "b"})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, models.RoleModeller, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AssignRole_Success_UpsertsAssignment(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	mockRepository.On("GetRoleAssignments", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "subject", Value: "jane"}}).Return([]models.RoleAssignment{}, nil)
+	mockRepository.On("UpsertRoleAssignment", mock.MatchedBy(func(assignment models.RoleAssignment) bool {
+		return assignment.TenantID == "the-binary" && assignment.Subject == "jane" && assignment.Role == models.RoleEditor && assignment.AssignedBy == "admin" && !assignment.AssignedAt.IsZero()
+	})).Return(nil)
+
+	actualErr := mockService.AssignRole("the-binary", "jane", models.RoleEditor, "admin")
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AssignRole_UnknownRole_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	actualErr := mockService.AssignRole("the-binary", "jane", "owner", "admin")
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, "role", validationErr.Errors[0].Field)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RemoveRole_LastAdmin_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	adminAssignment := models.RoleAssignment{TenantID: "the-binary", Subject: "admin", Role: models.RoleAdmin}
+	mockRepository.On("GetRoleAssignments", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "subject", Value: "admin"}}).Return([]models.RoleAssignment{adminAssignment}, nil)
+	mockRepository.On("GetRoleAssignments", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "role", Value: models.RoleAdmin}}).Return([]models.RoleAssignment{adminAssignment}, nil)
+
+	actualErr := mockService.RemoveRole("the-binary", "admin")
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, models.ValidationCodeNotAllowed, validationErr.Errors[0].Code)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RemoveRole_NoAssignment_ReturnsNotFound(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	mockRepository.On("GetRoleAssignments", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "subject", Value: "jane"}}).Return(nil, nil)
+
+	actualErr := mockService.RemoveRole("the-binary", "jane")
+	assert.ErrorIs(t, actualErr, db.ErrNotFound)
+
+	mockRepository.AssertExpectations(t)
+}
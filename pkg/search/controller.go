@@ -0,0 +1,64 @@
+package search
+
+import (
+	"api/pkg/common"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Controller interface {
+	Search(c *gin.Context)
+}
+
+type controller struct {
+	searchService Service
+}
+
+func NewController(searchService Service) Controller {
+	return &controller{
+		searchService: searchService,
+	}
+}
+
+func (c *controller) Search(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	text := context.Query("q")
+	kind := context.Query("kind")
+
+	if text == "" {
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, "q is required", nil)
+		return
+	}
+	if kind != "" && kind != "template" && kind != "instance" {
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, "kind must be template or instance", nil)
+		return
+	}
+
+	limit := int64(defaultLimit)
+	if rawLimit := context.Query("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.ParseInt(rawLimit, 10, 64)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxLimit {
+			common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxLimit), nil)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	res, err := c.searchService.Search(tenantID, text, kind, limit)
+	if err != nil {
+		log.Println("error searching: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
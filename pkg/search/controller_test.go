@@ -0,0 +1,102 @@
+package search
+
+import (
+	"api/pkg/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Search(tenantId string, text string, kind string, limit int64) ([]models.SearchHit, error) {
+	args := m.Called(tenantId, text, kind, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SearchHit), args.Error(1)
+}
+
+func TestNewController(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		searchService: mockService,
+	}
+
+	newController := NewController(mockService)
+
+	assert.Equal(t, mockController, newController)
+}
+
+func TestController_Search_Success_ReturnsOk(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		searchService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", "/api/v1/tenants/the-binary/search?q=pump&limit=5", nil)
+	ctx.AddParam("tenantId", "the-binary")
+
+	mockService.On("Search", "the-binary", "pump", "", int64(5)).Return([]models.SearchHit{
+		{Kind: "instance", ExternalId: "pump1", Name: "Pump 1", Score: 3},
+	}, nil)
+
+	mockController.Search(ctx)
+
+	assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+	assert.Contains(t, w.Body.String(), "pump1")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_Search_MissingQuery_ReturnsBadRequest(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		searchService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", "/api/v1/tenants/the-binary/search", nil)
+	ctx.AddParam("tenantId", "the-binary")
+
+	mockController.Search(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+	assert.JSONEq(t, `{"error":{"code":"invalid_request","message":"q is required"}}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_Search_Fails_ReturnsInternalServerError(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		searchService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("GET", "/api/v1/tenants/the-binary/search?q=pump", nil)
+	ctx.AddParam("tenantId", "the-binary")
+
+	mockService.On("Search", "the-binary", "pump", "", int64(20)).Return(nil, errors.New("error searching"))
+
+	mockController.Search(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, ctx.Writer.Status())
+	assert.JSONEq(t, `{"error":{"code":"internal_error","message":"internal server error"}}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
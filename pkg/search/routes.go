@@ -0,0 +1,13 @@
+package search
+
+import (
+	"api/pkg/common"
+	"api/pkg/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, searchController Controller, authorizer common.Authorizer) {
+	reads := r.Group("", authorizer.Require(models.PermissionTemplatesRead), authorizer.Require(models.PermissionInstancesRead))
+	reads.GET("/api/v1/tenants/:tenantId/search", searchController.Search)
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"cmp"
+	"log"
+	"slices"
+)
+
+type Service interface {
+	Search(tenantId string, text string, kind string, limit int64) ([]models.SearchHit, error)
+}
+
+type service struct {
+	db db.Repository
+}
+
+func NewService(dbRepository db.Repository) Service {
+	return &service{
+		db: dbRepository,
+	}
+}
+
+var collectionsByKind = map[string]string{
+	"template": "templates",
+	"instance": "instances",
+}
+
+func (s *service) Search(tenantId string, text string, kind string, limit int64) ([]models.SearchHit, error) {
+	hits := make([]models.SearchHit, 0)
+	for _, k := range []string{"template", "instance"} {
+		if kind != "" && kind != k {
+			continue
+		}
+
+		results, err := s.db.Search(collectionsByKind[k], tenantId, text, limit)
+		if err != nil {
+			log.Printf("error searching %s: %s", collectionsByKind[k], err)
+			return nil, err
+		}
+		hits = append(hits, results...)
+	}
+
+	slices.SortStableFunc(hits, func(a, b models.SearchHit) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.ExternalId, b.ExternalId)
+	})
+
+	if int64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
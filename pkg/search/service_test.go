@@ -0,0 +1,75 @@
+package search
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewService(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+	newService := NewService(mockRepository)
+
+	assert.Equal(t, mockService, newService)
+}
+
+func TestService_Search_Success_ReturnsRankedHitsAcrossCollections(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("Search", "templates", "the-binary", "pump", int64(2)).Return([]models.SearchHit{
+		{Kind: "template", ExternalId: "pump", Name: "Pump", Score: 1.5},
+	}, nil)
+	mockRepository.On("Search", "instances", "the-binary", "pump", int64(2)).Return([]models.SearchHit{
+		{Kind: "instance", ExternalId: "pump2", Name: "Pump 2", Score: 0.5},
+		{Kind: "instance", ExternalId: "pump1", Name: "Pump 1", Score: 3},
+	}, nil)
+
+	actual, actualErr := mockService.Search("the-binary", "pump", "", 2)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []models.SearchHit{
+		{Kind: "instance", ExternalId: "pump1", Name: "Pump 1", Score: 3},
+		{Kind: "template", ExternalId: "pump", Name: "Pump", Score: 1.5},
+	}, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_Search_Kind_SearchesSingleCollection(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("Search", "instances", "the-binary", "pump", int64(20)).Return([]models.SearchHit{}, nil)
+
+	actual, actualErr := mockService.Search("the-binary", "pump", "instance", 20)
+	assert.Nil(t, actualErr)
+	assert.Empty(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_Search_Fails_ReturnsError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	expectedErr := errors.New("error searching")
+	mockRepository.On("Search", "templates", "the-binary", "pump", int64(20)).Return(nil, expectedErr)
+
+	actual, actualErr := mockService.Search("the-binary", "pump", "", 20)
+	assert.Equal(t, expectedErr, actualErr)
+	assert.Nil(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
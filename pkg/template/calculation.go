@@ -0,0 +1,26 @@
+package template
+
+import (
+	"api/pkg/calculation"
+	"api/pkg/db"
+	"api/pkg/models"
+	"log"
+)
+
+type relationshipRepository struct {
+	db db.Repository
+}
+
+func (r relationshipRepository) GetRelationships() ([]models.Relationship, error) {
+	relationships, err := r.db.GetRelationships(nil, "relationships")
+	if err != nil {
+		log.Println("error fetching relationships: ", err)
+		return nil, err
+	}
+
+	return relationships, nil
+}
+
+func (s *service) newCalculator(tenantId string) calculation.Calculator {
+	return calculation.NewCalculator(s.db, s, relationshipRepository{db: s.db}, tenantId)
+}
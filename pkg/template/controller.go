@@ -1,11 +1,14 @@
 package template
 
 import (
-	"api/pkg/db"
+	"api/pkg/audit"
+	"api/pkg/common"
 	"api/pkg/models"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +19,14 @@ type Controller interface {
 	GetTemplatesList(c *gin.Context)
 	GetTemplateById(c *gin.Context)
 	UpdateTemplateById(c *gin.Context)
+	DeleteTemplateById(c *gin.Context)
+	ExportTemplates(c *gin.Context)
+	ExportTemplatePackage(c *gin.Context)
+	ImportTemplatePackage(c *gin.Context)
+	GetTemplateVersions(c *gin.Context)
+	GetTemplateVersion(c *gin.Context)
+	DiffTemplateVersions(c *gin.Context)
+	RestoreTemplateVersion(c *gin.Context)
 }
 
 type controller struct {
@@ -34,17 +45,51 @@ func (c *controller) UpdateTemplateById(context *gin.Context) {
 	var templateToUpdate models.Template
 	if err := context.ShouldBindJSON(&templateToUpdate); err != nil {
 		log.Println("error parsing request body: ", err)
-		context.Status(http.StatusBadRequest)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
 		return
 	}
 
-	if err := c.templateService.UpdateTemplate(tenantID, templateToUpdate); err != nil {
+	expectedVersion, err := common.ParseIfMatch(context.GetHeader(common.HeaderIfMatch))
+	if err != nil {
+		log.Println("error parsing precondition: ", err)
+		common.RespondWithPreconditionError(context, err)
+		return
+	}
+
+	dryRun := context.Query("dryRun") == "true"
+	report, err := c.templateService.WithAuditContext(audit.ContextFrom(context)).UpdateTemplate(tenantID, templateToUpdate, expectedVersion, dryRun)
+	if err != nil {
 		log.Println("error updating template: ", err)
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Header(common.HeaderETag, common.ETag(report.Version))
+
+	context.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func (c *controller) DeleteTemplateById(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	templateID := context.Param("templateId")
+	force := context.Query("force") == "true"
+
+	dependents, err := c.templateService.WithAuditContext(audit.ContextFrom(context)).DeleteTemplate(tenantID, templateID, force)
+	if err != nil {
+		log.Println("error deleting template: ", err)
+		if errors.Is(err, ErrTemplateInUse) {
+			common.RespondWithError(context, http.StatusConflict, common.ErrorCodeConflict, err.Error(), gin.H{"blockers": dependents})
+			return
+		}
+		if errors.Is(err, ErrTemplateNotDeletable) {
+			common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+			return
+		}
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
-	context.Status(http.StatusOK)
+	context.Status(http.StatusNoContent)
 }
 
 func (c *controller) CreateTemplate(context *gin.Context) {
@@ -53,17 +98,13 @@ func (c *controller) CreateTemplate(context *gin.Context) {
 
 	if err := context.ShouldBindJSON(&templateToAdd); err != nil {
 		log.Println("error parsing request body: ", err)
-		context.Status(http.StatusBadRequest)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
 		return
 	}
 
-	if err := c.templateService.AddTemplate(tenantID, templateToAdd); err != nil {
+	if err := c.templateService.WithAuditContext(audit.ContextFrom(context)).AddTemplate(tenantID, templateToAdd); err != nil {
 		log.Println("error adding template: ", err)
-		if errors.Is(err, db.ErrDuplicateExternalId) {
-			context.Status(http.StatusConflict)
-			return
-		}
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
@@ -76,7 +117,7 @@ func (c *controller) GetParentTemplates(context *gin.Context) {
 	res, err := c.templateService.GetParentTemplates(tenantID)
 	if err != nil {
 		log.Println("error getting parent templates: ", err)
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
@@ -86,14 +127,28 @@ func (c *controller) GetParentTemplates(context *gin.Context) {
 func (c *controller) GetTemplatesList(context *gin.Context) {
 	tenantID := context.Param("tenantId")
 
-	res, err := c.templateService.GetTemplates(tenantID)
+	query, err := common.ParseListQuery(context.Request.URL.Query())
+	if err != nil {
+		log.Println("error parsing list query: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+	for _, attribute := range query.Attributes {
+		if attribute.Value != "" {
+			log.Println("error parsing list query: template attributes have no values")
+			common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, "templates can only be filtered by attribute id", nil)
+			return
+		}
+	}
+
+	res, err := c.templateService.GetTemplates(tenantID, query)
 	if err != nil {
 		log.Println("error getting templates: ", err)
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"data": res})
+	context.JSON(http.StatusOK, res)
 }
 
 func (c *controller) GetTemplateById(context *gin.Context) {
@@ -103,9 +158,157 @@ func (c *controller) GetTemplateById(context *gin.Context) {
 	res, err := c.templateService.GetTemplate(tenantID, templateID)
 	if err != nil {
 		log.Println("error getting templates: ", err)
-		context.Status(http.StatusInternalServerError)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Header(common.HeaderETag, common.ETag(res.Version))
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) ExportTemplates(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+
+	writer := common.NewStreamWriter(context, common.ContentTypeNDJSON, "templates.ndjson")
+	if err := c.templateService.ExportTemplates(tenantID, writer); err != nil {
+		log.Println("error exporting templates: ", err)
+		if !context.Writer.Written() {
+			common.RespondWithServiceError(context, err)
+		}
+		return
+	}
+
+	writer.Close()
+}
+
+func (c *controller) ExportTemplatePackage(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	templateID := context.Param("templateId")
+
+	res, err := c.templateService.ExportTemplatePackage(tenantID, templateID)
+	if err != nil {
+		log.Println("error exporting template package: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", res.Root+".package.json"))
+	context.JSON(http.StatusOK, res)
+}
+
+func (c *controller) ImportTemplatePackage(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	var templatePackage models.TemplatePackage
+
+	if err := context.ShouldBindJSON(&templatePackage); err != nil {
+		log.Println("error parsing request body: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	importOptions := models.PackageImportOptions{
+		Strategy: strings.ToLower(context.DefaultQuery("strategy", models.ConflictStrategySkip)),
+		Parent:   context.Query("parent"),
+		DryRun:   context.Query("dryRun") == "true",
+	}
+	res, err := c.templateService.WithAuditContext(audit.ContextFrom(context)).ImportTemplatePackage(tenantID, templatePackage, importOptions)
+	if err != nil {
+		log.Println("error importing template package: ", err)
+		common.RespondWithServiceError(context, err)
 		return
 	}
 
 	context.JSON(http.StatusOK, gin.H{"data": res})
 }
+
+func (c *controller) GetTemplateVersions(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	templateID := context.Param("templateId")
+
+	res, err := c.templateService.GetTemplateVersions(tenantID, templateID)
+	if err != nil {
+		log.Println("error getting template versions: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) GetTemplateVersion(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	templateID := context.Param("templateId")
+
+	version, err := common.ParseVersion("version", context.Param("version"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	res, err := c.templateService.GetTemplateVersion(tenantID, templateID, version)
+	if err != nil {
+		log.Println("error getting template version: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) DiffTemplateVersions(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	templateID := context.Param("templateId")
+
+	from, err := common.ParseVersion("from", context.Query("from"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+	to, err := common.ParseVersion("to", context.Query("to"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	res, err := c.templateService.DiffTemplateVersions(tenantID, templateID, from, to)
+	if err != nil {
+		log.Println("error diffing template versions: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
+
+func (c *controller) RestoreTemplateVersion(context *gin.Context) {
+	tenantID := context.Param("tenantId")
+	templateID := context.Param("templateId")
+
+	version, err := common.ParseVersion("version", context.Param("version"))
+	if err != nil {
+		log.Println("error parsing version: ", err)
+		common.RespondWithError(context, http.StatusBadRequest, common.ErrorCodeInvalidRequest, err.Error(), nil)
+		return
+	}
+
+	expectedVersion, err := common.ParseIfMatch(context.GetHeader(common.HeaderIfMatch))
+	if err != nil {
+		log.Println("error parsing precondition: ", err)
+		common.RespondWithPreconditionError(context, err)
+		return
+	}
+
+	res, err := c.templateService.WithAuditContext(audit.ContextFrom(context)).RestoreTemplateVersion(tenantID, templateID, version, expectedVersion)
+	if err != nil {
+		log.Println("error restoring template version: ", err)
+		common.RespondWithServiceError(context, err)
+		return
+	}
+
+	context.Header(common.HeaderETag, common.ETag(res.Version))
+
+	context.JSON(http.StatusOK, gin.H{"data": res})
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -201,6 +202,7 @@ func TestController_UpdateTemplateById_Success(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = &http.Request{
 		Header: make(http.Header),
+		URL:    &url.URL{},
 	}
 	ctx.Request.Header.Set("Content-Type", "application/json")
 	ctx.Request.Method = "PUT"
@@ -222,12 +224,14 @@ func TestController_UpdateTemplateById_Success(t *testing.T) {
 		panic(err)
 	}
 	ctx.Request.Body = io.NopCloser(bytes.NewBuffer(jsonBytes))
+	ctx.Request.Header.Set("If-Match", `"3"`)
 
-	mockService.On("UpdateTemplate", mock.AnythingOfType("string"), mock.AnythingOfType("models.Template")).Return(nil)
+	mockService.On("UpdateTemplate", mock.AnythingOfType("string"), mock.AnythingOfType("models.Template"), int64(3), false).Return(&models.SchemaMigrationReport{Version: 4}, nil)
 
 	mockController.UpdateTemplateById(ctx)
 
 	assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
 
 	mockService.AssertExpectations(t)
 }
@@ -243,6 +247,7 @@ func TestController_UpdateTemplateById_FailsToParseRequestBody_ReturnsBadRequest
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = &http.Request{
 		Header: make(http.Header),
+		URL:    &url.URL{},
 	}
 	ctx.Request.Header.Set("Content-Type", "application/json")
 	ctx.Request.Method = "PUT"
@@ -283,6 +288,7 @@ func TestController_UpdateTemplateById_FailsToUpdateTemplate_ReturnsInternalServ
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = &http.Request{
 		Header: make(http.Header),
+		URL:    &url.URL{},
 	}
 	ctx.Request.Header.Set("Content-Type", "application/json")
 	ctx.Request.Method = "PUT"
@@ -305,9 +311,10 @@ func TestController_UpdateTemplateById_FailsToUpdateTemplate_ReturnsInternalServ
 		panic(err)
 	}
 	ctx.Request.Body = io.NopCloser(bytes.NewBuffer(jsonBytes))
+	ctx.Request.Header.Set("If-Match", "*")
 
 	expectedErr := errors.New("error updating new template")
-	mockService.On("UpdateTemplate", mock.AnythingOfType("string"), mock.AnythingOfType("models.Template")).Return(expectedErr)
+	mockService.On("UpdateTemplate", mock.AnythingOfType("string"), mock.AnythingOfType("models.Template"), int64(0), false).Return(nil, expectedErr)
 
 	mockController.UpdateTemplateById(ctx)
 
@@ -315,6 +322,110 @@ func TestController_UpdateTemplateById_FailsToUpdateTemplate_ReturnsInternalServ
 	mockService.AssertExpectations(t)
 }
 
+func TestController_UpdateTemplateById_MissingIfMatch_ReturnsPreconditionRequired(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{},
+	}
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Request.Method = "PUT"
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+	ctx.Request.Body = io.NopCloser(bytes.NewBufferString(`{"basicInformation":{"externalId":"testtemplate1"}}`))
+
+	mockController.UpdateTemplateById(ctx)
+
+	assert.Equal(t, http.StatusPreconditionRequired, ctx.Writer.Status())
+	mockService.AssertNotCalled(t, "UpdateTemplate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestController_UpdateTemplateById_StaleVersion_ReturnsPreconditionFailed(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{},
+	}
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Request.Header.Set("If-Match", `"2"`)
+	ctx.Request.Method = "PUT"
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+	ctx.Request.Body = io.NopCloser(bytes.NewBufferString(`{"basicInformation":{"externalId":"testtemplate1"}}`))
+
+	mockService.On("UpdateTemplate", "the-binary", mock.AnythingOfType("models.Template"), int64(2), false).Return(nil, db.ErrPreconditionFailed)
+
+	mockController.UpdateTemplateById(ctx)
+
+	assert.Equal(t, http.StatusPreconditionFailed, ctx.Writer.Status())
+	mockService.AssertExpectations(t)
+}
+
+func TestController_RestoreTemplateVersion_Success_ReturnsVersionETag(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+	}
+	ctx.Request.Method = "POST"
+	ctx.Request.Header.Set("If-Match", `"3"`)
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+	ctx.AddParam("version", "1")
+
+	mockService.On("RestoreTemplateVersion", "the-binary", "testtemplate1", int64(1), int64(3)).Return(&models.SchemaMigrationReport{Version: 4}, nil)
+
+	mockController.RestoreTemplateVersion(ctx)
+
+	assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_RestoreTemplateVersion_MissingIfMatch_ReturnsPreconditionRequired(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+	}
+	ctx.Request.Method = "POST"
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+	ctx.AddParam("version", "1")
+
+	mockController.RestoreTemplateVersion(ctx)
+
+	assert.Equal(t, http.StatusPreconditionRequired, ctx.Writer.Status())
+	mockService.AssertNotCalled(t, "RestoreTemplateVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestController_GetParentTemplates_Success(t *testing.T) {
 	mockService := &MockService{}
 	mockController := &controller{
@@ -387,6 +498,7 @@ func TestController_GetTemplatesList_Success(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = &http.Request{
 		Header: make(http.Header),
+		URL:    &url.URL{RawQuery: "limit=10&sort=-name"},
 	}
 	ctx.Request.Method = "GET"
 	ctx.AddParam("tenantId", "the-binary")
@@ -403,7 +515,9 @@ func TestController_GetTemplatesList_Success(t *testing.T) {
 		Metrics:    make([]models.TemplateMetric, 0),
 	}}
 
-	mockService.On("GetTemplates", mock.AnythingOfType("string")).Return(templatesResponse, nil)
+	mockService.On("GetTemplates", "the-binary", mock.MatchedBy(func(query models.ListQuery) bool {
+		return query.Limit == 10 && query.Sort == "name" && query.Descending
+	})).Return(&models.TemplatePage{Items: templatesResponse, Total: 1}, nil)
 
 	mockController.GetTemplatesList(ctx)
 
@@ -423,11 +537,12 @@ func TestController_GetTemplatesList_Fails_ReturnsInternalServerError(t *testing
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = &http.Request{
 		Header: make(http.Header),
+		URL:    &url.URL{RawQuery: "limit=10&sort=-name"},
 	}
 	ctx.Request.Method = "GET"
 	ctx.AddParam("tenantId", "the-binary")
 
-	mockService.On("GetTemplates", mock.AnythingOfType("string")).Return(nil, errors.New("error fetching templates"))
+	mockService.On("GetTemplates", mock.AnythingOfType("string"), mock.AnythingOfType("models.ListQuery")).Return(nil, errors.New("error fetching templates"))
 
 	mockController.GetTemplatesList(ctx)
 
@@ -436,6 +551,52 @@ func TestController_GetTemplatesList_Fails_ReturnsInternalServerError(t *testing
 	mockService.AssertExpectations(t)
 }
 
+func TestController_GetTemplatesList_InvalidQuery_ReturnsBadRequest(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{RawQuery: "sort=color"},
+	}
+	ctx.Request.Method = "GET"
+	ctx.AddParam("tenantId", "the-binary")
+
+	mockController.GetTemplatesList(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_GetTemplatesList_AttributeValueFilter_ReturnsBadRequest(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{RawQuery: "attribute=a1:42"},
+	}
+	ctx.Request.Method = "GET"
+	ctx.AddParam("tenantId", "the-binary")
+
+	mockController.GetTemplatesList(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+
+	mockService.AssertNotCalled(t, "GetTemplates", mock.Anything, mock.Anything)
+}
+
 func TestController_GetTemplatesById_Success(t *testing.T) {
 	mockService := &MockService{}
 	mockController := &controller{
@@ -497,3 +658,124 @@ func TestController_GetTemplateById_Fails_ReturnsInternalServerError(t *testing.
 
 	mockService.AssertExpectations(t)
 }
+
+func TestController_GetTemplateById_Success_ReturnsVersionETag(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+	}
+	ctx.Request.Method = "GET"
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+
+	mockService.On("GetTemplate", "the-binary", "testtemplate1").Return(&models.Template{Version: 4}, nil)
+
+	mockController.GetTemplateById(ctx)
+
+	assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_DeleteTemplateById_Success_ReturnsNoContent(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("DELETE", "/api/v1/tenants/the-binary/templates/testtemplate1?force=true", nil)
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+
+	mockService.On("DeleteTemplate", "the-binary", "testtemplate1", true).Return(nil, nil)
+
+	mockController.DeleteTemplateById(ctx)
+
+	assert.Equal(t, http.StatusNoContent, ctx.Writer.Status())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_DeleteTemplateById_TemplateInUse_ReturnsConflict(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("DELETE", "/api/v1/tenants/the-binary/templates/testtemplate1", nil)
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+
+	mockService.On("DeleteTemplate", "the-binary", "testtemplate1", false).Return(&models.TemplateDependents{
+		Templates: []string{"testtemplate2"},
+		Instances: []string{},
+	}, ErrTemplateInUse)
+
+	mockController.DeleteTemplateById(ctx)
+
+	assert.Equal(t, http.StatusConflict, ctx.Writer.Status())
+	assert.JSONEq(t, `{"error":{"code":"conflict","message":"template has child templates or instances","details":{"blockers":{"templates":["testtemplate2"],"instances":[]}}}}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_DeleteTemplateById_NotFound_ReturnsNotFound(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("DELETE", "/api/v1/tenants/the-binary/templates/testtemplate1", nil)
+	ctx.AddParam("tenantId", "the-binary")
+	ctx.AddParam("templateId", "testtemplate1")
+
+	mockService.On("DeleteTemplate", "the-binary", "testtemplate1", false).Return(nil, db.ErrNotFound)
+
+	mockController.DeleteTemplateById(ctx)
+
+	assert.Equal(t, http.StatusNotFound, ctx.Writer.Status())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_CreateTemplate_ValidationFails_ReturnsBadRequestWithDetails(t *testing.T) {
+	mockService := &MockService{}
+	mockController := &controller{
+		templateService: mockService,
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", "/api/v1/tenants/the-binary/templates", bytes.NewBufferString(`{"basicInformation":{"name":"Test Template 1"}}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.AddParam("tenantId", "the-binary")
+
+	validationErr := &models.ValidationError{}
+	validationErr.Add("attributes[0].name", "", models.ValidationCodeRequired, "attribute name is required but not provided")
+	mockService.On("AddTemplate", "the-binary", mock.AnythingOfType("models.Template")).Return(validationErr)
+
+	mockController.CreateTemplate(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+	assert.JSONEq(t, `{"error":{"code":"validation_failed","message":"validation failed","details":[{"field":"attributes[0].name","code":"required","message":"attribute name is required but not provided"}]}}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,118 @@
+package template
+
+import (
+	"api/pkg/models"
+	"encoding/json"
+	"io"
+	"log"
+	"slices"
+)
+
+func (s *service) ExportTemplates(tenantId string, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	resolved := make(map[string]models.Template)
+	err := s.db.StreamTemplateHierarchy(tenantId, func(template models.Template) error {
+		var parent *models.Template
+		if parentTemplate, ok := resolved[template.BasicInformation.Parent]; ok {
+			parent = &parentTemplate
+		}
+		template = withOwningTemplates(template, parent)
+		resolved[template.BasicInformation.ExternalID] = template
+		return encoder.Encode(ownedEntries(template))
+	})
+	if err != nil {
+		log.Println("error exporting templates: ", err)
+		return err
+	}
+
+	return nil
+}
+
+// withOwningTemplates fills in the owner of entries stored before templates tracked
+// ownership. An entry the parent also has is inherited from the parent's owner, so
+// parent must already be resolved.
+func withOwningTemplates(template models.Template, parent *models.Template) models.Template {
+	attributes := slices.Clone(template.Attributes)
+	for i, attribute := range attributes {
+		if attribute.OwningTemplate != "" {
+			continue
+		}
+		attributes[i].OwningTemplate = template.BasicInformation.ExternalID
+		if parent == nil {
+			continue
+		}
+		if parentIndex := slices.IndexFunc(parent.Attributes, func(a models.TemplateAttribute) bool {
+			return a.ID == attribute.ID || a.Name == attribute.Name
+		}); parentIndex != -1 {
+			attributes[i].OwningTemplate = parent.Attributes[parentIndex].OwningTemplate
+		}
+	}
+
+	metrics := slices.Clone(template.Metrics)
+	for i, metric := range metrics {
+		if metric.OwningTemplate != "" {
+			continue
+		}
+		metrics[i].OwningTemplate = template.BasicInformation.ExternalID
+		if parent == nil {
+			continue
+		}
+		if parentIndex := slices.IndexFunc(parent.Metrics, func(m models.TemplateMetric) bool {
+			return m.ID == metric.ID || m.Name == metric.Name
+		}); parentIndex != -1 {
+			metrics[i].OwningTemplate = parent.Metrics[parentIndex].OwningTemplate
+		}
+	}
+
+	template.Attributes = attributes
+	template.Metrics = metrics
+	return template
+}
+
+// resolveOwningTemplates applies withOwningTemplates to templates, parents first.
+func resolveOwningTemplates(templates []models.Template) map[string]models.Template {
+	resolved := make(map[string]models.Template, len(templates))
+	var resolve func(template models.Template) models.Template
+	resolve = func(template models.Template) models.Template {
+		if resolvedTemplate, ok := resolved[template.BasicInformation.ExternalID]; ok {
+			return resolvedTemplate
+		}
+		var parent *models.Template
+		if parentIndex := slices.IndexFunc(templates, func(t models.Template) bool {
+			return t.BasicInformation.ExternalID == template.BasicInformation.Parent
+		}); parentIndex != -1 {
+			parentTemplate := resolve(templates[parentIndex])
+			parent = &parentTemplate
+		}
+		resolvedTemplate := withOwningTemplates(template, parent)
+		resolved[template.BasicInformation.ExternalID] = resolvedTemplate
+		return resolvedTemplate
+	}
+	for _, template := range templates {
+		resolve(template)
+	}
+	return resolved
+}
+
+func ownedEntries(template models.Template) models.Template {
+	isOwned := func(owningTemplate string) bool {
+		return owningTemplate == template.BasicInformation.ExternalID
+	}
+
+	attributes := make([]models.TemplateAttribute, 0, len(template.Attributes))
+	for _, attribute := range template.Attributes {
+		if isOwned(attribute.OwningTemplate) {
+			attributes = append(attributes, attribute)
+		}
+	}
+	metrics := make([]models.TemplateMetric, 0, len(template.Metrics))
+	for _, metric := range template.Metrics {
+		if isOwned(metric.OwningTemplate) {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	template.Attributes = attributes
+	template.Metrics = metrics
+	return template
+}
@@ -0,0 +1,70 @@
+package template
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_ExportTemplates_Success_WritesOwnedEntriesOnly(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	mockRepository.On("StreamTemplateHierarchy", "the-binary").Return([]models.Template{
+		{
+			BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.building"},
+			Attributes:       []models.TemplateAttribute{{ID: "address", Name: "address", OwningTemplate: "p.com.building"}},
+		},
+		{
+			BasicInformation: models.TemplateBasicInformation{ExternalID: "c.office", Parent: "p.com.building", IsCustom: true},
+			Attributes: []models.TemplateAttribute{
+				{ID: "address", Name: "address", OwningTemplate: "p.com.building"},
+				{ID: "desks", Name: "desks", OwningTemplate: "c.office"},
+			},
+		},
+	}, nil)
+
+	var actual bytes.Buffer
+	actualErr := mockService.ExportTemplates("the-binary", &actual)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, `{"basicInformation":{"name":"","parent":"","externalId":"p.com.building","isCustom":false,"rootTemplate":""},"attributes":[{"id":"address","name":"address","dataType":"","isRequired":false,"isHidden":false,"owningTemplate":"p.com.building"}],"metrics":[]}
+{"basicInformation":{"name":"","parent":"p.com.building","externalId":"c.office","isCustom":true,"rootTemplate":""},"attributes":[{"id":"desks","name":"desks","dataType":"","isRequired":false,"isHidden":false,"owningTemplate":"c.office"}],"metrics":[]}
+`, actual.String())
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ExportTemplates_LegacyTemplates_ResolvesOwnershipFromParents(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	mockRepository.On("StreamTemplateHierarchy", "the-binary").Return([]models.Template{
+		{
+			BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.building"},
+			Attributes:       []models.TemplateAttribute{{ID: "address", Name: "address"}},
+		},
+		{
+			BasicInformation: models.TemplateBasicInformation{ExternalID: "c.office", Parent: "p.com.building", IsCustom: true},
+			Attributes:       []models.TemplateAttribute{{ID: "address", Name: "address"}, {ID: "desks", Name: "desks"}},
+			Metrics:          []models.TemplateMetric{{ID: "occupancy", Name: "occupancy"}},
+		},
+		{
+			BasicInformation: models.TemplateBasicInformation{ExternalID: "c.corner-office", Parent: "c.office", IsCustom: true},
+			Attributes:       []models.TemplateAttribute{{ID: "address", Name: "address"}, {ID: "desks", Name: "desks"}},
+			Metrics:          []models.TemplateMetric{{ID: "occupancy", Name: "occupancy"}},
+		},
+	}, nil)
+
+	var actual bytes.Buffer
+	actualErr := mockService.ExportTemplates("the-binary", &actual)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, `{"basicInformation":{"name":"","parent":"","externalId":"p.com.building","isCustom":false,"rootTemplate":""},"attributes":[{"id":"address","name":"address","dataType":"","isRequired":false,"isHidden":false,"owningTemplate":"p.com.building"}],"metrics":[]}
+{"basicInformation":{"name":"","parent":"p.com.building","externalId":"c.office","isCustom":true,"rootTemplate":""},"attributes":[{"id":"desks","name":"desks","dataType":"","isRequired":false,"isHidden":false,"owningTemplate":"c.office"}],"metrics":[{"id":"occupancy","name":"occupancy","metricType":"","unit":"","isManual":false,"value":null,"isCalculated":false,"isSourced":false,"owningTemplate":"c.office"}]}
+{"basicInformation":{"name":"","parent":"c.office","externalId":"c.corner-office","isCustom":true,"rootTemplate":""},"attributes":[],"metrics":[]}
+`, actual.String())
+
+	mockRepository.AssertExpectations(t)
+}
@@ -0,0 +1,82 @@
+package template
+
+import (
+	"api/pkg/audit"
+	"api/pkg/db"
+	"api/pkg/models"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *service) GetTemplateVersions(tenantId string, templateId string) ([]models.VersionSummary, error) {
+	template, err := s.GetTemplate(tenantId, templateId)
+	if err != nil {
+		log.Println("error getting template: ", err)
+		return nil, err
+	}
+
+	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: template.BasicInformation.ExternalID}}
+	revisions, err := s.db.GetTemplateRevisions(filter)
+	if err != nil {
+		log.Println("error getting template revisions: ", err)
+		return nil, err
+	}
+
+	versions := []models.VersionSummary{{Version: max(template.Version, models.InitialVersion), Current: true}}
+	for _, revision := range revisions {
+		versions = append(versions, models.VersionSummary{Version: revision.Version, ArchivedAt: &revision.ArchivedAt})
+	}
+	return versions, nil
+}
+
+func (s *service) GetTemplateVersion(tenantId string, templateId string, version int64) (*models.Template, error) {
+	template, err := s.GetTemplate(tenantId, templateId)
+	if err != nil {
+		log.Println("error getting template: ", err)
+		return nil, err
+	}
+	if version == max(template.Version, models.InitialVersion) {
+		return template, nil
+	}
+
+	filter := bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "basicInformation.externalId", Value: template.BasicInformation.ExternalID},
+		{Key: "version", Value: version},
+	}
+	revisions, err := s.db.GetTemplateRevisions(filter)
+	if err != nil {
+		log.Println("error getting template revisions: ", err)
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("%w: template %s has no version %d", db.ErrNotFound, template.BasicInformation.ExternalID, version)
+	}
+
+	return &revisions[0].Template, nil
+}
+
+func (s *service) DiffTemplateVersions(tenantId string, templateId string, from int64, to int64) (*models.VersionDiff, error) {
+	fromTemplate, err := s.GetTemplateVersion(tenantId, templateId, from)
+	if err != nil {
+		return nil, err
+	}
+	toTemplate, err := s.GetTemplateVersion(tenantId, templateId, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VersionDiff{From: from, To: to, Changes: audit.Diff(fromTemplate, toTemplate)}, nil
+}
+
+func (s *service) RestoreTemplateVersion(tenantId string, templateId string, version int64, expectedVersion int64) (*models.SchemaMigrationReport, error) {
+	template, err := s.GetTemplateVersion(tenantId, templateId, version)
+	if err != nil {
+		log.Println("error getting template version: ", err)
+		return nil, err
+	}
+
+	return s.UpdateTemplate(tenantId, *template, expectedVersion, false)
+}
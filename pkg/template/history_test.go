@@ -0,0 +1,140 @@
+package template
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newVersionedTemplate(name string, version int64) models.Template {
+	return models.Template{
+		TenantID: "the-binary",
+		BasicInformation: models.TemplateBasicInformation{
+			Name:       name,
+			Parent:     "p.com.asset",
+			ExternalID: "c.pump",
+			IsCustom:   true,
+		},
+		Attributes: []models.TemplateAttribute{{ID: "flow", Name: "flow", DataType: "integer", OwningTemplate: "c.pump"}},
+		Metrics:    []models.TemplateMetric{},
+		Version:    version,
+	}
+}
+
+func TestService_GetTemplateVersions_Success_ListsCurrentAndArchivedVersions(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	current := newVersionedTemplate("Pump", 3)
+	archivedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetTemplateRevisions", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "c.pump"}}).Return([]models.TemplateRevision{
+		{Template: newVersionedTemplate("Pump", 2), ArchivedAt: archivedAt},
+		{Template: newVersionedTemplate("Pump", 1), ArchivedAt: archivedAt},
+	}, nil)
+
+	actual, actualErr := mockService.GetTemplateVersions("the-binary", "c.pump")
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []models.VersionSummary{
+		{Version: 3, Current: true},
+		{Version: 2, ArchivedAt: &archivedAt},
+		{Version: 1, ArchivedAt: &archivedAt},
+	}, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DiffTemplateVersions_Success_ReturnsChanges(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	current := newVersionedTemplate("Pump", 2)
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetTemplateRevisions", bson.D{{Key: "tenantId", Value: "the-binary"}, {Key: "basicInformation.externalId", Value: "c.pump"}, {Key: "version", Value: int64(1)}}).Return([]models.TemplateRevision{
+		{Template: newVersionedTemplate("Water pump", 1)},
+	}, nil)
+
+	actual, actualErr := mockService.DiffTemplateVersions("the-binary", "c.pump", 1, 2)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, &models.VersionDiff{
+		From:    1,
+		To:      2,
+		Changes: []models.AuditChange{{Path: "basicInformation.name", Before: "Water pump", After: "Pump"}},
+	}, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_GetTemplateVersion_UnknownVersion_ReturnsNotFound(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	current := newVersionedTemplate("Pump", 2)
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetTemplateRevisions", mock.AnythingOfType("primitive.D")).Return([]models.TemplateRevision{}, nil)
+
+	actual, actualErr := mockService.GetTemplateVersion("the-binary", "c.pump", 7)
+	assert.Nil(t, actual)
+	assert.ErrorIs(t, actualErr, db.ErrNotFound)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RestoreTemplateVersion_InvalidRevision_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	current := newVersionedTemplate("Pump", 2)
+	invalid := newVersionedTemplate("Pump", 1)
+	invalid.Attributes[0].Name = ""
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetTemplateRevisions", mock.AnythingOfType("primitive.D")).Return([]models.TemplateRevision{{Template: invalid}}, nil)
+
+	actual, actualErr := mockService.RestoreTemplateVersion("the-binary", "c.pump", 1, 2)
+	assert.Nil(t, actual)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	mockRepository.AssertNotCalled(t, "ReplaceTemplate", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RestoreTemplateVersion_Success_UpdatesWithRevision(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	current := newVersionedTemplate("Pump", 2)
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetTemplateRevisions", mock.AnythingOfType("primitive.D")).Return([]models.TemplateRevision{{Template: newVersionedTemplate("Water pump", 1)}}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{current}, nil)
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(template models.Template) bool {
+		return template.BasicInformation.Name == "Water pump"
+	})).Return(int64(2), nil)
+
+	actual, actualErr := mockService.RestoreTemplateVersion("the-binary", "c.pump", 1, 2)
+	assert.Nil(t, actualErr)
+	assert.False(t, actual.DryRun)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RestoreTemplateVersion_StaleVersion_ReturnsPreconditionFailed(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	current := newVersionedTemplate("Pump", 3)
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&current, nil)
+	mockRepository.On("GetTemplateRevisions", mock.AnythingOfType("primitive.D")).Return([]models.TemplateRevision{{Template: newVersionedTemplate("Water pump", 1)}}, nil)
+
+	actual, actualErr := mockService.RestoreTemplateVersion("the-binary", "c.pump", 1, 2)
+	assert.Nil(t, actual)
+	assert.ErrorIs(t, actualErr, db.ErrPreconditionFailed)
+
+	mockRepository.AssertNotCalled(t, "ReplaceTemplate", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
@@ -0,0 +1,208 @@
+package template
+
+import (
+	"api/pkg/models"
+	"fmt"
+	"slices"
+)
+
+type templateRevision struct {
+	previous models.Template
+	current  models.Template
+}
+
+func diffTemplateSchema(previous models.Template, current models.Template) models.TemplateSchemaDiff {
+	diff := models.TemplateSchemaDiff{
+		AddedAttributes:         make([]string, 0),
+		RemovedAttributes:       make([]string, 0),
+		RetypedAttributes:       make([]string, 0),
+		NewlyRequiredAttributes: make([]string, 0),
+		AddedMetrics:            make([]string, 0),
+		RemovedMetrics:          make([]string, 0),
+		RetypedMetrics:          make([]string, 0),
+		ConstrainedAttributes:   make([]string, 0),
+		ConstrainedMetrics:      make([]string, 0),
+	}
+
+	for _, attribute := range current.Attributes {
+		previousIndex := slices.IndexFunc(previous.Attributes, func(a models.TemplateAttribute) bool {
+			return a.ID == attribute.ID
+		})
+		if previousIndex == -1 {
+			diff.AddedAttributes = append(diff.AddedAttributes, attribute.ID)
+			if attribute.IsRequired {
+				diff.NewlyRequiredAttributes = append(diff.NewlyRequiredAttributes, attribute.ID)
+			}
+			continue
+		}
+
+		previousAttribute := previous.Attributes[previousIndex]
+		if previousAttribute.DataType != attribute.DataType || (attribute.DataType == "enum" && !slices.Equal(previousAttribute.EnumValues, attribute.EnumValues)) {
+			diff.RetypedAttributes = append(diff.RetypedAttributes, attribute.ID)
+		}
+		if attribute.IsRequired && !previousAttribute.IsRequired {
+			diff.NewlyRequiredAttributes = append(diff.NewlyRequiredAttributes, attribute.ID)
+		}
+		if !previousAttribute.Constraints.Equal(attribute.Constraints) {
+			diff.ConstrainedAttributes = append(diff.ConstrainedAttributes, attribute.ID)
+		}
+	}
+	for _, attribute := range previous.Attributes {
+		if !slices.ContainsFunc(current.Attributes, func(a models.TemplateAttribute) bool {
+			return a.ID == attribute.ID
+		}) {
+			diff.RemovedAttributes = append(diff.RemovedAttributes, attribute.ID)
+		}
+	}
+
+	for _, metric := range current.Metrics {
+		previousIndex := slices.IndexFunc(previous.Metrics, func(m models.TemplateMetric) bool {
+			return m.ID == metric.ID
+		})
+		if previousIndex == -1 {
+			diff.AddedMetrics = append(diff.AddedMetrics, metric.ID)
+			continue
+		}
+		if previous.Metrics[previousIndex].MetricType != metric.MetricType {
+			diff.RetypedMetrics = append(diff.RetypedMetrics, metric.ID)
+		}
+		if !previous.Metrics[previousIndex].Constraints.Equal(metric.Constraints) {
+			diff.ConstrainedMetrics = append(diff.ConstrainedMetrics, metric.ID)
+		}
+	}
+	for _, metric := range previous.Metrics {
+		if !slices.ContainsFunc(current.Metrics, func(m models.TemplateMetric) bool {
+			return m.ID == metric.ID
+		}) {
+			diff.RemovedMetrics = append(diff.RemovedMetrics, metric.ID)
+		}
+	}
+
+	return diff
+}
+
+// formulasChanged reports whether current adds a calculated metric or changes the
+// formula of one, in which case the values stored on instances are stale.
+func formulasChanged(previous models.Template, current models.Template) bool {
+	return slices.ContainsFunc(current.Metrics, func(metric models.TemplateMetric) bool {
+		previousIndex := slices.IndexFunc(previous.Metrics, func(m models.TemplateMetric) bool {
+			return m.ID == metric.ID
+		})
+		if previousIndex == -1 {
+			return metric.Formula != ""
+		}
+		return previous.Metrics[previousIndex].Formula != metric.Formula
+	})
+}
+
+func migrateInstance(instance *models.Instance, template models.Template, diff models.TemplateSchemaDiff) (bool, []string) {
+	changed := false
+	reasons := make([]string, 0)
+
+	attributes := make([]models.InstanceAttribute, 0, len(instance.Attributes))
+	for _, attribute := range instance.Attributes {
+		if slices.Contains(diff.RemovedAttributes, attribute.ID) {
+			changed = true
+			continue
+		}
+
+		retyped := slices.Contains(diff.RetypedAttributes, attribute.ID)
+		if retyped || slices.Contains(diff.ConstrainedAttributes, attribute.ID) {
+			templateAttribute := template.Attributes[slices.IndexFunc(template.Attributes, func(a models.TemplateAttribute) bool {
+				return a.ID == attribute.ID
+			})]
+			value, err := models.ConvertAttributeValue(templateAttribute, attribute.Value)
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("attribute %s: %s", templateAttribute.Name, err))
+			} else if retyped {
+				attribute.Value = value
+				changed = true
+			}
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	for _, attributeId := range append(slices.Clone(diff.AddedAttributes), diff.NewlyRequiredAttributes...) {
+		templateAttribute := template.Attributes[slices.IndexFunc(template.Attributes, func(a models.TemplateAttribute) bool {
+			return a.ID == attributeId
+		})]
+		if !templateAttribute.Constraints.HasDefault() {
+			continue
+		}
+		attributeIndex := slices.IndexFunc(attributes, func(a models.InstanceAttribute) bool {
+			return a.ID == attributeId
+		})
+		if attributeIndex != -1 && models.ValueString(attributes[attributeIndex].Value) != "" {
+			continue
+		}
+		value, err := models.ConvertAttributeValue(templateAttribute, templateAttribute.Constraints.Default)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("attribute %s: %s", templateAttribute.Name, err))
+			continue
+		}
+		if attributeIndex == -1 {
+			attributes = append(attributes, models.InstanceAttribute{ID: attributeId, Value: value})
+		} else {
+			attributes[attributeIndex].Value = value
+		}
+		changed = true
+	}
+
+	for _, attributeId := range diff.NewlyRequiredAttributes {
+		if slices.ContainsFunc(attributes, func(a models.InstanceAttribute) bool {
+			return a.ID == attributeId && a.Value != nil && a.Value != ""
+		}) {
+			continue
+		}
+		templateAttribute := template.Attributes[slices.IndexFunc(template.Attributes, func(a models.TemplateAttribute) bool {
+			return a.ID == attributeId
+		})]
+		reasons = append(reasons, fmt.Sprintf("attribute %s is required but not provided", templateAttribute.Name))
+	}
+
+	metrics := make([]models.InstanceMetric, 0, len(instance.Metrics))
+	for _, metric := range instance.Metrics {
+		if slices.Contains(diff.RemovedMetrics, metric.ID) {
+			changed = true
+			continue
+		}
+
+		retyped := slices.Contains(diff.RetypedMetrics, metric.ID)
+		if (retyped || slices.Contains(diff.ConstrainedMetrics, metric.ID)) && metric.MetricBehaviour == "Manual" {
+			templateMetric := template.Metrics[slices.IndexFunc(template.Metrics, func(m models.TemplateMetric) bool {
+				return m.ID == metric.ID
+			})]
+			value, err := models.ConvertMetricValue(templateMetric, metric.Value)
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("metric %s: %s", templateMetric.Name, err))
+			} else if retyped {
+				metric.Value = value
+				changed = true
+			}
+		}
+		metrics = append(metrics, metric)
+	}
+
+	for _, metricId := range diff.AddedMetrics {
+		templateMetric := template.Metrics[slices.IndexFunc(template.Metrics, func(m models.TemplateMetric) bool {
+			return m.ID == metricId
+		})]
+		if !templateMetric.IsManual || templateMetric.Value == nil || slices.ContainsFunc(metrics, func(m models.InstanceMetric) bool {
+			return m.ID == metricId
+		}) {
+			continue
+		}
+		metrics = append(metrics, models.InstanceMetric{
+			ID:              templateMetric.ID,
+			MetricBehaviour: "Manual",
+			Value:           templateMetric.Value,
+		})
+		changed = true
+	}
+
+	if changed {
+		instance.Attributes = attributes
+		instance.Metrics = metrics
+	}
+	return changed, reasons
+}
@@ -2,6 +2,7 @@ package template
 
 import (
 	"api/pkg/models"
+	"io"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -15,12 +16,12 @@ func (m *MockService) AddTemplate(tenantId string, template models.Template) err
 	return args.Error(0)
 }
 
-func (m *MockService) GetTemplates(tenantId string) ([]models.Template, error) {
-	args := m.Called(tenantId)
+func (m *MockService) GetTemplates(tenantId string, query models.ListQuery) (*models.TemplatePage, error) {
+	args := m.Called(tenantId, query)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]models.Template), args.Error(1)
+	return args.Get(0).(*models.TemplatePage), args.Error(1)
 }
 
 func (m *MockService) GetTemplate(tenantId string, templateId string) (*models.Template, error) {
@@ -39,7 +40,83 @@ func (m *MockService) GetParentTemplates(tenantId string) ([]models.ParentTempla
 	return args.Get(0).([]models.ParentTemplateDropdown), args.Error(1)
 }
 
-func (m *MockService) UpdateTemplate(tenantId string, template models.Template) error {
-	args := m.Called(tenantId, template)
+func (m *MockService) UpdateTemplate(tenantId string, template models.Template, expectedVersion int64, dryRun bool) (*models.SchemaMigrationReport, error) {
+	args := m.Called(tenantId, template, expectedVersion, dryRun)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SchemaMigrationReport), args.Error(1)
+}
+
+func (m *MockService) DeleteTemplate(tenantId string, templateId string, force bool) (*models.TemplateDependents, error) {
+	args := m.Called(tenantId, templateId, force)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TemplateDependents), args.Error(1)
+}
+
+func (m *MockService) GetDescendantTemplates(tenantId string, templateId string) ([]models.Template, error) {
+	args := m.Called(tenantId, templateId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Template), args.Error(1)
+}
+
+func (m *MockService) ExportTemplates(tenantId string, w io.Writer) error {
+	args := m.Called(tenantId, w)
 	return args.Error(0)
 }
+
+func (m *MockService) ExportTemplatePackage(tenantId string, templateId string) (*models.TemplatePackage, error) {
+	args := m.Called(tenantId, templateId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TemplatePackage), args.Error(1)
+}
+
+func (m *MockService) ImportTemplatePackage(tenantId string, templatePackage models.TemplatePackage, importOptions models.PackageImportOptions) (*models.PackageImportReport, error) {
+	args := m.Called(tenantId, templatePackage, importOptions)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PackageImportReport), args.Error(1)
+}
+
+func (m *MockService) GetTemplateVersions(tenantId string, templateId string) ([]models.VersionSummary, error) {
+	args := m.Called(tenantId, templateId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.VersionSummary), args.Error(1)
+}
+
+func (m *MockService) GetTemplateVersion(tenantId string, templateId string, version int64) (*models.Template, error) {
+	args := m.Called(tenantId, templateId, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Template), args.Error(1)
+}
+
+func (m *MockService) DiffTemplateVersions(tenantId string, templateId string, from int64, to int64) (*models.VersionDiff, error) {
+	args := m.Called(tenantId, templateId, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.VersionDiff), args.Error(1)
+}
+
+func (m *MockService) RestoreTemplateVersion(tenantId string, templateId string, version int64, expectedVersion int64) (*models.SchemaMigrationReport, error) {
+	args := m.Called(tenantId, templateId, version, expectedVersion)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SchemaMigrationReport), args.Error(1)
+}
+
+func (m *MockService) WithAuditContext(auditContext models.AuditContext) Service {
+	return m
+}
@@ -0,0 +1,347 @@
+package template
+
+import (
+	"api/pkg/db"
+	"api/pkg/formula"
+	"api/pkg/models"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *service) ExportTemplatePackage(tenantId string, templateId string) (*models.TemplatePackage, error) {
+	templateId = strings.ToLower(templateId)
+	templates, err := s.db.GetAllTemplates(bson.D{{Key: "tenantId", Value: tenantId}}, nil)
+	if err != nil {
+		log.Println("error fetching all templates: ", err)
+		return nil, err
+	}
+
+	rootIndex := slices.IndexFunc(templates, func(t models.Template) bool {
+		return t.BasicInformation.ExternalID == templateId
+	})
+	if rootIndex == -1 {
+		log.Println("error getting template: ", db.ErrNotFound)
+		return nil, db.ErrNotFound
+	}
+
+	resolved := resolveOwningTemplates(templates)
+	packageTemplates := []models.Template{ownedEntries(resolved[templateId])}
+	for _, descendant := range descendantTemplates(templates, templateId) {
+		packageTemplates = append(packageTemplates, ownedEntries(resolved[descendant.BasicInformation.ExternalID]))
+	}
+
+	relationships, err := s.packageRelationships(packageTemplates)
+	if err != nil {
+		log.Println("error fetching relationships: ", err)
+		return nil, err
+	}
+
+	return &models.TemplatePackage{
+		Version:       models.TemplatePackageVersion,
+		Root:          templateId,
+		ExportedAt:    time.Now().UTC(),
+		Templates:     packageTemplates,
+		Relationships: relationships,
+	}, nil
+}
+
+func (s *service) packageRelationships(templates []models.Template) ([]models.Relationship, error) {
+	relationshipNames := make([]string, 0)
+	for _, template := range templates {
+		for _, metric := range template.Metrics {
+			if metric.Formula == "" {
+				continue
+			}
+			parsedFormula, err := formula.Parse(metric.Formula)
+			if err != nil {
+				continue
+			}
+			for _, name := range parsedFormula.Relationships() {
+				if !slices.Contains(relationshipNames, name) {
+					relationshipNames = append(relationshipNames, name)
+				}
+			}
+		}
+	}
+
+	relationships := make([]models.Relationship, 0)
+	if len(relationshipNames) == 0 {
+		return relationships, nil
+	}
+
+	relationshipTemplates, err := s.db.GetRelationships(nil, "relationships")
+	if err != nil {
+		return nil, err
+	}
+	for _, relationship := range relationshipTemplates {
+		if slices.Contains(relationshipNames, relationship.Name) {
+			relationships = append(relationships, relationship)
+		}
+	}
+	return relationships, nil
+}
+
+func (s *service) ImportTemplatePackage(tenantId string, templatePackage models.TemplatePackage, importOptions models.PackageImportOptions) (*models.PackageImportReport, error) {
+	if err := validatePackage(templatePackage, importOptions); err != nil {
+		log.Println("error validating template package: ", err)
+		return nil, err
+	}
+
+	report := &models.PackageImportReport{DryRun: importOptions.DryRun, Templates: make([]models.PackageTemplateResult, 0, len(templatePackage.Templates))}
+	if importOptions.DryRun {
+		if err := s.importPackage(tenantId, templatePackage, importOptions, report); err != nil {
+			log.Println("error running template package dry run: ", err)
+			return nil, err
+		}
+		return report, nil
+	}
+
+	err := s.db.WithTransaction(func(tx db.Repository) error {
+		report.Templates = report.Templates[:0]
+		txService := &service{db: tx}
+		return txService.importPackage(tenantId, templatePackage, importOptions, report)
+	})
+	if err != nil {
+		log.Println("error importing template package: ", err)
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func validatePackage(templatePackage models.TemplatePackage, importOptions models.PackageImportOptions) error {
+	validationErr := &models.ValidationError{}
+	if templatePackage.Version != models.TemplatePackageVersion {
+		validationErr.Add("version", "", models.ValidationCodeNotAllowed, fmt.Sprintf("package version %d is not supported", templatePackage.Version))
+	}
+	if !slices.Contains([]string{models.ConflictStrategySkip, models.ConflictStrategyOverwrite, models.ConflictStrategyRename}, importOptions.Strategy) {
+		validationErr.Add("strategy", "", models.ValidationCodeNotAllowed, "strategy must be skip, overwrite or rename")
+	}
+	if len(templatePackage.Templates) == 0 {
+		validationErr.Add("templates", "", models.ValidationCodeRequired, "the package contains no templates")
+	}
+
+	externalIds := make([]string, 0, len(templatePackage.Templates))
+	for i, template := range templatePackage.Templates {
+		externalId := strings.ToLower(template.BasicInformation.ExternalID)
+		if externalId == "" {
+			validationErr.Add(fmt.Sprintf("templates[%d].basicInformation.externalId", i), "", models.ValidationCodeRequired, "External Id is required but not provided")
+		} else if slices.Contains(externalIds, externalId) {
+			validationErr.Add(fmt.Sprintf("templates[%d].basicInformation.externalId", i), externalId, models.ValidationCodeNotUnique, fmt.Sprintf("template %s appears more than once", externalId))
+		}
+		if i > 0 && !slices.Contains(externalIds, strings.ToLower(template.BasicInformation.Parent)) {
+			validationErr.Add(fmt.Sprintf("templates[%d].basicInformation.parent", i), externalId, models.ValidationCodeInvalid, "parent must appear earlier in the package")
+		}
+		externalIds = append(externalIds, externalId)
+	}
+
+	return validationErr.ErrOrNil()
+}
+
+func (s *service) importPackage(tenantId string, templatePackage models.TemplatePackage, importOptions models.PackageImportOptions, report *models.PackageImportReport) error {
+	validationErr := &models.ValidationError{}
+	if len(templatePackage.Relationships) > 0 {
+		relationshipTemplates, err := s.db.GetRelationships(nil, "relationships")
+		if err != nil {
+			log.Println("error fetching relationships: ", err)
+			return err
+		}
+		for i, relationship := range templatePackage.Relationships {
+			if !slices.ContainsFunc(relationshipTemplates, func(r models.Relationship) bool {
+				return r.ID == relationship.ID || r.Name == relationship.Name
+			}) {
+				validationErr.Add(fmt.Sprintf("relationships[%d]", i), relationship.ID.Hex(), models.ValidationCodeUnknownField, fmt.Sprintf("relationship %s is not defined", relationship.Name))
+			}
+		}
+	}
+
+	templates, err := s.db.GetAllTemplates(bson.D{{Key: "tenantId", Value: tenantId}}, nil)
+	if err != nil {
+		log.Println("error fetching all templates: ", err)
+		return err
+	}
+
+	rootParent := strings.ToLower(importOptions.Parent)
+	if rootParent == "" {
+		rootParent = strings.ToLower(templatePackage.Templates[0].BasicInformation.Parent)
+	}
+	if !slices.ContainsFunc(templates, func(t models.Template) bool {
+		return t.BasicInformation.ExternalID == rootParent
+	}) {
+		validationErr.Add("parent", rootParent, models.ValidationCodeUnknownField, fmt.Sprintf("parent template %s does not exist", rootParent))
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	// A dry run writes nothing, so templates created earlier in the package are resolved from memory.
+	parentTemplate := func(externalId string) (*models.Template, error) {
+		if !importOptions.DryRun {
+			return s.GetTemplate(tenantId, externalId)
+		}
+		index := slices.IndexFunc(templates, func(t models.Template) bool {
+			return t.BasicInformation.ExternalID == externalId
+		})
+		if index == -1 {
+			return nil, db.ErrNotFound
+		}
+		return &templates[index], nil
+	}
+
+	importedExternalIds := make(map[string]string)
+	for i, packageTemplate := range templatePackage.Templates {
+		field := fmt.Sprintf("templates[%d]", i)
+		sourceExternalId := strings.ToLower(packageTemplate.BasicInformation.ExternalID)
+		parent := rootParent
+		if i > 0 {
+			parent = importedExternalIds[strings.ToLower(packageTemplate.BasicInformation.Parent)]
+		}
+
+		template := models.Template{
+			BasicInformation: models.TemplateBasicInformation{
+				Name:       packageTemplate.BasicInformation.Name,
+				Parent:     parent,
+				ExternalID: sourceExternalId,
+				IsCustom:   true,
+			},
+			Attributes: slices.Clone(packageTemplate.Attributes),
+			Metrics:    slices.Clone(packageTemplate.Metrics),
+		}
+		result := models.PackageTemplateResult{ExternalId: sourceExternalId, ImportedAs: sourceExternalId, Status: models.PackageStatusCreated}
+
+		existingIndex := slices.IndexFunc(templates, func(t models.Template) bool {
+			return t.BasicInformation.ExternalID == sourceExternalId
+		})
+		if existingIndex != -1 {
+			switch importOptions.Strategy {
+			case models.ConflictStrategySkip:
+				importedExternalIds[sourceExternalId] = sourceExternalId
+				result.Status = models.PackageStatusSkipped
+				report.Templates = append(report.Templates, result)
+				continue
+			case models.ConflictStrategyRename:
+				template.BasicInformation.ExternalID = availableExternalId(templates, sourceExternalId)
+				result.ImportedAs = template.BasicInformation.ExternalID
+				result.Status = models.PackageStatusRenamed
+			case models.ConflictStrategyOverwrite:
+				if !templates[existingIndex].BasicInformation.IsCustom {
+					validationErr.Add(field+".basicInformation.externalId", sourceExternalId, models.ValidationCodeNotAllowed, fmt.Sprintf("template %s is not a custom template and cannot be overwritten", sourceExternalId))
+					return validationErr
+				}
+				result.Status = models.PackageStatusOverwritten
+			}
+		}
+		importedExternalIds[sourceExternalId] = template.BasicInformation.ExternalID
+
+		if result.Status == models.PackageStatusOverwritten {
+			err = s.overwriteTemplate(tenantId, template, templates[existingIndex], importOptions.DryRun)
+		} else {
+			var createdTemplate *models.Template
+			createdTemplate, err = s.createTemplate(tenantId, template, parentTemplate, importOptions.DryRun)
+			if createdTemplate != nil {
+				templates = append(templates, *createdTemplate)
+			}
+		}
+		if err != nil {
+			log.Println("error importing template: ", err)
+			return packageTemplateError(field, err)
+		}
+		report.Templates = append(report.Templates, result)
+	}
+
+	return nil
+}
+
+func (s *service) createTemplate(tenantId string, template models.Template, parentTemplate func(externalId string) (*models.Template, error), dryRun bool) (*models.Template, error) {
+	newTemplate, err := prepareTemplate(tenantId, template, parentTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return newTemplate, nil
+	}
+
+	if err := s.db.AddOne("templates", *newTemplate); err != nil {
+		log.Println("error inserting template: ", err)
+		return nil, err
+	}
+
+	return newTemplate, nil
+}
+
+func (s *service) overwriteTemplate(tenantId string, template models.Template, existingTemplate models.Template, dryRun bool) error {
+	externalId := existingTemplate.BasicInformation.ExternalID
+	template.BasicInformation.Parent = existingTemplate.BasicInformation.Parent
+	template.BasicInformation.RootTemplate = existingTemplate.BasicInformation.RootTemplate
+
+	attributes := make([]models.TemplateAttribute, 0, len(existingTemplate.Attributes)+len(template.Attributes))
+	for _, attribute := range existingTemplate.Attributes {
+		if attribute.OwningTemplate != externalId {
+			attributes = append(attributes, attribute)
+		}
+	}
+	for _, attribute := range template.Attributes {
+		existingIndex := slices.IndexFunc(existingTemplate.Attributes, func(a models.TemplateAttribute) bool {
+			return a.OwningTemplate == externalId && a.Name == attribute.Name
+		})
+		attribute.ID = ""
+		if existingIndex != -1 {
+			attribute.ID = existingTemplate.Attributes[existingIndex].ID
+		}
+		attribute.OwningTemplate = externalId
+		attributes = append(attributes, attribute)
+	}
+
+	metrics := make([]models.TemplateMetric, 0, len(existingTemplate.Metrics)+len(template.Metrics))
+	for _, metric := range existingTemplate.Metrics {
+		if metric.OwningTemplate != externalId {
+			metrics = append(metrics, metric)
+		}
+	}
+	for _, metric := range template.Metrics {
+		existingIndex := slices.IndexFunc(existingTemplate.Metrics, func(m models.TemplateMetric) bool {
+			return m.OwningTemplate == externalId && m.Name == metric.Name
+		})
+		metric.ID = ""
+		if existingIndex != -1 {
+			metric.ID = existingTemplate.Metrics[existingIndex].ID
+		}
+		metric.OwningTemplate = externalId
+		metrics = append(metrics, metric)
+	}
+
+	template.Attributes = attributes
+	template.Metrics = metrics
+	_, err := s.UpdateTemplate(tenantId, template, max(existingTemplate.Version, models.InitialVersion), dryRun)
+	return err
+}
+
+func availableExternalId(templates []models.Template, externalId string) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", externalId, n)
+		if !slices.ContainsFunc(templates, func(t models.Template) bool {
+			return t.BasicInformation.ExternalID == candidate
+		}) {
+			return candidate
+		}
+	}
+}
+
+func packageTemplateError(field string, err error) error {
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	prefixedErr := &models.ValidationError{}
+	for _, fieldErr := range validationErr.Errors {
+		prefixedErr.Add(field+"."+fieldErr.Field, fieldErr.ID, fieldErr.Code, fieldErr.Message)
+	}
+	return prefixedErr
+}
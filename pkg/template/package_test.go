@@ -0,0 +1,211 @@
+package template
+
+import (
+	"api/pkg/db"
+	"api/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func newPackageTemplates() []models.Template {
+	return []models.Template{
+		{
+			TenantID:         "source",
+			BasicInformation: models.TemplateBasicInformation{Name: "Building", ExternalID: "p.com.building"},
+			Attributes:       []models.TemplateAttribute{{ID: "address", Name: "address", DataType: "string", OwningTemplate: "p.com.building"}},
+			Metrics:          make([]models.TemplateMetric, 0),
+		},
+		{
+			TenantID:         "source",
+			BasicInformation: models.TemplateBasicInformation{Name: "Office", ExternalID: "c.office", Parent: "p.com.building", RootTemplate: "p.com.building", IsCustom: true},
+			Attributes: []models.TemplateAttribute{
+				{ID: "address", Name: "address", DataType: "string", OwningTemplate: "p.com.building"},
+				{ID: "desks", Name: "desks", DataType: "integer", OwningTemplate: "c.office"},
+			},
+			Metrics: []models.TemplateMetric{
+				{ID: "floors", Name: "floors", MetricType: "integer", IsCalculated: true, Formula: `count("contains")`, OwningTemplate: "c.office"},
+			},
+		},
+		{
+			TenantID:         "source",
+			BasicInformation: models.TemplateBasicInformation{Name: "Small office", ExternalID: "c.office.small", Parent: "c.office", RootTemplate: "p.com.building", IsCustom: true},
+			Attributes: []models.TemplateAttribute{
+				{ID: "address", Name: "address", DataType: "string", OwningTemplate: "p.com.building"},
+				{ID: "desks", Name: "desks", DataType: "integer", OwningTemplate: "c.office"},
+			},
+			Metrics: []models.TemplateMetric{
+				{ID: "floors", Name: "floors", MetricType: "integer", IsCalculated: true, Formula: `count("contains")`, OwningTemplate: "c.office"},
+			},
+		},
+	}
+}
+
+func templateFilter(externalId string) interface{} {
+	return mock.MatchedBy(func(filter primitive.D) bool {
+		return len(filter) > 1 && filter[1].Value == externalId
+	})
+}
+
+func TestService_ExportTemplatePackage_Success_ContainsSubtreeAndRelationships(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	containsId, feedsId := primitive.NewObjectID(), primitive.NewObjectID()
+	mockRepository.On("GetAllTemplates", bson.D{{Key: "tenantId", Value: "source"}}, (*options.FindOptions)(nil)).Return(newPackageTemplates(), nil)
+	mockRepository.On("GetRelationships", primitive.D(nil), "relationships").Return([]models.Relationship{
+		{ID: containsId, Name: "contains", Source: "p.com.building", Target: []string{"p.com.floor"}},
+		{ID: feedsId, Name: "feeds", Source: "p.com.meter", Target: []string{"p.com.building"}},
+	}, nil)
+
+	actual, actualErr := mockService.ExportTemplatePackage("source", "C.Office")
+	assert.Nil(t, actualErr)
+	assert.Equal(t, models.TemplatePackageVersion, actual.Version)
+	assert.Equal(t, "c.office", actual.Root)
+	assert.Len(t, actual.Templates, 2)
+	assert.Equal(t, []models.TemplateAttribute{{ID: "desks", Name: "desks", DataType: "integer", OwningTemplate: "c.office"}}, actual.Templates[0].Attributes)
+	assert.Empty(t, actual.Templates[1].Attributes)
+	assert.Empty(t, actual.Templates[1].Metrics)
+	assert.Equal(t, []models.Relationship{{ID: containsId, Name: "contains", Source: "p.com.building", Target: []string{"p.com.floor"}}}, actual.Relationships)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportTemplatePackage_Rename_RemapsConflictingExternalIds(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	sourceTemplates := newPackageTemplates()
+	templatePackage := models.TemplatePackage{
+		Version:       models.TemplatePackageVersion,
+		Root:          "c.office",
+		Templates:     []models.Template{ownedEntries(sourceTemplates[1]), ownedEntries(sourceTemplates[2])},
+		Relationships: []models.Relationship{{ID: primitive.NewObjectID(), Name: "contains"}},
+	}
+	targetTemplates := newPackageTemplates()[:2]
+
+	mockRepository.On("GetRelationships", primitive.D(nil), "relationships").Return([]models.Relationship{{ID: primitive.NewObjectID(), Name: "contains"}}, nil)
+	mockRepository.On("GetAllTemplates", bson.D{{Key: "tenantId", Value: "target"}}, (*options.FindOptions)(nil)).Return(targetTemplates, nil)
+	mockRepository.On("GetTemplate", templateFilter("p.com.building")).Return(&targetTemplates[0], nil)
+	mockRepository.On("GetTemplate", templateFilter("c.office-2")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "c.office-2", Parent: "p.com.building", RootTemplate: "p.com.building", IsCustom: true},
+		Attributes:       []models.TemplateAttribute{{ID: "address", Name: "address", OwningTemplate: "p.com.building"}, {ID: "desks-2", Name: "desks", OwningTemplate: "c.office-2"}},
+		Metrics:          []models.TemplateMetric{{ID: "floors-2", Name: "floors", MetricType: "integer", IsCalculated: true, Formula: `count("contains")`, OwningTemplate: "c.office-2"}},
+	}, nil)
+	mockRepository.On("AddOne", "templates", mock.MatchedBy(func(template models.Template) bool {
+		return template.TenantID == "target" && template.BasicInformation.ExternalID == "c.office-2" && template.BasicInformation.Parent == "p.com.building" &&
+			len(template.Attributes) == 2 && template.Attributes[1].OwningTemplate == "c.office-2" && template.Attributes[1].ID != "desks"
+	})).Return(nil).Once()
+	mockRepository.On("AddOne", "templates", mock.MatchedBy(func(template models.Template) bool {
+		return template.BasicInformation.ExternalID == "c.office.small" && template.BasicInformation.Parent == "c.office-2" && len(template.Metrics) == 1
+	})).Return(nil).Once()
+
+	actual, actualErr := mockService.ImportTemplatePackage("target", templatePackage, models.PackageImportOptions{Strategy: models.ConflictStrategyRename})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, &models.PackageImportReport{Templates: []models.PackageTemplateResult{
+		{ExternalId: "c.office", ImportedAs: "c.office-2", Status: models.PackageStatusRenamed},
+		{ExternalId: "c.office.small", ImportedAs: "c.office.small", Status: models.PackageStatusCreated},
+	}}, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportTemplatePackage_Skip_AttachesChildrenToExistingTemplate(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	sourceTemplates := newPackageTemplates()
+	templatePackage := models.TemplatePackage{
+		Version:   models.TemplatePackageVersion,
+		Templates: []models.Template{ownedEntries(sourceTemplates[1]), ownedEntries(sourceTemplates[2])},
+	}
+	targetTemplates := newPackageTemplates()[:2]
+
+	mockRepository.On("GetAllTemplates", bson.D{{Key: "tenantId", Value: "target"}}, (*options.FindOptions)(nil)).Return(targetTemplates, nil)
+
+	actual, actualErr := mockService.ImportTemplatePackage("target", templatePackage, models.PackageImportOptions{Strategy: models.ConflictStrategySkip, DryRun: true})
+	assert.Nil(t, actualErr)
+	assert.Equal(t, &models.PackageImportReport{DryRun: true, Templates: []models.PackageTemplateResult{
+		{ExternalId: "c.office", ImportedAs: "c.office", Status: models.PackageStatusSkipped},
+		{ExternalId: "c.office.small", ImportedAs: "c.office.small", Status: models.PackageStatusCreated},
+	}}, actual)
+
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportTemplatePackage_DryRunRenameUnknownReference_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	sourceTemplates := newPackageTemplates()
+	templatePackage := models.TemplatePackage{
+		Version:   models.TemplatePackageVersion,
+		Templates: []models.Template{ownedEntries(sourceTemplates[1]), ownedEntries(sourceTemplates[2])},
+	}
+	templatePackage.Templates[1].Metrics = []models.TemplateMetric{{Name: "desk ratio", MetricType: "float", IsCalculated: true, Formula: "[meeting rooms] / desks"}}
+
+	mockRepository.On("GetAllTemplates", bson.D{{Key: "tenantId", Value: "target"}}, (*options.FindOptions)(nil)).Return(newPackageTemplates()[:2], nil)
+
+	actual, actualErr := mockService.ImportTemplatePackage("target", templatePackage, models.PackageImportOptions{Strategy: models.ConflictStrategyRename, DryRun: true})
+	assert.Nil(t, actual)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, "templates[1].metrics[1].formula", validationErr.Errors[0].Field)
+
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportTemplatePackage_OverwriteSystemTemplate_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	templatePackage := models.TemplatePackage{
+		Version:   models.TemplatePackageVersion,
+		Templates: []models.Template{ownedEntries(newPackageTemplates()[0])},
+	}
+	templatePackage.Templates[0].BasicInformation.Parent = "p.com.asset"
+
+	mockRepository.On("GetAllTemplates", bson.D{{Key: "tenantId", Value: "target"}}, (*options.FindOptions)(nil)).Return(append(newPackageTemplates(), models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.asset"},
+	}), nil)
+
+	actual, actualErr := mockService.ImportTemplatePackage("target", templatePackage, models.PackageImportOptions{Strategy: models.ConflictStrategyOverwrite})
+	assert.Nil(t, actual)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, "templates[0].basicInformation.externalId", validationErr.Errors[0].Field)
+	assert.Equal(t, models.ValidationCodeNotAllowed, validationErr.Errors[0].Code)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_ImportTemplatePackage_InvalidPackage_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := NewService(mockRepository)
+
+	templatePackage := models.TemplatePackage{
+		Version:   2,
+		Templates: []models.Template{{BasicInformation: models.TemplateBasicInformation{ExternalID: "c.office.small", Parent: "c.office"}}, {BasicInformation: models.TemplateBasicInformation{ExternalID: "c.other", Parent: "c.office"}}},
+	}
+
+	actual, actualErr := mockService.ImportTemplatePackage("target", templatePackage, models.PackageImportOptions{Strategy: "merge"})
+	assert.Nil(t, actual)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	fields := make([]string, 0)
+	for _, fieldErr := range validationErr.Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	assert.Equal(t, []string{"version", "strategy", "templates[1].basicInformation.parent"}, fields)
+
+	mockRepository.AssertExpectations(t)
+}
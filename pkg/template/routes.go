@@ -1,13 +1,27 @@
 package template
 
 import (
+	"api/pkg/common"
+	"api/pkg/models"
+
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(r *gin.Engine, templateController Controller) {
-	r.GET("/api/v1/tenants/:tenantId/templates", templateController.GetTemplatesList)
-	r.GET("/api/v1/tenants/:tenantId/templates/:templateId", templateController.GetTemplateById)
-	r.GET("/api/v1/tenants/:tenantId/templates/parent", templateController.GetParentTemplates)
-	r.POST("/api/v1/tenants/:tenantId/templates", templateController.CreateTemplate)
-	r.PUT("/api/v1/tenants/:tenantId/templates/:templateId", templateController.UpdateTemplateById)
+func RegisterRoutes(r *gin.Engine, templateController Controller, authorizer common.Authorizer) {
+	reads := r.Group("", authorizer.Require(models.PermissionTemplatesRead))
+	reads.GET("/api/v1/tenants/:tenantId/templates", templateController.GetTemplatesList)
+	reads.GET("/api/v1/tenants/:tenantId/templates/:templateId", templateController.GetTemplateById)
+	reads.GET("/api/v1/tenants/:tenantId/templates/parent", templateController.GetParentTemplates)
+	reads.GET("/api/v1/tenants/:tenantId/templates/export", templateController.ExportTemplates)
+	reads.GET("/api/v1/tenants/:tenantId/templates/:templateId/package", templateController.ExportTemplatePackage)
+	reads.GET("/api/v1/tenants/:tenantId/templates/:templateId/versions", templateController.GetTemplateVersions)
+	reads.GET("/api/v1/tenants/:tenantId/templates/:templateId/versions/diff", templateController.DiffTemplateVersions)
+	reads.GET("/api/v1/tenants/:tenantId/templates/:templateId/versions/:version", templateController.GetTemplateVersion)
+
+	writes := r.Group("", authorizer.Require(models.PermissionTemplatesWrite))
+	writes.POST("/api/v1/tenants/:tenantId/templates", templateController.CreateTemplate)
+	writes.POST("/api/v1/tenants/:tenantId/templates/package", templateController.ImportTemplatePackage)
+	writes.PUT("/api/v1/tenants/:tenantId/templates/:templateId", templateController.UpdateTemplateById)
+	writes.DELETE("/api/v1/tenants/:tenantId/templates/:templateId", templateController.DeleteTemplateById)
+	writes.POST("/api/v1/tenants/:tenantId/templates/:templateId/versions/:version/restore", templateController.RestoreTemplateVersion)
 }
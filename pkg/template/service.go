@@ -1,9 +1,17 @@
 package template
 
 import (
+	"api/pkg/audit"
+	"api/pkg/calculation"
 	"api/pkg/db"
+	"api/pkg/formula"
 	"api/pkg/models"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
@@ -13,12 +21,27 @@ import (
 
 type Service interface {
 	AddTemplate(tenantId string, template models.Template) error
-	GetTemplates(tenantId string) ([]models.Template, error)
+	GetTemplates(tenantId string, query models.ListQuery) (*models.TemplatePage, error)
 	GetTemplate(tenantId string, templateId string) (*models.Template, error)
 	GetParentTemplates(tenantId string) ([]models.ParentTemplateDropdown, error)
-	UpdateTemplate(tenantId string, template models.Template) error
+	UpdateTemplate(tenantId string, template models.Template, expectedVersion int64, dryRun bool) (*models.SchemaMigrationReport, error)
+	DeleteTemplate(tenantId string, templateId string, force bool) (*models.TemplateDependents, error)
+	GetDescendantTemplates(tenantId string, templateId string) ([]models.Template, error)
+	ExportTemplates(tenantId string, w io.Writer) error
+	ExportTemplatePackage(tenantId string, templateId string) (*models.TemplatePackage, error)
+	ImportTemplatePackage(tenantId string, templatePackage models.TemplatePackage, importOptions models.PackageImportOptions) (*models.PackageImportReport, error)
+	GetTemplateVersions(tenantId string, templateId string) ([]models.VersionSummary, error)
+	GetTemplateVersion(tenantId string, templateId string, version int64) (*models.Template, error)
+	DiffTemplateVersions(tenantId string, templateId string, from int64, to int64) (*models.VersionDiff, error)
+	RestoreTemplateVersion(tenantId string, templateId string, version int64, expectedVersion int64) (*models.SchemaMigrationReport, error)
+	WithAuditContext(auditContext models.AuditContext) Service
 }
 
+var (
+	ErrTemplateInUse        = errors.New("template has child templates or instances")
+	ErrTemplateNotDeletable = errors.New("template is not a custom template")
+)
+
 type service struct {
 	db db.Repository
 }
@@ -29,10 +52,13 @@ func NewService(dbRepository db.Repository) Service {
 	}
 }
 
-func (s *service) UpdateTemplate(tenantId string, template models.Template) error {
+func (s *service) WithAuditContext(auditContext models.AuditContext) Service {
+	return &service{db: audit.NewRepository(s.db, auditContext)}
+}
+
+func (s *service) UpdateTemplate(tenantId string, template models.Template, expectedVersion int64, dryRun bool) (*models.SchemaMigrationReport, error) {
 	template.TenantID = tenantId
 	template.BasicInformation.ExternalID = strings.ToLower(template.BasicInformation.ExternalID)
-	filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: template.BasicInformation.ExternalID}}
 
 	for i, attribute := range template.Attributes {
 		if attribute.ID == "" {
@@ -40,6 +66,9 @@ func (s *service) UpdateTemplate(tenantId string, template models.Template) erro
 			attribute.ID = attributeID.String()
 			template.Attributes[i] = attribute
 		}
+		if attribute.OwningTemplate == "" {
+			template.Attributes[i].OwningTemplate = template.BasicInformation.ExternalID
+		}
 	}
 
 	for i, metric := range template.Metrics {
@@ -47,14 +76,370 @@ func (s *service) UpdateTemplate(tenantId string, template models.Template) erro
 			metricId, _ := uuid.NewUUID()
 			template.Metrics[i].ID = metricId.String()
 		}
+		if metric.OwningTemplate == "" {
+			template.Metrics[i].OwningTemplate = template.BasicInformation.ExternalID
+		}
 	}
 
-	if err := s.db.ReplaceTemplate(filter, template); err != nil {
-		log.Println("error updating template: ", err)
+	validationErr := &models.ValidationError{}
+	validationErr.Merge(validateTemplateEntries(template))
+	validationErr.Merge(validateFormulas(template))
+	if err := validationErr.ErrOrNil(); err != nil {
+		log.Println("error validating template: ", err)
+		return nil, err
+	}
+
+	if dryRun {
+		return s.updateTemplate(tenantId, template, expectedVersion, dryRun)
+	}
+
+	var report *models.SchemaMigrationReport
+	err := s.db.WithTransaction(func(tx db.Repository) error {
+		var err error
+		report, err = (&service{db: tx}).updateTemplate(tenantId, template, expectedVersion, dryRun)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (s *service) updateTemplate(tenantId string, template models.Template, expectedVersion int64, dryRun bool) (*models.SchemaMigrationReport, error) {
+	existingTemplate, err := s.GetTemplate(tenantId, template.BasicInformation.ExternalID)
+	if err != nil {
+		log.Println("error getting template: ", err)
+		return nil, err
+	}
+	if expectedVersion != 0 && expectedVersion != max(existingTemplate.Version, models.InitialVersion) {
+		log.Printf("template %s is not at version %d\n", template.BasicInformation.ExternalID, expectedVersion)
+		return nil, db.ErrPreconditionFailed
+	}
+	template.BasicInformation.IsCustom = existingTemplate.BasicInformation.IsCustom
+
+	templates, err := s.db.GetAllTemplates(bson.D{{Key: "tenantId", Value: tenantId}}, nil)
+	if err != nil {
+		log.Println("error fetching all templates: ", err)
+		return nil, err
+	}
+
+	revisions := append([]templateRevision{{previous: *existingTemplate, current: template}}, descendantRevisions(templates, template)...)
+
+	report := &models.SchemaMigrationReport{
+		DryRun:                dryRun,
+		Version:               max(existingTemplate.Version, models.InitialVersion),
+		Diff:                  diffTemplateSchema(*existingTemplate, template),
+		MigratedInstances:     make([]string, 0),
+		RecalculatedInstances: make([]string, 0),
+		InvalidInstances:      make([]models.InvalidInstance, 0),
+	}
+	migratedInstances, err := s.migrateInstances(s.newCalculator(tenantId), tenantId, revisions, report)
+	if err != nil {
+		log.Println("error migrating instances: ", err)
+		return nil, err
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for i, revision := range revisions {
+		filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: revision.current.BasicInformation.ExternalID}}
+		if i == 0 {
+			filter = db.VersionFilter(filter, expectedVersion)
+		}
+		version, err := s.db.ReplaceTemplate(filter, revision.current)
+		if err != nil {
+			log.Println("error updating template: ", err)
+			return nil, err
+		}
+		if i == 0 {
+			report.Version = version
+		}
+	}
+
+	for _, instance := range migratedInstances {
+		filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: instance.BasicInformation.ExternalId}}
+		if _, err := s.db.ReplaceInstance(filter, instance); err != nil {
+			log.Println("error migrating instance: ", err)
+			return nil, err
+		}
+	}
+
+	// A fresh calculator so dependents are recalculated against the templates just written.
+	if err := s.newCalculator(tenantId).Propagate(report.RecalculatedInstances); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (s *service) migrateInstances(calculator calculation.Calculator, tenantId string, revisions []templateRevision, report *models.SchemaMigrationReport) ([]models.Instance, error) {
+	migratedInstances := make([]models.Instance, 0)
+	for _, revision := range revisions {
+		diff := diffTemplateSchema(revision.previous, revision.current)
+		recalculate := formulasChanged(revision.previous, revision.current)
+		if diff.IsEmpty() && !recalculate {
+			continue
+		}
+
+		filter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.parent", Value: revision.current.BasicInformation.ExternalID}}
+		instances, err := s.db.GetAllInstances(filter, nil)
+		if err != nil {
+			log.Println("error fetching template instances: ", err)
+			return nil, err
+		}
+
+		for _, instance := range instances {
+			migrated, reasons := migrateInstance(&instance, revision.current, diff)
+			if migrated {
+				report.MigratedInstances = append(report.MigratedInstances, instance.BasicInformation.ExternalId)
+			}
+			recalculated := recalculate && calculator.Calculate(&instance, &revision.current)
+			if recalculated {
+				report.RecalculatedInstances = append(report.RecalculatedInstances, instance.BasicInformation.ExternalId)
+			}
+			if migrated || recalculated {
+				migratedInstances = append(migratedInstances, instance)
+			}
+			if len(reasons) > 0 {
+				report.InvalidInstances = append(report.InvalidInstances, models.InvalidInstance{
+					ExternalId: instance.BasicInformation.ExternalId,
+					Template:   revision.current.BasicInformation.ExternalID,
+					Reasons:    reasons,
+				})
+			}
+		}
+	}
+
+	return migratedInstances, nil
+}
+
+func descendantRevisions(templates []models.Template, template models.Template) []templateRevision {
+	revisions := make([]templateRevision, 0)
+	owner := template.BasicInformation.ExternalID
+	for _, descendant := range descendantTemplates(templates, owner) {
+		descendantExternalId := descendant.BasicInformation.ExternalID
+		attributes, attributesChanged := syncInheritedEntries(descendant.Attributes, template.Attributes, owner, descendantExternalId, func(a models.TemplateAttribute) string {
+			return a.ID
+		}, func(a models.TemplateAttribute) string {
+			return a.OwningTemplate
+		})
+		metrics, metricsChanged := syncInheritedEntries(descendant.Metrics, template.Metrics, owner, descendantExternalId, func(m models.TemplateMetric) string {
+			return m.ID
+		}, func(m models.TemplateMetric) string {
+			return m.OwningTemplate
+		})
+		if !attributesChanged && !metricsChanged {
+			continue
+		}
+
+		updated := descendant
+		updated.Attributes = attributes
+		updated.Metrics = metrics
+		revisions = append(revisions, templateRevision{previous: descendant, current: updated})
+	}
+
+	return revisions
+}
+
+// syncInheritedEntries brings the entries a descendant inherited from owner in
+// line with owner's current entries. Entries owned by anyone else are kept as is,
+// and newly added entries are placed after the descendant's other inherited ones.
+func syncInheritedEntries[T any](entries []T, ownerEntries []T, owner string, descendant string, id func(T) string, owningTemplate func(T) string) ([]T, bool) {
+	changed := false
+	result := make([]T, 0, len(entries))
+	insertAt := -1
+	for _, entry := range entries {
+		if owningTemplate(entry) != owner {
+			result = append(result, entry)
+			continue
+		}
+
+		ownerEntryIndex := slices.IndexFunc(ownerEntries, func(e T) bool {
+			return owningTemplate(e) == owner && id(e) == id(entry)
+		})
+		if ownerEntryIndex == -1 {
+			changed = true
+			continue
+		}
+
+		ownerEntry := ownerEntries[ownerEntryIndex]
+		if !reflect.DeepEqual(entry, ownerEntry) {
+			changed = true
+		}
+		result = append(result, ownerEntry)
+		insertAt = len(result)
+	}
+
+	if insertAt == -1 {
+		insertAt = slices.IndexFunc(result, func(e T) bool {
+			return owningTemplate(e) == descendant
+		})
+		if insertAt == -1 {
+			insertAt = len(result)
+		}
+	}
+
+	for _, ownerEntry := range ownerEntries {
+		if owningTemplate(ownerEntry) != owner || slices.ContainsFunc(result, func(e T) bool {
+			return id(e) == id(ownerEntry)
+		}) {
+			continue
+		}
+		result = slices.Insert(result, insertAt, ownerEntry)
+		insertAt++
+		changed = true
+	}
+
+	return result, changed
+}
+
+func (s *service) DeleteTemplate(tenantId string, templateId string, force bool) (*models.TemplateDependents, error) {
+	var dependents *models.TemplateDependents
+	err := s.db.WithTransaction(func(tx db.Repository) error {
+		var err error
+		dependents, err = (&service{db: tx}).deleteTemplate(tenantId, strings.ToLower(templateId), force)
+		return err
+	})
+	if err != nil {
+		return dependents, err
+	}
+
+	return nil, nil
+}
+
+func (s *service) deleteTemplate(tenantId string, templateId string, force bool) (*models.TemplateDependents, error) {
+	template, err := s.GetTemplate(tenantId, templateId)
+	if err != nil {
+		log.Println("error getting template: ", err)
+		return nil, err
+	}
+
+	if !template.BasicInformation.IsCustom {
+		return nil, ErrTemplateNotDeletable
+	}
+
+	templates, err := s.db.GetAllTemplates(bson.D{{Key: "tenantId", Value: tenantId}}, nil)
+	if err != nil {
+		log.Println("error fetching all templates: ", err)
+		return nil, err
+	}
+
+	templateExternalIds := []string{template.BasicInformation.ExternalID}
+	for _, descendant := range descendantTemplates(templates, template.BasicInformation.ExternalID) {
+		templateExternalIds = append(templateExternalIds, descendant.BasicInformation.ExternalID)
+	}
+
+	instancesFilter := bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "basicInformation.parent", Value: bson.D{{Key: "$in", Value: templateExternalIds}}},
+	}
+	instances, err := s.db.GetAllInstances(instancesFilter, nil)
+	if err != nil {
+		log.Println("error fetching template instances: ", err)
+		return nil, err
+	}
+
+	if !force && (len(templateExternalIds) > 1 || len(instances) > 0) {
+		dependents := &models.TemplateDependents{
+			Templates: templateExternalIds[1:],
+			Instances: make([]string, 0),
+		}
+		for _, instance := range instances {
+			dependents.Instances = append(dependents.Instances, instance.BasicInformation.ExternalId)
+		}
+		return dependents, ErrTemplateInUse
+	}
+
+	calculator := s.newCalculator(tenantId)
+	strippedExternalIds := make([]string, 0)
+	if len(instances) > 0 {
+		instanceExternalIds := make([]string, 0, len(instances))
+		for _, instance := range instances {
+			instanceExternalIds = append(instanceExternalIds, instance.BasicInformation.ExternalId)
+		}
+
+		strippedExternalIds, err = s.removeInstanceReferences(calculator, tenantId, instanceExternalIds)
+		if err != nil {
+			log.Println("error removing instance references: ", err)
+			return nil, err
+		}
+
+		if err := s.db.DeleteInstances(instancesFilter); err != nil {
+			log.Println("error deleting template instances: ", err)
+			return nil, err
+		}
+	}
+
+	templatesFilter := bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "basicInformation.externalId", Value: bson.D{{Key: "$in", Value: templateExternalIds}}},
+	}
+	if err := s.db.DeleteTemplates(templatesFilter); err != nil {
+		log.Println("error deleting templates: ", err)
+		return nil, err
 	}
 
-	return nil
+	return nil, calculator.Propagate(strippedExternalIds)
+}
+
+// removeInstanceReferences strips the given instances from the relationships of the instances
+// that target them and recalculates those instances. It returns the instances it changed.
+func (s *service) removeInstanceReferences(calculator calculation.Calculator, tenantId string, instanceExternalIds []string) ([]string, error) {
+	filter := bson.D{
+		{Key: "tenantId", Value: tenantId},
+		{Key: "basicInformation.externalId", Value: bson.D{{Key: "$nin", Value: instanceExternalIds}}},
+		{Key: "relationships.target", Value: bson.D{{Key: "$in", Value: instanceExternalIds}}},
+	}
+	referencingInstances, err := s.db.GetAllInstances(filter, nil)
+	if err != nil {
+		log.Println("error fetching referencing instances: ", err)
+		return nil, err
+	}
+
+	strippedExternalIds := make([]string, 0)
+	for i := range referencingInstances {
+		referencingInstance := &referencingInstances[i]
+		if !referencingInstance.RemoveRelationshipTargets(instanceExternalIds) {
+			continue
+		}
+		strippedExternalIds = append(strippedExternalIds, referencingInstance.BasicInformation.ExternalId)
+
+		referencingTemplate, err := calculator.Template(referencingInstance.BasicInformation.Parent)
+		if err != nil {
+			return nil, err
+		}
+		calculator.Calculate(referencingInstance, referencingTemplate)
+
+		instanceFilter := bson.D{{Key: "tenantId", Value: tenantId}, {Key: "basicInformation.externalId", Value: referencingInstance.BasicInformation.ExternalId}}
+		if _, err := s.db.ReplaceInstance(instanceFilter, referencingInstance); err != nil {
+			log.Println("error updating instance: ", err)
+			return nil, err
+		}
+	}
+
+	return strippedExternalIds, nil
+}
+
+func descendantTemplates(templates []models.Template, templateExternalId string) []models.Template {
+	descendants := make([]models.Template, 0)
+	parents := []string{templateExternalId}
+	for len(parents) > 0 {
+		parent := parents[0]
+		parents = parents[1:]
+		for _, t := range templates {
+			if t.BasicInformation.Parent != parent || slices.ContainsFunc(descendants, func(d models.Template) bool {
+				return d.BasicInformation.ExternalID == t.BasicInformation.ExternalID
+			}) {
+				continue
+			}
+			descendants = append(descendants, t)
+			parents = append(parents, t.BasicInformation.ExternalID)
+		}
+	}
+	return descendants
 }
 
 func (s *service) GetParentTemplates(tenantId string) ([]models.ParentTemplateDropdown, error) {
@@ -80,47 +465,62 @@ func (s *service) GetParentTemplates(tenantId string) ([]models.ParentTemplateDr
 }
 
 func (s *service) AddTemplate(tenantId string, template models.Template) error {
-	if len(template.Attributes) > 0 {
-		for i, attribute := range template.Attributes {
-			attributeID, _ := uuid.NewUUID()
-			attribute.ID = attributeID.String()
-			template.Attributes[i] = attribute
-		}
+	_, err := s.createTemplate(tenantId, template, func(externalId string) (*models.Template, error) {
+		return s.GetTemplate(tenantId, externalId)
+	}, false)
+	return err
+}
+
+func prepareTemplate(tenantId string, template models.Template, parentTemplate func(externalId string) (*models.Template, error)) (*models.Template, error) {
+	template.TenantID = tenantId
+	template.BasicInformation.ExternalID = strings.ToLower(template.BasicInformation.ExternalID)
+	template.BasicInformation.IsCustom = true
+	template.Attributes = slices.Clone(template.Attributes)
+	for i, attribute := range template.Attributes {
+		attributeID, _ := uuid.NewUUID()
+		attribute.ID = attributeID.String()
+		attribute.OwningTemplate = template.BasicInformation.ExternalID
+		template.Attributes[i] = attribute
 	}
+	template.Metrics = slices.Clone(template.Metrics)
 	for i := range template.Metrics {
 		metricId, _ := uuid.NewUUID()
 		template.Metrics[i].ID = metricId.String()
+		template.Metrics[i].OwningTemplate = template.BasicInformation.ExternalID
+	}
+
+	if err := validateTemplateEntries(template); err != nil {
+		log.Println("error validating template: ", err)
+		return nil, err
 	}
-	template.TenantID = tenantId
-	template.BasicInformation.ExternalID = strings.ToLower(template.BasicInformation.ExternalID)
 
-	parentTemplate, err := s.GetTemplate(tenantId, template.BasicInformation.Parent)
+	parent, err := parentTemplate(template.BasicInformation.Parent)
 	if err != nil {
 		log.Println("error fetching parent template: ", err)
-		return err
+		return nil, err
 	}
-	if parentTemplate.BasicInformation.RootTemplate == "" {
-		template.BasicInformation.RootTemplate = parentTemplate.BasicInformation.ExternalID
+	if parent.BasicInformation.RootTemplate == "" {
+		template.BasicInformation.RootTemplate = parent.BasicInformation.ExternalID
 	} else {
-		template.BasicInformation.RootTemplate = parentTemplate.BasicInformation.RootTemplate
+		template.BasicInformation.RootTemplate = parent.BasicInformation.RootTemplate
 	}
 
-	template.Attributes = append(parentTemplate.Attributes, template.Attributes...)
-	template.Metrics = append(parentTemplate.Metrics, template.Metrics...)
+	template.Attributes = append(slices.Clone(parent.Attributes), template.Attributes...)
+	template.Metrics = append(slices.Clone(parent.Metrics), template.Metrics...)
 
-	if err := s.db.AddOne("templates", template); err != nil {
-		log.Println("error inserting template: ", err)
-		return err
+	if err := validateFormulas(template); err != nil {
+		log.Println("error validating formulas: ", err)
+		return nil, err
 	}
 
-	return nil
+	template.Version = models.InitialVersion
+	return &template, nil
 }
 
-func (s *service) GetTemplates(tenantId string) ([]models.Template, error) {
-	filter := bson.D{{Key: "tenantId", Value: tenantId}}
-	opts := options.Find().SetSort(bson.D{{Key: "basicInformation.externalId", Value: 1}})
+func (s *service) GetTemplates(tenantId string, query models.ListQuery) (*models.TemplatePage, error) {
+	query.TenantID = tenantId
 
-	templates, err := s.db.GetAllTemplates(filter, opts)
+	templates, err := s.db.ListTemplates(query)
 	if err != nil {
 		log.Println("error getting all templates: ", err)
 		return nil, err
@@ -140,3 +540,76 @@ func (s *service) GetTemplate(tenantId string, templateId string) (*models.Templ
 
 	return template, nil
 }
+
+func (s *service) GetDescendantTemplates(tenantId string, templateId string) ([]models.Template, error) {
+	templates, err := s.db.GetAllTemplates(bson.D{{Key: "tenantId", Value: tenantId}}, nil)
+	if err != nil {
+		log.Println("error fetching all templates: ", err)
+		return nil, err
+	}
+
+	return descendantTemplates(templates, templateId), nil
+}
+
+func validateTemplateEntries(template models.Template) error {
+	validationErr := &models.ValidationError{}
+	for i, attribute := range template.Attributes {
+		if attribute.Name == "" {
+			validationErr.Add(fmt.Sprintf("attributes[%d].name", i), attribute.ID, models.ValidationCodeRequired, "attribute name is required but not provided")
+		}
+		if attribute.DataType == "enum" && len(attribute.EnumValues) == 0 {
+			validationErr.Add(fmt.Sprintf("attributes[%d].enumValues", i), attribute.ID, models.ValidationCodeRequired, "enum attributes need at least one value")
+		}
+		if err := attribute.Constraints.Check(); err != nil {
+			validationErr.Add(fmt.Sprintf("attributes[%d].constraints", i), attribute.ID, models.ValueErrorCode(err), err.Error())
+		} else if attribute.Constraints.HasDefault() {
+			if _, err := models.ConvertAttributeValue(attribute, attribute.Constraints.Default); err != nil {
+				validationErr.Add(fmt.Sprintf("attributes[%d].constraints.default", i), attribute.ID, models.ValueErrorCode(err), err.Error())
+			}
+		}
+	}
+	for i, metric := range template.Metrics {
+		if metric.Name == "" {
+			validationErr.Add(fmt.Sprintf("metrics[%d].name", i), metric.ID, models.ValidationCodeRequired, "metric name is required but not provided")
+		}
+		if err := metric.Constraints.Check(); err != nil {
+			validationErr.Add(fmt.Sprintf("metrics[%d].constraints", i), metric.ID, models.ValueErrorCode(err), err.Error())
+		} else if metric.Constraints.HasDefault() {
+			if _, err := models.ConvertMetricValue(metric, metric.Constraints.Default); err != nil {
+				validationErr.Add(fmt.Sprintf("metrics[%d].constraints.default", i), metric.ID, models.ValueErrorCode(err), err.Error())
+			}
+		}
+	}
+	return validationErr.ErrOrNil()
+}
+
+func validateFormulas(template models.Template) error {
+	validationErr := &models.ValidationError{}
+	formulas := make(map[string]*formula.Formula)
+	for i, metric := range template.Metrics {
+		if metric.Formula == "" {
+			continue
+		}
+		field := fmt.Sprintf("metrics[%d].formula", i)
+		parsedFormula, err := formula.Parse(metric.Formula)
+		if err != nil {
+			validationErr.Add(field, metric.ID, models.ValidationCodeInvalid, err.Error())
+			continue
+		}
+		for _, reference := range parsedFormula.References() {
+			if !slices.ContainsFunc(template.Metrics, func(m models.TemplateMetric) bool {
+				return m.Name == reference
+			}) && !slices.ContainsFunc(template.Attributes, func(a models.TemplateAttribute) bool {
+				return a.Name == reference
+			}) {
+				validationErr.Add(field, metric.ID, models.ValidationCodeUnknownField, fmt.Sprintf("%q is not an attribute or metric of the template", reference))
+			}
+		}
+		formulas[metric.Name] = parsedFormula
+	}
+
+	if _, err := formula.Order(formulas); err != nil {
+		validationErr.Add("metrics", "", models.ValidationCodeInvalid, err.Error())
+	}
+	return validationErr.ErrOrNil()
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestNewService(t *testing.T) {
@@ -72,6 +74,68 @@ func TestService_AddTemplate_Success_CreatesTemplate(t *testing.T) {
 	mockRepository.AssertExpectations(t)
 }
 
+func TestService_AddTemplate_OwnEntries_SetsOwningTemplate(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "p.com.asset",
+		},
+		Attributes: []models.TemplateAttribute{{ID: "1", Name: "serial", DataType: "string", OwningTemplate: "p.com.asset"}},
+		Metrics:    make([]models.TemplateMetric, 0),
+	}, nil)
+	mockRepository.On("AddOne", "templates", mock.MatchedBy(func(template models.Template) bool {
+		return template.Attributes[0].OwningTemplate == "p.com.asset" &&
+			template.Attributes[1].OwningTemplate == "testtemplate1" &&
+			template.Metrics[0].OwningTemplate == "testtemplate1"
+	})).Return(nil)
+
+	actual := mockService.AddTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			Name:       "testtemplate1",
+			Parent:     "p.com.asset",
+			ExternalID: "TestTemplate1",
+			IsCustom:   true,
+		},
+		Attributes: []models.TemplateAttribute{{Name: "attribute1", DataType: "integer"}},
+		Metrics:    []models.TemplateMetric{{Name: "metric1", MetricType: "integer"}},
+	})
+	assert.Nil(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_AddTemplate_ClientMarksNotCustom_StoresCustomTemplate(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "p.com.asset",
+		},
+	}, nil)
+	mockRepository.On("AddOne", "templates", mock.MatchedBy(func(template models.Template) bool {
+		return template.BasicInformation.IsCustom
+	})).Return(nil)
+
+	actual := mockService.AddTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			Name:       "testtemplate1",
+			Parent:     "p.com.asset",
+			ExternalID: "testtemplate1",
+			IsCustom:   false,
+		},
+	})
+	assert.Nil(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
 func TestService_AddTemplate_Fails_ReturnsDuplicateExternalIdError(t *testing.T) {
 	mockRepository := &db.MockedDbRepository{}
 	mockService := &service{
@@ -115,6 +179,28 @@ func TestService_AddTemplate_FailsGettingParentTemplate_ReturnsError(t *testing.
 	mockRepository.AssertExpectations(t)
 }
 
+func TestService_AddTemplate_MissingEntryNames_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	actualErr := mockService.AddTemplate("the-binary", models.Template{
+		Attributes: []models.TemplateAttribute{{DataType: "integer"}},
+		Metrics:    []models.TemplateMetric{{MetricType: "float"}},
+	})
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Len(t, validationErr.Errors, 2)
+	assert.Equal(t, "attributes[0].name", validationErr.Errors[0].Field)
+	assert.Equal(t, "metrics[0].name", validationErr.Errors[1].Field)
+	assert.Equal(t, models.ValidationCodeRequired, validationErr.Errors[1].Code)
+
+	mockRepository.AssertNotCalled(t, "GetTemplate", mock.Anything)
+	mockRepository.AssertNotCalled(t, "AddOne", mock.Anything, mock.Anything)
+}
+
 func TestService_AddTemplate_Fails_ReturnsError(t *testing.T) {
 	mockRepository := &db.MockedDbRepository{}
 	mockService := &service{
@@ -216,10 +302,11 @@ func TestService_GetTemplates_Success_ReturnsTemplates(t *testing.T) {
 			Metrics:    nil,
 		})
 
-	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return(expected, nil)
+	mockRepository.On("ListTemplates", models.ListQuery{TenantID: "the-binary", Sort: "name"}).Return(&models.TemplatePage{Items: expected, Total: 2}, nil)
 
-	actual, actualErr := mockService.GetTemplates("the-binary")
-	assert.Equal(t, expected, actual)
+	actual, actualErr := mockService.GetTemplates("the-binary", models.ListQuery{Sort: "name"})
+	assert.Equal(t, expected, actual.Items)
+	assert.Equal(t, int64(2), actual.Total)
 	assert.Nil(t, actualErr)
 
 	mockRepository.AssertExpectations(t)
@@ -233,9 +320,9 @@ func TestService_GetTemplates_Fails_ReturnsError(t *testing.T) {
 
 	expectedErr := errors.New("error fetching templates")
 
-	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return(nil, expectedErr)
+	mockRepository.On("ListTemplates", mock.AnythingOfType("models.ListQuery")).Return(nil, expectedErr)
 
-	actual, actualErr := mockService.GetTemplates("the-binary")
+	actual, actualErr := mockService.GetTemplates("the-binary", models.ListQuery{})
 	assert.Equal(t, expectedErr, actualErr)
 	assert.Nil(t, actual)
 
@@ -248,9 +335,18 @@ func TestService_UpdateTemplate_Success(t *testing.T) {
 		db: mockRepository,
 	}
 
-	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("models.Template")).Return(nil)
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(template models.Template) bool {
+		return !template.BasicInformation.IsCustom
+	})).Return(int64(2), nil)
 
-	actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+	_, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
 		TenantID: "the-binary",
 		BasicInformation: models.TemplateBasicInformation{
 			Name:       "testtemplate1",
@@ -294,7 +390,7 @@ func TestService_UpdateTemplate_Success(t *testing.T) {
 			IsSourced:      false,
 			OwningTemplate: "testtemplate1",
 		}},
-	})
+	}, 0, false)
 	assert.Nil(t, actualErr)
 
 	mockRepository.AssertExpectations(t)
@@ -308,14 +404,396 @@ func TestService_UpdateTemplate_Fails_ReturnsError(t *testing.T) {
 
 	expectedErr := errors.New("error replacing template")
 
-	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("models.Template")).Return(expectedErr)
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{}, nil)
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("models.Template")).Return(int64(0), expectedErr)
 
-	actualErr := mockService.UpdateTemplate("the-binary", models.Template{})
+	_, actualErr := mockService.UpdateTemplate("the-binary", models.Template{}, 0, false)
 	assert.Equal(t, expectedErr, actualErr)
 
 	mockRepository.AssertExpectations(t)
 }
 
+func TestService_UpdateTemplate_Success_PropagatesInheritedEntriesToDescendants(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	child := models.Template{
+		TenantID: "the-binary",
+		BasicInformation: models.TemplateBasicInformation{
+			Parent:     "testtemplate1",
+			ExternalID: "testtemplate2",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "asset-attribute", Name: "Asset", OwningTemplate: "p.com.asset"},
+			{ID: "attribute1", Name: "attribute1", OwningTemplate: "testtemplate1"},
+			{ID: "attribute2", Name: "attribute2", OwningTemplate: "testtemplate1"},
+			{ID: "child-attribute", Name: "child", OwningTemplate: "testtemplate2"},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "child-metric", Name: "child", OwningTemplate: "testtemplate2"},
+		},
+	}
+	grandchild := models.Template{
+		TenantID: "the-binary",
+		BasicInformation: models.TemplateBasicInformation{
+			Parent:     "testtemplate2",
+			ExternalID: "testtemplate3",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "attribute1", Name: "attribute1", OwningTemplate: "testtemplate1"},
+		},
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			Parent:     "p.com.asset",
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "asset-attribute", Name: "Asset", OwningTemplate: "p.com.asset"},
+			{ID: "attribute1", Name: "attribute1", OwningTemplate: "testtemplate1"},
+			{ID: "attribute2", Name: "attribute2", OwningTemplate: "testtemplate1"},
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{child, grandchild}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil)
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(template models.Template) bool {
+		return template.BasicInformation.ExternalID == "testtemplate1"
+	})).Return(int64(5), nil)
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(template models.Template) bool {
+		return template.BasicInformation.ExternalID == "testtemplate2" && assert.ObjectsAreEqual([]models.TemplateAttribute{
+			{ID: "asset-attribute", Name: "Asset", OwningTemplate: "p.com.asset"},
+			{ID: "attribute1", Name: "attribute1 renamed", OwningTemplate: "testtemplate1"},
+			{ID: "attribute3", Name: "attribute3", OwningTemplate: "testtemplate1"},
+			{ID: "child-attribute", Name: "child", OwningTemplate: "testtemplate2"},
+		}, template.Attributes) && assert.ObjectsAreEqual([]models.TemplateMetric{
+			{ID: "metric1", Name: "metric1", OwningTemplate: "testtemplate1"},
+			{ID: "child-metric", Name: "child", OwningTemplate: "testtemplate2"},
+		}, template.Metrics)
+	})).Return(int64(2), nil)
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(template models.Template) bool {
+		return template.BasicInformation.ExternalID == "testtemplate3" && len(template.Attributes) == 2 && template.Attributes[0].Name == "attribute1 renamed"
+	})).Return(int64(2), nil)
+
+	actual, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			Parent:     "p.com.asset",
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "asset-attribute", Name: "Asset", OwningTemplate: "p.com.asset"},
+			{ID: "attribute1", Name: "attribute1 renamed", OwningTemplate: "testtemplate1"},
+			{ID: "attribute3", Name: "attribute3", OwningTemplate: "testtemplate1"},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "metric1", Name: "metric1", OwningTemplate: "testtemplate1"},
+		},
+	}, 0, false)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, int64(5), actual.Version)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateTemplate_FailsFetchingTemplates_ReturnsError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	expectedErr := errors.New("error fetching templates")
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return(nil, expectedErr)
+
+	_, actualErr := mockService.UpdateTemplate("the-binary", models.Template{}, 0, false)
+	assert.Equal(t, expectedErr, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateTemplate_DryRun_ReportsInstancesWithoutWriting(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "attribute1", Name: "attribute1", DataType: "string", OwningTemplate: "testtemplate1"},
+			{ID: "attribute2", Name: "attribute2", DataType: "string", OwningTemplate: "testtemplate1"},
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance1"},
+			Attributes: []models.InstanceAttribute{
+				{ID: "attribute1", Value: "42"},
+				{ID: "attribute2", Value: "obsolete"},
+			},
+		},
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance2"},
+			Attributes: []models.InstanceAttribute{
+				{ID: "attribute1", Value: "not a number"},
+			},
+		},
+	}, nil)
+
+	actual, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "attribute1", Name: "attribute1", DataType: "integer", OwningTemplate: "testtemplate1"},
+			{ID: "attribute3", Name: "attribute3", DataType: "string", IsRequired: true, OwningTemplate: "testtemplate1"},
+		},
+	}, 0, true)
+	assert.Nil(t, actualErr)
+	assert.True(t, actual.DryRun)
+	assert.Equal(t, []string{"attribute3"}, actual.Diff.AddedAttributes)
+	assert.Equal(t, []string{"attribute2"}, actual.Diff.RemovedAttributes)
+	assert.Equal(t, []string{"attribute1"}, actual.Diff.RetypedAttributes)
+	assert.Equal(t, []string{"testinstance1"}, actual.MigratedInstances)
+	assert.Equal(t, []models.InvalidInstance{
+		{ExternalId: "testinstance1", Template: "testtemplate1", Reasons: []string{"attribute attribute3 is required but not provided"}},
+		{ExternalId: "testinstance2", Template: "testtemplate1", Reasons: []string{
+			"attribute attribute1: invalid value: \"not a number\" is not an integer value",
+			"attribute attribute3 is required but not provided",
+		}},
+	}, actual.InvalidInstances)
+
+	mockRepository.AssertNotCalled(t, "ReplaceTemplate", mock.Anything, mock.Anything)
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateTemplate_Success_MigratesInstances(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "attribute1", Name: "attribute1", DataType: "string", OwningTemplate: "testtemplate1"},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "metric1", Name: "metric1", MetricType: "integer", OwningTemplate: "testtemplate1"},
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance1"},
+			Attributes: []models.InstanceAttribute{
+				{ID: "attribute1", Value: "3.5"},
+			},
+			Metrics: []models.InstanceMetric{
+				{ID: "metric1", MetricBehaviour: "Manual", Value: 10},
+			},
+		},
+	}, nil)
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("models.Template")).Return(int64(2), nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance1"},
+		Attributes: []models.InstanceAttribute{
+			{ID: "attribute1", Value: 3.5},
+		},
+		Metrics: []models.InstanceMetric{
+			{ID: "metric2", MetricBehaviour: "Manual", Value: 7},
+		},
+	}).Return(int64(2), nil)
+
+	actual, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "attribute1", Name: "attribute1", DataType: "float", OwningTemplate: "testtemplate1"},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "metric2", Name: "metric2", MetricType: "integer", IsManual: true, Value: 7, OwningTemplate: "testtemplate1"},
+		},
+	}, 0, false)
+	assert.Nil(t, actualErr)
+	assert.False(t, actual.DryRun)
+	assert.Equal(t, []string{"testinstance1"}, actual.MigratedInstances)
+	assert.Empty(t, actual.InvalidInstances)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateTemplate_FormulaChanged_RecalculatesInstances(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "area", Name: "area", DataType: "float", OwningTemplate: "testtemplate1"},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "capacity", Name: "capacity", MetricType: "float", IsCalculated: true, Formula: "area * 2", OwningTemplate: "testtemplate1"},
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance1", Parent: "testtemplate1"},
+			Attributes:       []models.InstanceAttribute{{ID: "area", Value: float64(10)}},
+			Metrics:          []models.InstanceMetric{{ID: "capacity", MetricBehaviour: "Calculated", Value: float64(20)}},
+		},
+	}, nil).Once()
+	mockRepository.On("ReplaceTemplate", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("models.Template")).Return(int64(2), nil)
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), models.Instance{
+		BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance1", Parent: "testtemplate1"},
+		Attributes:       []models.InstanceAttribute{{ID: "area", Value: float64(10)}},
+		Metrics:          []models.InstanceMetric{{ID: "capacity", MetricBehaviour: "Calculated", Value: float64(30)}},
+	}).Return(int64(2), nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil).Once()
+
+	actual, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "area", Name: "area", DataType: "float", OwningTemplate: "testtemplate1"},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "capacity", Name: "capacity", MetricType: "float", IsCalculated: true, Formula: "area * 3", OwningTemplate: "testtemplate1"},
+		},
+	}, 0, false)
+	assert.Nil(t, actualErr)
+	assert.True(t, actual.Diff.IsEmpty())
+	assert.Empty(t, actual.MigratedInstances)
+	assert.Equal(t, []string{"testinstance1"}, actual.RecalculatedInstances)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateTemplate_DryRun_FillsDefaultsAndReportsConstraintViolations(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	maxFloors := 10.0
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "floors", Name: "floors", DataType: "integer", OwningTemplate: "testtemplate1"},
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance1"},
+			Attributes:       []models.InstanceAttribute{{ID: "floors", Value: 4}},
+		},
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance2"},
+			Attributes:       []models.InstanceAttribute{{ID: "floors", Value: 12}},
+		},
+	}, nil)
+
+	actual, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "testtemplate1",
+		},
+		Attributes: []models.TemplateAttribute{
+			{ID: "floors", Name: "floors", DataType: "integer", OwningTemplate: "testtemplate1", Constraints: &models.Constraints{Max: &maxFloors}},
+			{ID: "status", Name: "status", DataType: "string", IsRequired: true, OwningTemplate: "testtemplate1", Constraints: &models.Constraints{Default: "active"}},
+		},
+	}, 0, true)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, []string{"floors"}, actual.Diff.ConstrainedAttributes)
+	assert.Equal(t, []string{"testinstance1", "testinstance2"}, actual.MigratedInstances)
+	assert.Equal(t, []models.InvalidInstance{
+		{ExternalId: "testinstance2", Template: "testtemplate1", Reasons: []string{"attribute floors: value is out of range: 12 is greater than 10"}},
+	}, actual.InvalidInstances)
+
+	mockRepository.AssertNotCalled(t, "ReplaceInstance", mock.Anything, mock.Anything)
+}
+
+func TestService_UpdateTemplate_InvalidConstraints_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	minLength, maxLength := 5, 2
+	_, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		Attributes: []models.TemplateAttribute{
+			{ID: "code", Name: "code", DataType: "string", Constraints: &models.Constraints{MinLength: &minLength, MaxLength: &maxLength}},
+			{ID: "floors", Name: "floors", DataType: "integer", Constraints: &models.Constraints{Default: "many"}},
+			{ID: "zone", Name: "zone", DataType: "string", Constraints: &models.Constraints{Pattern: "("}},
+		},
+	}, 0, false)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []string{"attributes[0].constraints", "attributes[1].constraints.default", "attributes[2].constraints"}, []string{
+		validationErr.Errors[0].Field, validationErr.Errors[1].Field, validationErr.Errors[2].Field,
+	})
+	assert.Equal(t, models.ValidationCodeTypeMismatch, validationErr.Errors[1].Code)
+
+	mockRepository.AssertNotCalled(t, "GetTemplate", mock.Anything)
+}
+
+func TestService_UpdateTemplate_InvalidFormulas_ReturnsValidationError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	_, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		Attributes: []models.TemplateAttribute{
+			{ID: "floors", Name: "floors", DataType: "integer"},
+		},
+		Metrics: []models.TemplateMetric{
+			{ID: "a", Name: "a", MetricType: "float", IsCalculated: true, Formula: "b * floors"},
+			{ID: "b", Name: "b", MetricType: "float", IsCalculated: true, Formula: "a + 1"},
+			{ID: "c", Name: "c", MetricType: "float", IsCalculated: true, Formula: "height * 2"},
+			{ID: "d", Name: "d", MetricType: "float", IsCalculated: true, Formula: "(floors"},
+		},
+	}, 0, false)
+
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, actualErr, &validationErr)
+	assert.Equal(t, []models.FieldError{
+		{Field: "metrics[2].formula", ID: "c", Code: models.ValidationCodeUnknownField, Message: `"height" is not an attribute or metric of the template`},
+		{Field: "metrics[3].formula", ID: "d", Code: models.ValidationCodeInvalid, Message: `formula syntax error: expected ")" at position 7`},
+		{Field: "metrics", Code: models.ValidationCodeInvalid, Message: "formula cycle: a -> b -> a"},
+	}, validationErr.Errors)
+
+	mockRepository.AssertNotCalled(t, "GetTemplate", mock.Anything)
+}
+
 func TestService_GetParentTemplates_Success_ReturnsExternalIdSlice(t *testing.T) {
 	mockRepository := &db.MockedDbRepository{}
 	mockService := &service{
@@ -387,3 +865,203 @@ func TestService_GetParentTemplates_Fails_ReturnsError(t *testing.T) {
 
 	mockRepository.AssertExpectations(t)
 }
+
+func TestService_DeleteTemplate_WithDependents_ReturnsTemplateInUseError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			Parent:     "p.com.asset",
+			ExternalID: "testtemplate1",
+			IsCustom:   true,
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{
+		{BasicInformation: models.TemplateBasicInformation{Parent: "p.com.asset", ExternalID: "testtemplate1"}},
+		{BasicInformation: models.TemplateBasicInformation{Parent: "testtemplate1", ExternalID: "testtemplate2"}},
+		{BasicInformation: models.TemplateBasicInformation{Parent: "testtemplate2", ExternalID: "testtemplate3"}},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{BasicInformation: models.InstanceBasicInformation{Parent: "testtemplate1", ExternalId: "testinstance1"}},
+	}, nil)
+
+	expected := &models.TemplateDependents{
+		Templates: []string{"testtemplate2", "testtemplate3"},
+		Instances: []string{"testinstance1"},
+	}
+
+	actual, actualErr := mockService.DeleteTemplate("the-binary", "testtemplate1", false)
+	assert.Equal(t, ErrTemplateInUse, actualErr)
+	assert.Equal(t, expected, actual)
+
+	mockRepository.AssertNotCalled(t, "DeleteTemplates", mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DeleteTemplate_Force_DeletesDescendantsAndInstances(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			Parent:     "p.com.asset",
+			ExternalID: "testtemplate1",
+			IsCustom:   true,
+		},
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{
+		{BasicInformation: models.TemplateBasicInformation{Parent: "p.com.asset", ExternalID: "testtemplate1"}},
+		{BasicInformation: models.TemplateBasicInformation{Parent: "testtemplate1", ExternalID: "testtemplate2"}},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{BasicInformation: models.InstanceBasicInformation{Parent: "testtemplate2", ExternalId: "testinstance1"}},
+	}, nil).Once()
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "testinstance2"},
+			Relationships: []models.InstanceRelationship{
+				{ID: "1", Target: "testinstance1"},
+			},
+		},
+	}, nil).Once()
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance *models.Instance) bool {
+		return instance.BasicInformation.ExternalId == "testinstance2" && len(instance.Relationships) == 0
+	})).Return(int64(2), nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil).Once()
+	mockRepository.On("DeleteInstances", mock.AnythingOfType("primitive.D")).Return(nil)
+	mockRepository.On("DeleteTemplates", mock.AnythingOfType("primitive.D")).Return(nil)
+
+	actual, actualErr := mockService.DeleteTemplate("the-binary", "testtemplate1", true)
+	assert.Nil(t, actualErr)
+	assert.Nil(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DeleteTemplate_Force_RecalculatesReferencingInstances(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	containsId := primitive.NewObjectID()
+	mockRepository.On("GetTemplate", templateFilter("testtemplate1")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{Parent: "p.com.asset", ExternalID: "testtemplate1", IsCustom: true},
+	}, nil)
+	mockRepository.On("GetTemplate", templateFilter("p.com.site")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "p.com.site"},
+		Metrics:          []models.TemplateMetric{{ID: "building-count", Name: "buildings", MetricType: "integer", IsCalculated: true, Formula: `count("contains")`}},
+	}, nil)
+	mockRepository.On("GetRelationships", primitive.D(nil), "relationships").Return([]models.Relationship{{ID: containsId, Name: "contains"}}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{
+		{BasicInformation: models.TemplateBasicInformation{Parent: "p.com.asset", ExternalID: "testtemplate1"}},
+	}, nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{BasicInformation: models.InstanceBasicInformation{Parent: "testtemplate1", ExternalId: "building1"}},
+	}, nil).Once()
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{
+		{
+			BasicInformation: models.InstanceBasicInformation{ExternalId: "site1", Parent: "p.com.site"},
+			Metrics:          []models.InstanceMetric{{ID: "building-count", MetricBehaviour: "Calculated", Value: float64(1)}},
+			Relationships:    []models.InstanceRelationship{{ID: "1", Target: primitive.A{"building1"}, RelationshipTemplateId: containsId}},
+		},
+	}, nil).Once()
+	mockRepository.On("ReplaceInstance", mock.AnythingOfType("primitive.D"), mock.MatchedBy(func(instance *models.Instance) bool {
+		return instance.BasicInformation.ExternalId == "site1" &&
+			assert.ObjectsAreEqual([]models.InstanceMetric{{ID: "building-count", MetricBehaviour: "Calculated", Value: float64(0)}}, instance.Metrics)
+	})).Return(int64(2), nil)
+	mockRepository.On("DeleteInstances", mock.AnythingOfType("primitive.D")).Return(nil)
+	mockRepository.On("DeleteTemplates", mock.AnythingOfType("primitive.D")).Return(nil)
+	mockRepository.On("GetAllInstances", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Instance{}, nil).Once()
+
+	actual, actualErr := mockService.DeleteTemplate("the-binary", "testtemplate1", true)
+	assert.Nil(t, actualErr)
+	assert.Nil(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DeleteTemplate_NotCustom_ReturnsNotDeletableError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{
+			ExternalID: "p.com.asset",
+			IsCustom:   false,
+		},
+	}, nil)
+
+	actual, actualErr := mockService.DeleteTemplate("the-binary", "p.com.asset", true)
+	assert.Equal(t, ErrTemplateNotDeletable, actualErr)
+	assert.Nil(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_DeleteTemplate_FailsGettingTemplate_ReturnsError(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(nil, db.ErrNotFound)
+
+	actual, actualErr := mockService.DeleteTemplate("the-binary", "testtemplate1", false)
+	assert.Equal(t, db.ErrNotFound, actualErr)
+	assert.Nil(t, actual)
+
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateTemplate_StaleVersion_ReturnsPreconditionFailed(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "testtemplate1"},
+		Version:          5,
+	}, nil)
+
+	_, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "testtemplate1"},
+	}, 4, false)
+	assert.ErrorIs(t, actualErr, db.ErrPreconditionFailed)
+
+	mockRepository.AssertNotCalled(t, "ReplaceTemplate", mock.Anything, mock.Anything)
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateTemplate_MatchingVersion_ReplacesWithVersionedFilter(t *testing.T) {
+	mockRepository := &db.MockedDbRepository{}
+	mockService := &service{
+		db: mockRepository,
+	}
+
+	mockRepository.On("GetTemplate", mock.AnythingOfType("primitive.D")).Return(&models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "testtemplate1"},
+		Version:          5,
+	}, nil)
+	mockRepository.On("GetAllTemplates", mock.AnythingOfType("primitive.D"), mock.AnythingOfType("*options.FindOptions")).Return([]models.Template{}, nil)
+	mockRepository.On("ReplaceTemplate", bson.D{
+		{Key: "tenantId", Value: "the-binary"},
+		{Key: "basicInformation.externalId", Value: "testtemplate1"},
+		{Key: "version", Value: int64(5)},
+	}, mock.AnythingOfType("models.Template")).Return(int64(2), nil)
+
+	_, actualErr := mockService.UpdateTemplate("the-binary", models.Template{
+		BasicInformation: models.TemplateBasicInformation{ExternalID: "testtemplate1", Name: "renamed"},
+	}, 5, false)
+	assert.Nil(t, actualErr)
+
+	mockRepository.AssertExpectations(t)
+}